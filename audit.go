@@ -0,0 +1,84 @@
+package goresource
+
+import (
+	"context"
+	"time"
+)
+
+// Now 当前时间(审计、软删除), 测试时可替换
+var Now = time.Now
+
+// ICreateAuditable 创建审计, Create 时填充
+// CreatedFields 返回各资源的字段名(postgres 列名、bson 名、gorm 列名), Upsert 已存在时不更新这些字段
+type ICreateAuditable interface {
+	SetCreated(by string, at time.Time)
+	CreatedFields() []string
+}
+
+// IUpdateAuditable 更新审计, Update 时填充
+// UpdatedFields 返回各资源的字段名(postgres 列名、bson 名、gorm 列名), 指定更新字段时自动加上这些字段
+type IUpdateAuditable interface {
+	SetUpdated(by string, at time.Time)
+	UpdatedFields() []string
+}
+
+// IAuditable 审计模型 (创建人、创建时间、更新人、更新时间)
+type IAuditable interface {
+	ICreateAuditable
+	IUpdateAuditable
+}
+
+type actorCtxKey struct{}
+
+// ContextWithActor ctx 携带操作人
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+// ActorFromContext ctx 中的操作人
+func ActorFromContext(ctx context.Context) (actor string, ok bool) {
+	if ctx == nil {
+		return
+	}
+
+	actor, ok = ctx.Value(actorCtxKey{}).(string)
+	return
+}
+
+// AuditCreate 填充创建审计, 同时填充更新审计
+func AuditCreate(ctx context.Context, entry IDbModel) {
+	actor, _ := ActorFromContext(ctx)
+	at := Now()
+	if auditable, ok := entry.(ICreateAuditable); ok {
+		auditable.SetCreated(actor, at)
+	}
+	if auditable, ok := entry.(IUpdateAuditable); ok {
+		auditable.SetUpdated(actor, at)
+	}
+}
+
+// AuditUpdate 填充更新审计
+func AuditUpdate(ctx context.Context, entry IDbModel) {
+	if auditable, ok := entry.(IUpdateAuditable); ok {
+		actor, _ := ActorFromContext(ctx)
+		auditable.SetUpdated(actor, Now())
+	}
+}
+
+// CreateAuditFields 创建审计字段, 非 ICreateAuditable 时为空
+func CreateAuditFields(entry IDbModel) []string {
+	if auditable, ok := entry.(ICreateAuditable); ok {
+		return auditable.CreatedFields()
+	}
+
+	return nil
+}
+
+// UpdateAuditFields 更新审计字段, 非 IUpdateAuditable 时为空
+func UpdateAuditFields(entry IDbModel) []string {
+	if auditable, ok := entry.(IUpdateAuditable); ok {
+		return auditable.UpdatedFields()
+	}
+
+	return nil
+}
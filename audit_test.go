@@ -0,0 +1,69 @@
+package goresource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAuditModel struct {
+	testPerson
+	createdBy, updatedBy string
+	createdAt, updatedAt time.Time
+}
+
+func (m *testAuditModel) SetCreated(by string, at time.Time) {
+	m.createdBy, m.createdAt = by, at
+}
+
+func (m *testAuditModel) CreatedFields() []string {
+	return []string{"created_by", "created_at"}
+}
+
+func (m *testAuditModel) SetUpdated(by string, at time.Time) {
+	m.updatedBy, m.updatedAt = by, at
+}
+
+func (m *testAuditModel) UpdatedFields() []string {
+	return []string{"updated_by", "updated_at"}
+}
+
+func Test_Audit(test *testing.T) {
+	now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	Now = func() time.Time {
+		return now
+	}
+	defer func() {
+		Now = time.Now
+	}()
+
+	test.Run("create", func(t *testing.T) {
+		entry := &testAuditModel{}
+		AuditCreate(ContextWithActor(context.Background(), "ctl"), entry)
+		a := assert.New(t)
+		a.Equal("ctl", entry.createdBy)
+		a.Equal(now, entry.createdAt)
+		a.Equal("ctl", entry.updatedBy)
+		a.Equal(now, entry.updatedAt)
+	})
+
+	test.Run("update without actor", func(t *testing.T) {
+		entry := &testAuditModel{createdBy: "ctl"}
+		AuditUpdate(context.Background(), entry)
+		a := assert.New(t)
+		a.Equal("ctl", entry.createdBy)
+		a.True(entry.createdAt.IsZero())
+		a.Equal("", entry.updatedBy)
+		a.Equal(now, entry.updatedAt)
+	})
+}
+
+func Test_AuditFields(t *testing.T) {
+	a := assert.New(t)
+	a.Equal([]string{"created_by", "created_at"}, CreateAuditFields(&testAuditModel{}))
+	a.Equal([]string{"updated_by", "updated_at"}, UpdateAuditFields(&testAuditModel{}))
+	a.Empty(CreateAuditFields(&testPerson{}))
+	a.Empty(UpdateAuditFields(&testPerson{}))
+}
@@ -0,0 +1,9 @@
+package balancetype
+
+// Value 从库选择策略
+type Value int
+
+const (
+	RoundRobin   Value = iota // 轮询
+	LeastLatency              // 最低延迟(按读操作耗时)
+)
@@ -0,0 +1,326 @@
+package goresource
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/tools"
+)
+
+// ICache 实体缓存, 容量与过期由实现决定 (默认实现 lru.New)
+type ICache interface {
+	Get(key string) (value interface{}, ok bool)
+	Set(key string, value interface{})
+	Delete(key string)
+}
+
+// CacheStats 缓存命中统计
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRate 命中率
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// ICacheResource 带实体缓存的资源
+type ICacheResource interface {
+	IResource
+	Stats() CacheStats
+}
+
+// WithCache 按主键查询(First 且条件仅为 expr.ByID)使用缓存, 键为 Table()+ID
+// Update、Delete 成功后失效, UpdateMany、DeleteMany 调用后失效(工作单元中在提交后失效), 带条件参数时失效整个表
+func WithCache(resource IResource, cache ICache) ICacheResource {
+	return &cacheResource{
+		resource:    resource,
+		cache:       cache,
+		generations: make(map[string]uint64),
+		invalidated: make(map[string]uint64),
+	}
+}
+
+type cacheResource struct {
+	resource IResource
+	cache    ICache
+	hits     uint64
+	misses   uint64
+
+	rw          sync.RWMutex
+	generations map[string]uint64 // 表 -> 版本, 整表失效时递增
+	invalidated map[string]uint64 // 表 -> 失效次数, 读取期间有失效时不写入缓存
+}
+
+func (r *cacheResource) Db(args ...interface{}) IRepository {
+	return &cacheRepository{
+		IRepository: r.resource.Db(args...),
+		resource:    r,
+		uow:         ParseDbArgs(args...).Uow,
+	}
+}
+
+func (r *cacheResource) Uow() IUnitOfWork {
+	return r.resource.Uow()
+}
+
+func (r *cacheResource) Unwrap() IResource {
+	return r.resource
+}
+
+func (r *cacheResource) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&r.hits),
+		Misses: atomic.LoadUint64(&r.misses),
+	}
+}
+
+func (r *cacheResource) key(table string, id interface{}) string {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	return fmt.Sprintf("%s:%d:%v", table, r.generations[table], id)
+}
+
+// invalidations 表的失效次数
+func (r *cacheResource) invalidations(table string) uint64 {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	return r.invalidated[table]
+}
+
+// set 读取期间表有失效时不写入(避免写入失效前读取的旧数据)
+func (r *cacheResource) set(table string, invalidations uint64, key string, value interface{}) {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	if r.invalidated[table] == invalidations {
+		r.cache.Set(key, value)
+	}
+}
+
+// invalidate id 为空时失效整个表
+func (r *cacheResource) invalidate(table string, id interface{}) {
+	r.rw.Lock()
+	r.invalidated[table]++
+	if tools.IsEmpty(id) {
+		r.generations[table]++
+	}
+	r.rw.Unlock()
+
+	if !tools.IsEmpty(id) {
+		r.cache.Delete(r.key(table, id))
+	}
+}
+
+type cacheRepository struct {
+	IRepository
+
+	resource *cacheResource
+	uow      IUnitOfWork
+}
+
+func (r *cacheRepository) Delete(entry IDbModel, args ...interface{}) (err error) {
+	if err = r.IRepository.Delete(entry, args...); err == nil {
+		r.invalidate(entry, args)
+	}
+
+	return
+}
+
+func (r *cacheRepository) Update(entry IDbModel, args ...interface{}) (err error) {
+	if err = r.IRepository.Update(entry, args...); err == nil {
+		r.invalidate(entry, args)
+	}
+
+	return
+}
+
+func (r *cacheRepository) CreateMany(entries []IDbModel, batchSize int) error {
+	return CreateMany(r.IRepository, entries, batchSize)
+}
+
+// UpdateMany 部分失败时其余数据已写入, 无论成功与否都失效全部模型
+func (r *cacheRepository) UpdateMany(entries []IDbModel, batchSize int) (err error) {
+	err = UpdateMany(r.IRepository, entries, batchSize)
+	for _, entry := range entries {
+		r.invalidate(entry, nil)
+	}
+
+	return
+}
+
+// DeleteMany 部分失败时其余数据已删除, 无论成功与否都失效全部模型
+func (r *cacheRepository) DeleteMany(entries []IDbModel, batchSize int) (err error) {
+	err = DeleteMany(r.IRepository, entries, batchSize)
+	for _, entry := range entries {
+		r.invalidate(entry, nil)
+	}
+
+	return
+}
+
+// Upsert 按非主键字段判断时失效整个表
+func (r *cacheRepository) Upsert(entry IDbModel, opts UpsertOptions) (inserted bool, err error) {
+	if inserted, err = Upsert(r.IRepository, entry, opts); err == nil {
+		var args []interface{}
+		if len(opts.ConflictFields) > 0 {
+			args = append(args, opts)
+		}
+		r.invalidate(entry, args)
+	}
+
+	return
+}
+
+func (r *cacheRepository) Query() IQuery {
+	return &cacheQuery{
+		IQuery:     r.IRepository.Query(),
+		repository: r,
+	}
+}
+
+// invalidate 工作单元中在提交后失效
+func (r *cacheRepository) invalidate(entry IDbModel, args []interface{}) {
+	table, id := entry.Table(), entry.GetID()
+	if len(args) > 0 {
+		id = nil
+	}
+	fn := func() {
+		r.resource.invalidate(table, id)
+	}
+	if r.uow == nil {
+		fn()
+		return
+	}
+	if hook, ok := r.uow.(ICommitHook); ok {
+		hook.OnCommitted(fn)
+		return
+	}
+	fn()
+}
+
+type cacheQuery struct {
+	IQuery
+
+	repository *cacheRepository
+	id         interface{} // expr.ByID 的主键
+	tainted    bool        // 有其他条件、排序、字段等, 不使用缓存
+}
+
+func (q *cacheQuery) Asc(fields ...string) IQuery {
+	q.IQuery, q.tainted = q.IQuery.Asc(fields...), true
+	return q
+}
+
+func (q *cacheQuery) Desc(fields ...string) IQuery {
+	q.IQuery, q.tainted = q.IQuery.Desc(fields...), true
+	return q
+}
+
+func (q *cacheQuery) Fields(args ...interface{}) IQuery {
+	q.IQuery, q.tainted = q.IQuery.Fields(args...), true
+	return q
+}
+
+func (q *cacheQuery) Page(page int) IQuery {
+	q.IQuery, q.tainted = q.IQuery.Page(page), true
+	return q
+}
+
+func (q *cacheQuery) PageSize(pageSize int) IQuery {
+	q.IQuery, q.tainted = q.IQuery.PageSize(pageSize), true
+	return q
+}
+
+func (q *cacheQuery) SetOpts(opts ...interface{}) IQuery {
+	q.IQuery, q.tainted = q.IQuery.SetOpts(opts...), true
+	return q
+}
+
+func (q *cacheQuery) WithDeleted() IQuery {
+	q.IQuery, q.tainted = q.IQuery.WithDeleted(), true
+	return q
+}
+
+func (q *cacheQuery) OnlyDeleted() IQuery {
+	q.IQuery, q.tainted = q.IQuery.OnlyDeleted(), true
+	return q
+}
+
+func (q *cacheQuery) BatchSize(size int) IQuery {
+	q.IQuery = q.IQuery.BatchSize(size)
+	return q
+}
+
+func (q *cacheQuery) After(token string) IQuery {
+	q.IQuery, q.tainted = q.IQuery.After(token), true
+	return q
+}
+
+func (q *cacheQuery) Before(token string) IQuery {
+	q.IQuery, q.tainted = q.IQuery.Before(token), true
+	return q
+}
+
+func (q *cacheQuery) Where(args ...interface{}) IQuery {
+	q.IQuery = q.IQuery.Where(args...)
+	if len(args) == 1 && q.id == nil {
+		if e, ok := args[0].(expr.Expr); ok {
+			if id, ok := expr.IDOf(e); ok {
+				q.id = id
+				return q
+			}
+		}
+	}
+	q.tainted = true
+
+	return q
+}
+
+// First 按主键查询时使用缓存, 未找到时不缓存
+func (q *cacheQuery) First(res interface{}) error {
+	return q.first(res, IQuery.First)
+}
+
+func (q *cacheQuery) MustFirst(res interface{}) error {
+	return q.first(res, IQuery.MustFirst)
+}
+
+func (q *cacheQuery) first(res interface{}, first func(query IQuery, res interface{}) error) (err error) {
+	entry, ok := res.(IDbModel)
+	if !ok || q.tainted || q.id == nil || reflect.TypeOf(res).Kind() != reflect.Ptr {
+		return first(q.IQuery, res)
+	}
+
+	resource := q.repository.resource
+	table := entry.Table()
+	key, invalidations := resource.key(table, q.id), resource.invalidations(table)
+	resRv := reflect.ValueOf(res).Elem()
+	if v, ok := resource.cache.Get(key); ok && reflect.TypeOf(v) == resRv.Type() {
+		atomic.AddUint64(&resource.hits, 1)
+		resRv.Set(reflect.ValueOf(v))
+		return
+	}
+
+	atomic.AddUint64(&resource.misses, 1)
+	row := reflect.New(resRv.Type())
+	if err = first(q.IQuery, row.Interface()); err != nil {
+		return
+	}
+	if found, ok := row.Interface().(IDbModel); ok && !tools.IsEmpty(found.GetID()) {
+		resRv.Set(row.Elem())
+		resource.set(table, invalidations, key, row.Elem().Interface())
+	}
+
+	return
+}
@@ -0,0 +1,247 @@
+package goresource
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xm-chentl/goresource/dbtype"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PoolConfig 连接池配置 (0 使用各资源默认值)
+type PoolConfig struct {
+	MaxOpenConns int           `json:"maxOpenConns" yaml:"maxOpenConns"` // 最大连接数
+	MaxIdleConns int           `json:"maxIdleConns" yaml:"maxIdleConns"` // 最大空闲连接(postgres 为最小连接数)
+	MaxLifetime  time.Duration `json:"maxLifetime" yaml:"maxLifetime"`   // 连接最大存活时间 如: 1h
+	MaxIdleTime  time.Duration `json:"maxIdleTime" yaml:"maxIdleTime"`   // 连接最大空闲时间 如: 10m
+}
+
+// ResourceConfig 资源配置
+type ResourceConfig struct {
+	Alias    string       `json:"alias" yaml:"alias"`
+	Type     dbtype.Value `json:"type" yaml:"type"` // 为空时按 dsn 协议确定
+	DSN      string       `json:"dsn" yaml:"dsn"`
+	Database string       `json:"database" yaml:"database"` // 数据库名(mongo 为空时取 dsn 中的数据库)
+	Pool     PoolConfig   `json:"pool" yaml:"pool"`
+}
+
+// Config 配置文件
+type Config struct {
+	Resources []ResourceConfig `json:"resources" yaml:"resources"`
+}
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// NewFromConfig 从配置(yaml/json)创建资源工厂, 支持 ${ENV}、${ENV:-默认值} 环境变量
+//
+//	resources:
+//	  - alias: main
+//	    type: mysql
+//	    dsn: ${MYSQL_DSN}
+//	    pool:
+//	      maxOpenConns: 100
+//	      maxLifetime: 1h
+func NewFromConfig(r io.Reader) (f IFactory, err error) {
+	cfg, err := ParseConfig(r)
+	if err != nil {
+		return
+	}
+
+	return NewByConfig(cfg.Resources...)
+}
+
+// NewFromEnv 从环境变量创建资源工厂, 变量格式 {PREFIX}_{ALIAS}_{KEY}
+// KEY: TYPE、DSN、DATABASE、MAX_OPEN_CONNS、MAX_IDLE_CONNS、MAX_LIFETIME、MAX_IDLE_TIME
+func NewFromEnv(prefix string) (f IFactory, err error) {
+	cfgs, err := parseEnv(prefix, os.Environ())
+	if err != nil {
+		return
+	}
+
+	return NewByConfig(cfgs...)
+}
+
+// NewByConfig 按配置打开资源, 同时登记名称与类型(同类型以第一个为准)
+func NewByConfig(cfgs ...ResourceConfig) (IFactory, error) {
+	res, err := newFactoryByConfig(cfgs)
+	if err != nil {
+		return nil, err
+	}
+	for _, cfg := range cfgs {
+		if _, err = res.BuildByName(cfg.Alias); err != nil {
+			return nil, fmt.Errorf("alias: %s open failed: %w", cfg.Alias, err)
+		}
+	}
+
+	return res, nil
+}
+
+// NewLazy 按配置创建工厂, 资源在首次 BuildByType / BuildByName 时打开
+func NewLazy(cfgs ...ResourceConfig) (IFactory, error) {
+	return newFactoryByConfig(cfgs)
+}
+
+// NewByDSN 按 dsn 协议创建工厂(延迟打开), 名称 -> dsn
+func NewByDSN(aliasOfDsn map[string]string) (IFactory, error) {
+	cfgs := make([]ResourceConfig, 0, len(aliasOfDsn))
+	for alias, dsn := range aliasOfDsn {
+		cfgs = append(cfgs, ResourceConfig{
+			Alias: alias,
+			DSN:   dsn,
+		})
+	}
+	sort.Slice(cfgs, func(i, j int) bool {
+		return cfgs[i].Alias < cfgs[j].Alias
+	})
+
+	return NewLazy(cfgs...)
+}
+
+func newFactoryByConfig(cfgs []ResourceConfig) (*factory, error) {
+	res := newFactory()
+	for _, cfg := range cfgs {
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := res.nameOfConfig[cfg.Alias]; ok {
+			return nil, fmt.Errorf("alias: %s configured twice", cfg.Alias)
+		}
+
+		res.nameOfConfig[cfg.Alias] = cfg
+		if _, ok := res.dbTypeOfAlias[cfg.Type]; !ok {
+			res.dbTypeOfAlias[cfg.Type] = cfg.Alias
+		}
+	}
+
+	return res, nil
+}
+
+// ParseConfig 解析配置(yaml/json)后替换值中的环境变量(变量值不参与解析, 注释中的变量不替换)
+func ParseConfig(r io.Reader) (cfg Config, err error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return
+	}
+	// json 是 yaml 的子集
+	var node yaml.Node
+	if err = yaml.Unmarshal(content, &node); err != nil || node.Kind == 0 {
+		return
+	}
+	missing := make([]string, 0)
+	expandEnv(&node, &missing)
+	if len(missing) > 0 {
+		err = fmt.Errorf("env: %s not set", strings.Join(missing, ", "))
+		return
+	}
+	err = node.Decode(&cfg)
+
+	return
+}
+
+func (c *ResourceConfig) validate() error {
+	if c.Alias == "" {
+		return fmt.Errorf("resource config alias is empty")
+	}
+	if c.Type == "" {
+		var ok bool
+		if c.Type, ok = DbTypeOf(c.DSN); !ok {
+			return fmt.Errorf("alias: %s type is empty and dsn scheme not registered", c.Alias)
+		}
+	}
+
+	return nil
+}
+
+// expandEnv 替换标量节点值中的环境变量, 未设置且无默认值的变量加入 missing
+func expandEnv(node *yaml.Node, missing *[]string) {
+	for _, child := range node.Content {
+		expandEnv(child, missing)
+	}
+	if node.Kind != yaml.ScalarNode || !envPattern.MatchString(node.Value) {
+		return
+	}
+
+	node.Value = envPattern.ReplaceAllStringFunc(node.Value, func(item string) string {
+		matches := envPattern.FindStringSubmatch(item)
+		if v, ok := os.LookupEnv(matches[1]); ok {
+			return v
+		}
+		if len(matches[2]) > 0 {
+			return matches[3]
+		}
+		*missing = append(*missing, matches[1])
+		return item
+	})
+	// 未加引号的值按替换后的内容重新确定类型(如 maxOpenConns: ${MAX_OPEN_CONNS})
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		node.Tag = ""
+	}
+}
+
+func parseEnv(prefix string, environ []string) (cfgs []ResourceConfig, err error) {
+	prefix = strings.ToUpper(strings.TrimSuffix(prefix, "_")) + "_"
+	keys := []string{"TYPE", "DSN", "DATABASE", "MAX_OPEN_CONNS", "MAX_IDLE_CONNS", "MAX_LIFETIME", "MAX_IDLE_TIME"}
+	aliasOfCfg := make(map[string]*ResourceConfig)
+	for _, item := range environ {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], prefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(kv[0], prefix)
+		for _, key := range keys {
+			if !strings.HasSuffix(name, "_"+key) {
+				continue
+			}
+			alias := strings.ToLower(strings.TrimSuffix(name, "_"+key))
+			cfg, ok := aliasOfCfg[alias]
+			if !ok {
+				cfg = &ResourceConfig{Alias: alias}
+				aliasOfCfg[alias] = cfg
+			}
+			if err = cfg.set(key, kv[1]); err != nil {
+				err = fmt.Errorf("env: %s %w", kv[0], err)
+				return
+			}
+			break
+		}
+	}
+
+	cfgs = make([]ResourceConfig, 0, len(aliasOfCfg))
+	for _, cfg := range aliasOfCfg {
+		cfgs = append(cfgs, *cfg)
+	}
+	sort.Slice(cfgs, func(i, j int) bool {
+		return cfgs[i].Alias < cfgs[j].Alias
+	})
+
+	return
+}
+
+func (c *ResourceConfig) set(key, value string) (err error) {
+	switch key {
+	case "TYPE":
+		c.Type = dbtype.Value(value)
+	case "DSN":
+		c.DSN = value
+	case "DATABASE":
+		c.Database = value
+	case "MAX_OPEN_CONNS":
+		c.Pool.MaxOpenConns, err = strconv.Atoi(value)
+	case "MAX_IDLE_CONNS":
+		c.Pool.MaxIdleConns, err = strconv.Atoi(value)
+	case "MAX_LIFETIME":
+		c.Pool.MaxLifetime, err = time.ParseDuration(value)
+	case "MAX_IDLE_TIME":
+		c.Pool.MaxIdleTime, err = time.ParseDuration(value)
+	}
+
+	return
+}
@@ -0,0 +1,165 @@
+package goresource
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xm-chentl/goresource/dbtype"
+)
+
+const testDbType dbtype.Value = "test"
+
+type testResource struct {
+	IResource
+	cfg ResourceConfig
+}
+
+// testOpened 测试驱动打开次数
+var testOpened int
+
+func init() {
+	Register(testDbType, DriverFunc(func(cfg ResourceConfig) (IResource, error) {
+		testOpened++
+		return &testResource{cfg: cfg}, nil
+	}))
+	RegisterScheme("test", testDbType)
+}
+
+func Test_ParseConfig(test *testing.T) {
+	test.Run("yaml", func(t *testing.T) {
+		t.Setenv("TEST_DSN", "test://127.0.0.1")
+		cfg, err := ParseConfig(strings.NewReader(`
+resources:
+  - alias: main
+    type: test
+    dsn: ${TEST_DSN}
+    database: ${TEST_DATABASE:-demo}
+    pool:
+      maxOpenConns: 20
+      maxLifetime: 1h
+`))
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal([]ResourceConfig{
+			{
+				Alias:    "main",
+				Type:     testDbType,
+				DSN:      "test://127.0.0.1",
+				Database: "demo",
+				Pool: PoolConfig{
+					MaxOpenConns: 20,
+					MaxLifetime:  time.Hour,
+				},
+			},
+		}, cfg.Resources)
+	})
+
+	test.Run("json", func(t *testing.T) {
+		cfg, err := ParseConfig(strings.NewReader(`{"resources": [{"alias": "main", "type": "test", "dsn": "dsn", "pool": {"maxIdleTime": "10m"}}]}`))
+		a := assert.New(t)
+		a.NoError(err)
+		a.Len(cfg.Resources, 1)
+		a.Equal(10*time.Minute, cfg.Resources[0].Pool.MaxIdleTime)
+	})
+
+	test.Run("env value not parsed", func(t *testing.T) {
+		secret := "p\"w'd: x #y\nalias: injected"
+		t.Setenv("TEST_SECRET_DSN", secret)
+		t.Setenv("TEST_MAX_OPEN_CONNS", "30")
+		cfg, err := ParseConfig(strings.NewReader(`
+# dsn: ${TEST_ONLY_IN_COMMENT}
+resources:
+  - alias: main
+    dsn: "${TEST_SECRET_DSN}"
+    database: ${TEST_SECRET_DSN}
+    pool:
+      maxOpenConns: ${TEST_MAX_OPEN_CONNS}
+`))
+		a := assert.New(t)
+		a.NoError(err)
+		a.Len(cfg.Resources, 1)
+		a.Equal("main", cfg.Resources[0].Alias)
+		a.Equal(secret, cfg.Resources[0].DSN)
+		a.Equal(secret, cfg.Resources[0].Database)
+		a.Equal(30, cfg.Resources[0].Pool.MaxOpenConns)
+	})
+
+	test.Run("env not set", func(t *testing.T) {
+		_, err := ParseConfig(strings.NewReader(`dsn: ${TEST_NOT_SET_DSN}`))
+		assert.Error(t, err)
+	})
+}
+
+func Test_NewFromConfig(test *testing.T) {
+	test.Run("name and type", func(t *testing.T) {
+		f, err := NewFromConfig(strings.NewReader(`
+resources:
+  - alias: main
+    type: test
+    dsn: main
+  - alias: log
+    type: test
+    dsn: log
+`))
+		a := assert.New(t)
+		a.NoError(err)
+
+		main, err := f.BuildByName("main")
+		a.NoError(err)
+		a.Equal("main", main.(*testResource).cfg.DSN)
+		log, err := f.BuildByName("log")
+		a.NoError(err)
+		a.Equal("log", log.(*testResource).cfg.DSN)
+		res, err := f.BuildByType(testDbType)
+		a.NoError(err)
+		a.Equal(main, res)
+	})
+
+	test.Run("driver not registered", func(t *testing.T) {
+		_, err := NewFromConfig(strings.NewReader(`
+resources:
+  - alias: main
+    type: not-registered
+`))
+		assert.Error(t, err)
+	})
+
+	test.Run("alias twice", func(t *testing.T) {
+		_, err := NewByConfig(
+			ResourceConfig{Alias: "main", Type: testDbType},
+			ResourceConfig{Alias: "main", Type: testDbType},
+		)
+		assert.Error(t, err)
+	})
+}
+
+func Test_parseEnv(test *testing.T) {
+	cfgs, err := parseEnv("APP", []string{
+		"APP_MAIN_TYPE=test",
+		"APP_MAIN_DSN=main",
+		"APP_MAIN_MAX_OPEN_CONNS=10",
+		"APP_ORDER_LOG_TYPE=test",
+		"APP_ORDER_LOG_MAX_LIFETIME=30m",
+		"OTHER_MAIN_DSN=other",
+	})
+	a := assert.New(test)
+	a.NoError(err)
+	a.Equal([]ResourceConfig{
+		{
+			Alias: "main",
+			Type:  testDbType,
+			DSN:   "main",
+			Pool:  PoolConfig{MaxOpenConns: 10},
+		},
+		{
+			Alias: "order_log",
+			Type:  testDbType,
+			Pool:  PoolConfig{MaxLifetime: 30 * time.Minute},
+		},
+	}, cfgs)
+
+	_, err = parseEnv("APP", []string{"APP_MAIN_MAX_OPEN_CONNS=ten"})
+	a.Error(err)
+}
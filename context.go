@@ -0,0 +1,56 @@
+package goresource
+
+import (
+	"context"
+
+	"github.com/xm-chentl/goresource/uowstatus"
+)
+
+type uowCtxKey struct{}
+
+// ContextWithUow ctx 携带工作单元, 资源 Db(ctx) 时自动登记
+func ContextWithUow(ctx context.Context, uow IUnitOfWork) context.Context {
+	return context.WithValue(ctx, uowCtxKey{}, uow)
+}
+
+// UowFromContext ctx 中的工作单元
+func UowFromContext(ctx context.Context) (uow IUnitOfWork, ok bool) {
+	if ctx == nil {
+		return
+	}
+
+	uow, ok = ctx.Value(uowCtxKey{}).(IUnitOfWork)
+	return
+}
+
+// DbArgs IResource.Db 参数
+type DbArgs struct {
+	Ctx context.Context // 默认 context.Background()
+	Uow IUnitOfWork     // 参数中的工作单元, 其次为 ctx 中待提交的工作单元
+}
+
+// ParseDbArgs 解析 IResource.Db 参数 (ctx、uow 顺序不限)
+func ParseDbArgs(args ...interface{}) (res DbArgs) {
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case context.Context:
+			if res.Ctx == nil {
+				res.Ctx = v
+			}
+		case IUnitOfWork:
+			if res.Uow == nil {
+				res.Uow = v
+			}
+		}
+	}
+	if res.Ctx == nil {
+		res.Ctx = context.Background()
+	}
+	if res.Uow == nil {
+		if uow, ok := UowFromContext(res.Ctx); ok && uow.Status() == uowstatus.Pending {
+			res.Uow = uow
+		}
+	}
+
+	return
+}
@@ -0,0 +1,39 @@
+package goresource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseDbArgs(test *testing.T) {
+	test.Run("args", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), testDbType, "ctx")
+		uow := Uow()
+		res := ParseDbArgs(uow, ctx)
+		a := assert.New(t)
+		a.Equal(ctx, res.Ctx)
+		a.Equal(uow, res.Uow)
+	})
+
+	test.Run("empty", func(t *testing.T) {
+		res := ParseDbArgs()
+		a := assert.New(t)
+		a.NotNil(res.Ctx)
+		a.Nil(res.Uow)
+	})
+
+	test.Run("uow in ctx", func(t *testing.T) {
+		uow := Uow()
+		ctx := ContextWithUow(context.Background(), uow)
+		a := assert.New(t)
+		a.Equal(uow, ParseDbArgs(ctx).Uow)
+
+		other := Uow()
+		a.Equal(other, ParseDbArgs(ctx, other).Uow)
+
+		uow.Discard()
+		a.Nil(ParseDbArgs(ctx).Uow)
+	})
+}
@@ -8,6 +8,7 @@ func (v Value) String() string {
 }
 
 const (
+	Memory    Value = "memory"
 	Mongo     Value = "mongo"
 	MySQL     Value = "mysql"
 	TimeScale Value = "timescale"
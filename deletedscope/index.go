@@ -0,0 +1,10 @@
+package deletedscope
+
+// Value 软删除查询范围
+type Value int
+
+const (
+	Exclude Value = iota // 排除已删除(默认)
+	Include              // 包含已删除 WithDeleted
+	Only                 // 仅已删除 OnlyDeleted
+)
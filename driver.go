@@ -0,0 +1,125 @@
+package goresource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xm-chentl/goresource/dbtype"
+)
+
+// Driver 资源驱动, 各资源包在 init 中注册
+type Driver interface {
+	Open(cfg ResourceConfig) (IResource, error)
+}
+
+// DriverFunc 函数形式的驱动
+type DriverFunc func(cfg ResourceConfig) (IResource, error)
+
+func (f DriverFunc) Open(cfg ResourceConfig) (IResource, error) {
+	return f(cfg)
+}
+
+var (
+	driversRw sync.RWMutex
+	drivers   = make(map[dbtype.Value]Driver)
+	schemes   = make(map[string]dbtype.Value)
+)
+
+// Register 注册驱动, 重复注册或驱动为空时 panic
+func Register(dbType dbtype.Value, driver Driver) {
+	driversRw.Lock()
+	defer driversRw.Unlock()
+
+	if driver == nil {
+		panic("goresource: Register driver is nil")
+	}
+	if _, ok := drivers[dbType]; ok {
+		panic("goresource: Register called twice for driver " + dbType.String())
+	}
+	drivers[dbType] = driver
+}
+
+// RegisterScheme 注册 dsn 协议对应的类型, 如: postgres:// -> timescale
+func RegisterScheme(scheme string, dbType dbtype.Value) {
+	driversRw.Lock()
+	defer driversRw.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if _, ok := schemes[scheme]; ok {
+		panic("goresource: RegisterScheme called twice for scheme " + scheme)
+	}
+	schemes[scheme] = dbType
+}
+
+// DbTypeOf 按 dsn 协议获取类型
+func DbTypeOf(dsn string) (dbType dbtype.Value, ok bool) {
+	index := strings.Index(dsn, "://")
+	if index <= 0 {
+		return
+	}
+
+	driversRw.RLock()
+	defer driversRw.RUnlock()
+
+	dbType, ok = schemes[strings.ToLower(dsn[:index])]
+	return
+}
+
+// Drivers 已注册的驱动类型
+func Drivers() []dbtype.Value {
+	driversRw.RLock()
+	defer driversRw.RUnlock()
+
+	res := make([]dbtype.Value, 0, len(drivers))
+	for dbType := range drivers {
+		res = append(res, dbType)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i] < res[j]
+	})
+
+	return res
+}
+
+// Open 按配置类型打开资源, 类型为空时按 dsn 协议确定
+func Open(cfg ResourceConfig) (resource IResource, err error) {
+	if cfg.Type == "" {
+		var ok bool
+		if cfg.Type, ok = DbTypeOf(cfg.DSN); !ok {
+			err = fmt.Errorf("dsn: scheme of %q not registered", redact(cfg.DSN))
+			return
+		}
+	}
+
+	driversRw.RLock()
+	driver, ok := drivers[cfg.Type]
+	driversRw.RUnlock()
+	if !ok {
+		err = fmt.Errorf("dbtype: %s driver not registered (forgotten import?)", cfg.Type.String())
+		return
+	}
+
+	return driver.Open(cfg)
+}
+
+// OpenDSN 按 dsn 协议打开资源
+func OpenDSN(dsn string) (IResource, error) {
+	return Open(ResourceConfig{DSN: dsn})
+}
+
+// redact 隐藏 dsn 中的密码
+func redact(dsn string) string {
+	index := strings.Index(dsn, "://")
+	at := strings.LastIndex(dsn, "@")
+	if index < 0 || at < index {
+		return dsn
+	}
+	userInfo := dsn[index+3 : at]
+	if colon := strings.Index(userInfo, ":"); colon >= 0 {
+		return dsn[:index+3] + userInfo[:colon] + ":***" + dsn[at:]
+	}
+
+	return dsn
+}
@@ -1,3 +1,7 @@
 package elasticex
 
-var ()
+import "errors"
+
+var (
+	ErrQueryNotSupported = errors.New("elasticex: query not supported")
+)
@@ -0,0 +1,94 @@
+package elasticex
+
+import (
+	"github.com/xm-chentl/goresource"
+)
+
+// query 暂未实现, 执行时返回 ErrQueryNotSupported
+type query struct{}
+
+func (q *query) Count(entry goresource.IDbModel) (count int64, err error) {
+	err = ErrQueryNotSupported
+	return
+}
+
+func (q *query) Exec(res interface{}, args ...interface{}) error {
+	return ErrQueryNotSupported
+}
+
+func (q *query) Fields(args ...interface{}) goresource.IQuery {
+	return q
+}
+
+func (q *query) Find(res interface{}) error {
+	return ErrQueryNotSupported
+}
+
+func (q *query) FindPage(res interface{}) (info goresource.PageInfo, err error) {
+	err = ErrQueryNotSupported
+	return
+}
+
+func (q *query) First(res interface{}) error {
+	return ErrQueryNotSupported
+}
+
+func (q *query) MustFirst(res interface{}) error {
+	return ErrQueryNotSupported
+}
+
+func (q *query) Asc(fields ...string) goresource.IQuery {
+	return q
+}
+
+func (q *query) Desc(fields ...string) goresource.IQuery {
+	return q
+}
+
+func (q *query) Page(page int) goresource.IQuery {
+	return q
+}
+
+func (q *query) PageSize(pageSize int) goresource.IQuery {
+	return q
+}
+
+func (q *query) ToArray(res interface{}) error {
+	return ErrQueryNotSupported
+}
+
+func (q *query) Where(args ...interface{}) goresource.IQuery {
+	return q
+}
+
+func (q *query) SetOpts(opts ...interface{}) goresource.IQuery {
+	return q
+}
+
+func (q *query) WithDeleted() goresource.IQuery {
+	return q
+}
+
+func (q *query) OnlyDeleted() goresource.IQuery {
+	return q
+}
+
+func (q *query) BatchSize(size int) goresource.IQuery {
+	return q
+}
+
+func (q *query) Cursor(entry goresource.IDbModel) (goresource.ICursor, error) {
+	return nil, ErrQueryNotSupported
+}
+
+func (q *query) After(token string) goresource.IQuery {
+	return q
+}
+
+func (q *query) Before(token string) goresource.IQuery {
+	return q
+}
+
+func (q *query) PageToken() (next, prev string) {
+	return
+}
@@ -58,5 +58,5 @@ func (r *repository) Update(entry goresource.IDbModel, args ...interface{}) (err
 }
 
 func (r *repository) Query() goresource.IQuery {
-	return nil
+	return &query{}
 }
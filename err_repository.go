@@ -0,0 +1,131 @@
+package goresource
+
+// errRepository 无法确定资源时返回, 所有操作返回 err
+type errRepository struct {
+	err error
+}
+
+func (r errRepository) Create(entry IDbModel, args ...interface{}) error {
+	return r.err
+}
+
+func (r errRepository) Delete(entry IDbModel, args ...interface{}) error {
+	return r.err
+}
+
+func (r errRepository) Update(entry IDbModel, args ...interface{}) error {
+	return r.err
+}
+
+func (r errRepository) CreateMany(entries []IDbModel, batchSize int) error {
+	return r.err
+}
+
+func (r errRepository) UpdateMany(entries []IDbModel, batchSize int) error {
+	return r.err
+}
+
+func (r errRepository) DeleteMany(entries []IDbModel, batchSize int) error {
+	return r.err
+}
+
+func (r errRepository) Upsert(entry IDbModel, opts UpsertOptions) (bool, error) {
+	return false, r.err
+}
+
+func (r errRepository) Query() IQuery {
+	return errQuery(r)
+}
+
+type errQuery struct {
+	err error
+}
+
+func (q errQuery) Asc(fields ...string) IQuery {
+	return q
+}
+
+func (q errQuery) Count(entry IDbModel) (int64, error) {
+	return 0, q.err
+}
+
+func (q errQuery) Desc(fields ...string) IQuery {
+	return q
+}
+
+func (q errQuery) Exec(res interface{}, args ...interface{}) error {
+	return q.err
+}
+
+func (q errQuery) Fields(args ...interface{}) IQuery {
+	return q
+}
+
+func (q errQuery) Find(res interface{}) error {
+	return q.err
+}
+
+func (q errQuery) First(res interface{}) error {
+	return q.err
+}
+
+func (q errQuery) MustFirst(res interface{}) error {
+	return q.err
+}
+
+func (q errQuery) Page(page int) IQuery {
+	return q
+}
+
+func (q errQuery) PageSize(pageSize int) IQuery {
+	return q
+}
+
+func (q errQuery) SetOpts(opts ...interface{}) IQuery {
+	return q
+}
+
+func (q errQuery) ToArray(res interface{}) error {
+	return q.err
+}
+
+func (q errQuery) Where(args ...interface{}) IQuery {
+	return q
+}
+
+func (q errQuery) WithDeleted() IQuery {
+	return q
+}
+
+func (q errQuery) OnlyDeleted() IQuery {
+	return q
+}
+
+func (q errQuery) BatchSize(size int) IQuery {
+	return q
+}
+
+func (q errQuery) Cursor(entry IDbModel) (ICursor, error) {
+	return nil, q.err
+}
+
+func (q errQuery) FindPage(res interface{}) (PageInfo, error) {
+	return PageInfo{}, q.err
+}
+
+func (q errQuery) After(token string) IQuery {
+	return q
+}
+
+func (q errQuery) Before(token string) IQuery {
+	return q
+}
+
+func (q errQuery) PageToken() (next, prev string) {
+	return
+}
+
+// NewErrRepository 所有操作返回 err 的仓储
+func NewErrRepository(err error) IRepository {
+	return errRepository{err: err}
+}
@@ -1,6 +1,13 @@
 package errs
 
-import "errors"
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
 
 var (
 	ResIsNotPtr            = errors.New("res is not ptr")
@@ -12,4 +19,123 @@ var (
 	QueryArgsError         = errors.New("args parameter error")
 	QueryGrammarEmptyError = errors.New("query grammar empty")
 	GrammarError           = errors.New("sql grammar error")
+	UowPrepareNotSupported = errors.New("unit of work prepare not supported")
+	UowDiscarded           = errors.New("unit of work has been discarded")
+	UowNotSupported        = errors.New("unit of work not supported by resource")
+	FactoryClosed          = errors.New("factory has been closed")
+	TenantNotFound         = errors.New("tenant not found in context")
+	TenantInvalid          = errors.New("tenant id invalid")
+	StopIteration          = errors.New("stop iteration")
+	KeysetTokenInvalid     = errors.New("keyset token invalid")
+	KeysetSecretRequired   = errors.New("keyset secret not set, call keyset.SetSecret")
+	KeysetNullKey          = errors.New("keyset sort key is null")
+	UpsertNotSupported     = errors.New("upsert not supported")
+)
+
+// 驱动错误分类, 使用 errors.Is 判断 (原错误通过 errors.Unwrap 获取)
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrDuplicateKey   = errors.New("duplicate key")
+	ErrForeignKey     = errors.New("foreign key violation")
+	ErrDeadlock       = errors.New("deadlock or serialization failure")
+	ErrTimeout        = errors.New("timeout")
+	ErrConnectionLost = errors.New("connection lost")
 )
+
+// DriverError 已分类的驱动错误, Kind 为上面的分类之一
+type DriverError struct {
+	Kind error
+	Err  error
+}
+
+func (e *DriverError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DriverError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DriverError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Wrap 按 kind 包装驱动错误, kind 为 nil 时按超时、连接错误分类, 无法分类或已分类时原样返回
+func Wrap(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	var driverErr *DriverError
+	if errors.As(err, &driverErr) {
+		return err
+	}
+	if kind == nil {
+		kind = kindOf(err)
+	}
+	if kind == nil {
+		return err
+	}
+
+	return &DriverError{Kind: kind, Err: err}
+}
+
+func kindOf(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrTimeout
+		}
+		return ErrConnectionLost
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return ErrConnectionLost
+	}
+
+	return nil
+}
+
+// ConcurrencyConflict 乐观锁冲突, 按版本更新时未匹配到数据
+type ConcurrencyConflict struct {
+	Table   string
+	ID      interface{}
+	Version int64 // 更新时的版本
+}
+
+func (e *ConcurrencyConflict) Error() string {
+	return fmt.Sprintf("concurrency conflict: %s id %v version %d", e.Table, e.ID, e.Version)
+}
+
+// BulkFailure 批量操作失败项, Index 为传入切片中的索引
+type BulkFailure struct {
+	Index int
+	Err   error
+}
+
+// BulkError 批量操作部分或全部失败
+type BulkError struct {
+	Failures []BulkFailure
+}
+
+func (e *BulkError) Error() string {
+	if len(e.Failures) == 0 {
+		return "bulk failed"
+	}
+
+	return fmt.Sprintf("bulk failed: %d items, first index %d: %v", len(e.Failures), e.Failures[0].Index, e.Failures[0].Err)
+}
+
+// Add 记录失败项
+func (e *BulkError) Add(index int, err error) {
+	e.Failures = append(e.Failures, BulkFailure{Index: index, Err: err})
+}
+
+// OrNil 没有失败项时返回 nil
+func (e *BulkError) OrNil() error {
+	if e == nil || len(e.Failures) == 0 {
+		return nil
+	}
+
+	return e
+}
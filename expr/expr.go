@@ -0,0 +1,172 @@
+package expr
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Op 操作符
+type Op string
+
+const (
+	OpEq     Op = "="
+	OpNe     Op = "<>"
+	OpGt     Op = ">"
+	OpGte    Op = ">="
+	OpLt     Op = "<"
+	OpLte    Op = "<="
+	OpIn     Op = "IN"
+	OpLike   Op = "LIKE"
+	OpIsNull Op = "IS NULL"
+	OpAnd    Op = "AND"
+	OpOr     Op = "OR"
+	OpNot    Op = "NOT"
+)
+
+// PK 主键伪字段, 由各资源映射为主键(postgres pk 列、mongo _id、gorm 主键、内存 GetID)
+const PK = "$pk"
+
+// Expr 与资源无关的筛选表达式, 由各资源的 query.Where 编译为原生条件
+type Expr interface {
+	expr()
+}
+
+// Cond 字段条件
+type Cond struct {
+	Field string
+	Op    Op
+	Value interface{}
+}
+
+func (Cond) expr() {}
+
+// Logic 逻辑组合 AND/OR
+type Logic struct {
+	Op    Op
+	Items []Expr
+}
+
+func (Logic) expr() {}
+
+// Negation 取反
+type Negation struct {
+	Item Expr
+}
+
+func (Negation) expr() {}
+
+// ByID 主键等于 id
+func ByID(id interface{}) Expr {
+	return Eq(PK, id)
+}
+
+func Eq(field string, value interface{}) Expr {
+	return Cond{Field: field, Op: OpEq, Value: value}
+}
+
+func Ne(field string, value interface{}) Expr {
+	return Cond{Field: field, Op: OpNe, Value: value}
+}
+
+func Gt(field string, value interface{}) Expr {
+	return Cond{Field: field, Op: OpGt, Value: value}
+}
+
+func Gte(field string, value interface{}) Expr {
+	return Cond{Field: field, Op: OpGte, Value: value}
+}
+
+func Lt(field string, value interface{}) Expr {
+	return Cond{Field: field, Op: OpLt, Value: value}
+}
+
+func Lte(field string, value interface{}) Expr {
+	return Cond{Field: field, Op: OpLte, Value: value}
+}
+
+// In values 只有一个且为切片时展开
+func In(field string, values ...interface{}) Expr {
+	if len(values) == 1 {
+		rv := reflect.ValueOf(values[0])
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			values = make([]interface{}, 0, rv.Len())
+			for i := 0; i < rv.Len(); i++ {
+				values = append(values, rv.Index(i).Interface())
+			}
+		}
+	}
+
+	return Cond{Field: field, Op: OpIn, Value: values}
+}
+
+// Like pattern 使用 sql 通配符 % _
+func Like(field string, pattern string) Expr {
+	return Cond{Field: field, Op: OpLike, Value: pattern}
+}
+
+func IsNull(field string) Expr {
+	return Cond{Field: field, Op: OpIsNull}
+}
+
+func And(items ...Expr) Expr {
+	return Logic{Op: OpAnd, Items: items}
+}
+
+func Or(items ...Expr) Expr {
+	return Logic{Op: OpOr, Items: items}
+}
+
+func Not(item Expr) Expr {
+	return Negation{Item: item}
+}
+
+// LikeToRegexp 将 like 通配符转换为正则
+func LikeToRegexp(pattern string) string {
+	var bf strings.Builder
+	bf.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			bf.WriteString(".*")
+		case '_':
+			bf.WriteString(".")
+		default:
+			bf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	bf.WriteString("$")
+
+	return bf.String()
+}
+
+// ReplaceField 替换字段名(返回新表达式)
+func ReplaceField(e Expr, field, to string) Expr {
+	switch v := e.(type) {
+	case Cond:
+		if v.Field == field {
+			v.Field = to
+		}
+		return v
+	case Logic:
+		items := make([]Expr, 0, len(v.Items))
+		for _, item := range v.Items {
+			items = append(items, ReplaceField(item, field, to))
+		}
+		return Logic{Op: v.Op, Items: items}
+	case Negation:
+		return Negation{Item: ReplaceField(v.Item, field, to)}
+	}
+
+	return e
+}
+
+// IDOf 表达式为主键等于条件时返回 id
+func IDOf(e Expr) (id interface{}, ok bool) {
+	c, ok := e.(Cond)
+	if !ok || c.Field != PK || c.Op != OpEq {
+		return nil, false
+	}
+
+	return c.Value, true
+}
@@ -0,0 +1,89 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect sql 方言
+type Dialect struct {
+	Field       func(field string) string // 字段格式化
+	Placeholder func(index int) string    // 占位符 index 从1开始
+}
+
+// ToSQL 生成条件语句 offset 为已占用的参数个数(占位符从 offset+1 开始编号)
+func ToSQL(e Expr, dialect Dialect, offset int) (sql string, args []interface{}) {
+	c := &compiler{
+		dialect: dialect,
+		args:    make([]interface{}, 0),
+		offset:  offset,
+	}
+	sql = c.compile(e)
+	args = c.args
+
+	return
+}
+
+type compiler struct {
+	dialect Dialect
+	args    []interface{}
+	offset  int
+}
+
+func (c *compiler) compile(e Expr) string {
+	switch v := e.(type) {
+	case Cond:
+		return c.cond(v)
+	case Logic:
+		if len(v.Items) == 0 {
+			if v.Op == OpOr {
+				return "1 = 0"
+			}
+			return "1 = 1"
+		}
+		items := make([]string, 0, len(v.Items))
+		for _, item := range v.Items {
+			items = append(items, c.compile(item))
+		}
+		if len(items) == 1 {
+			return items[0]
+		}
+		return "(" + strings.Join(items, fmt.Sprintf(" %s ", v.Op)) + ")"
+	case Negation:
+		return "NOT (" + c.compile(v.Item) + ")"
+	}
+
+	return "1 = 1"
+}
+
+func (c *compiler) cond(v Cond) string {
+	field := v.Field
+	if c.dialect.Field != nil {
+		field = c.dialect.Field(field)
+	}
+	switch v.Op {
+	case OpIsNull:
+		return field + " IS NULL"
+	case OpIn:
+		values, _ := v.Value.([]interface{})
+		if len(values) == 0 {
+			return "1 = 0"
+		}
+		vars := make([]string, 0, len(values))
+		for _, value := range values {
+			vars = append(vars, c.bind(value))
+		}
+		return fmt.Sprintf("%s IN (%s)", field, strings.Join(vars, ", "))
+	}
+
+	return fmt.Sprintf("%s %s %s", field, v.Op, c.bind(v.Value))
+}
+
+func (c *compiler) bind(value interface{}) string {
+	c.args = append(c.args, value)
+	if c.dialect.Placeholder == nil {
+		return "?"
+	}
+
+	return c.dialect.Placeholder(c.offset + len(c.args))
+}
@@ -0,0 +1,82 @@
+package expr
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testPostgresDialect = Dialect{
+		Field: func(field string) string {
+			return fmt.Sprintf(`"%s"`, field)
+		},
+		Placeholder: func(index int) string {
+			return fmt.Sprintf("$%d", index)
+		},
+	}
+	testMysqlDialect = Dialect{
+		Field: func(field string) string {
+			return "`" + field + "`"
+		},
+	}
+)
+
+func Test_ToSQL(test *testing.T) {
+	test.Run("eq", func(t *testing.T) {
+		sql, args := ToSQL(Eq("name", "ctl"), testPostgresDialect, 0)
+		a := assert.New(t)
+		a.Equal(`"name" = $1`, sql)
+		a.Equal([]interface{}{"ctl"}, args)
+	})
+
+	test.Run("and.or", func(t *testing.T) {
+		sql, args := ToSQL(And(
+			Gt("age", 18),
+			Or(Like("name", "c%"), IsNull("name")),
+			In("id", []int64{1, 2}),
+		), testPostgresDialect, 0)
+		a := assert.New(t)
+		a.Equal(`("age" > $1 AND ("name" LIKE $2 OR "name" IS NULL) AND "id" IN ($3, $4))`, sql)
+		a.Equal([]interface{}{18, "c%", int64(1), int64(2)}, args)
+	})
+
+	test.Run("offset", func(t *testing.T) {
+		sql, args := ToSQL(Not(Eq("age", 1)), testPostgresDialect, 2)
+		a := assert.New(t)
+		a.Equal(`NOT ("age" = $3)`, sql)
+		a.Equal([]interface{}{1}, args)
+	})
+
+	test.Run("mysql", func(t *testing.T) {
+		sql, args := ToSQL(And(Ne("age", 1), Lte("age", 9)), testMysqlDialect, 0)
+		a := assert.New(t)
+		a.Equal("(`age` <> ? AND `age` <= ?)", sql)
+		a.Equal([]interface{}{1, 9}, args)
+	})
+
+	test.Run("in.empty", func(t *testing.T) {
+		sql, args := ToSQL(In("id"), testMysqlDialect, 0)
+		a := assert.New(t)
+		a.Equal("1 = 0", sql)
+		a.Empty(args)
+	})
+}
+
+func Test_LikeToRegexp(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(`^a\.b.*c.$`, LikeToRegexp("a.b%c_"))
+}
+
+func Test_ReplaceField(t *testing.T) {
+	e := ReplaceField(And(ByID(1), Not(Eq(PK, 2)), Eq("name", "ctl")), PK, "id")
+	a := assert.New(t)
+	a.Equal(And(Eq("id", 1), Not(Eq("id", 2)), Eq("name", "ctl")), e)
+
+	id, ok := IDOf(ByID(1))
+	a.True(ok)
+	a.Equal(1, id)
+	_, ok = IDOf(Eq("id", 1))
+	a.False(ok)
+}
@@ -2,8 +2,10 @@ package goresource
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
 )
 
 // Configure 配置 todo: 暂时不封装
@@ -14,40 +16,105 @@ type Configure struct {
 }
 
 type factory struct {
+	rw               sync.RWMutex
 	dbTypeOfResource map[dbtype.Value]IResource
 	nameOfResource   map[string]IResource
+
+	dbTypeOfAlias map[dbtype.Value]string   // 类型 -> 名称
+	nameOfConfig  map[string]ResourceConfig // 资源配置
+	closed        bool
 }
 
-func (f factory) BuildByType(dbType dbtype.Value) (resource IResource, err error) {
+func (f *factory) BuildByType(dbType dbtype.Value) (resource IResource, err error) {
+	f.rw.RLock()
 	resource, ok := f.dbTypeOfResource[dbType]
+	f.rw.RUnlock()
+	if ok {
+		return
+	}
+
+	f.rw.Lock()
+	defer f.rw.Unlock()
+
+	if f.closed {
+		err = errs.FactoryClosed
+		return
+	}
+	if resource, ok = f.dbTypeOfResource[dbType]; ok {
+		return
+	}
+	alias, ok := f.dbTypeOfAlias[dbType]
 	if !ok {
 		err = fmt.Errorf("dbtype: %s database implementation not configured", dbType.String())
 		return
 	}
+	if resource, err = f.open(alias); err != nil {
+		return
+	}
+	f.dbTypeOfResource[dbType] = resource
 
 	return
 }
 
-func (f factory) BuildByName(name string) (resource IResource, err error) {
+func (f *factory) BuildByName(name string) (resource IResource, err error) {
+	f.rw.RLock()
 	resource, ok := f.nameOfResource[name]
-	if !ok {
+	f.rw.RUnlock()
+	if ok {
+		return
+	}
+
+	f.rw.Lock()
+	defer f.rw.Unlock()
+
+	if f.closed {
+		err = errs.FactoryClosed
+		return
+	}
+	if resource, ok = f.nameOfResource[name]; ok {
+		return
+	}
+	if _, ok = f.nameOfConfig[name]; !ok {
 		err = fmt.Errorf("name: %s database implementation not configured", name)
 		return
 	}
 
+	return f.open(name)
+}
+
+// open 打开未打开的资源(须持有写锁), 失败时下次重试
+func (f *factory) open(alias string) (resource IResource, err error) {
+	if resource, ok := f.nameOfResource[alias]; ok {
+		return resource, nil
+	}
+
+	if resource, err = Open(f.nameOfConfig[alias]); err != nil {
+		return
+	}
+	f.nameOfResource[alias] = resource
+
 	return
 }
 
 func NewByType(dbTypeConfig map[dbtype.Value]IResource) IFactory {
-	return &factory{
-		dbTypeOfResource: dbTypeConfig,
-		nameOfResource:   make(map[string]IResource),
-	}
+	res := newFactory()
+	res.dbTypeOfResource = dbTypeConfig
+
+	return res
 }
 
 func NewByName(aliasConfig map[string]IResource) IFactory {
+	res := newFactory()
+	res.nameOfResource = aliasConfig
+
+	return res
+}
+
+func newFactory() *factory {
 	return &factory{
 		dbTypeOfResource: make(map[dbtype.Value]IResource),
-		nameOfResource:   aliasConfig,
+		nameOfResource:   make(map[string]IResource),
+		dbTypeOfAlias:    make(map[dbtype.Value]string),
+		nameOfConfig:     make(map[string]ResourceConfig),
 	}
 }
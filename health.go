@@ -0,0 +1,165 @@
+package goresource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/healthstatus"
+)
+
+// Health 资源健康状态
+type Health struct {
+	Alias   string
+	Type    dbtype.Value
+	Status  healthstatus.Value
+	Latency time.Duration // Ping 耗时
+	Err     error
+}
+
+// namedResource 已打开的资源(同一实例只出现一次)
+type namedResource struct {
+	alias    string
+	dbType   dbtype.Value
+	resource IResource
+}
+
+func (f *factory) Close() error {
+	f.rw.Lock()
+	if f.closed {
+		f.rw.Unlock()
+		return nil
+	}
+	resources := f.resources()
+	f.closed = true
+	f.dbTypeOfResource = make(map[dbtype.Value]IResource)
+	f.nameOfResource = make(map[string]IResource)
+	f.rw.Unlock()
+
+	messages := make([]string, 0)
+	for _, item := range resources {
+		closer, ok := as[io.Closer](item.resource)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", item.alias, err.Error()))
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("close resource failed: %s", strings.Join(messages, "; "))
+	}
+
+	return nil
+}
+
+// Health 并发 Ping 已打开的资源, 延迟打开的资源不会因此打开
+func (f *factory) Health(ctx context.Context) map[string]Health {
+	f.rw.RLock()
+	resources := f.resources()
+	res := make(map[string]Health, len(resources)+len(f.nameOfConfig))
+	for alias, cfg := range f.nameOfConfig {
+		if _, ok := f.nameOfResource[alias]; ok {
+			continue
+		}
+		res[alias] = Health{
+			Alias:  alias,
+			Type:   cfg.Type,
+			Status: healthstatus.Idle,
+		}
+	}
+	closed := f.closed
+	f.rw.RUnlock()
+	if closed {
+		for alias, item := range res {
+			item.Status = healthstatus.Down
+			item.Err = errs.FactoryClosed
+			res[alias] = item
+		}
+		return res
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for _, item := range resources {
+		wg.Add(1)
+		go func(item namedResource) {
+			defer wg.Done()
+
+			h := ping(ctx, item.resource)
+			h.Alias = item.alias
+			h.Type = item.dbType
+			mutex.Lock()
+			res[item.alias] = h
+			mutex.Unlock()
+		}(item)
+	}
+	wg.Wait()
+
+	return res
+}
+
+// resources 已打开的资源, 仅按类型登记的以类型为名称 (须持有锁)
+func (f *factory) resources() []namedResource {
+	res := make([]namedResource, 0, len(f.nameOfResource)+len(f.dbTypeOfResource))
+	for alias, resource := range f.nameOfResource {
+		res = append(res, namedResource{
+			alias:    alias,
+			dbType:   f.nameOfConfig[alias].Type,
+			resource: resource,
+		})
+	}
+	for dbType, resource := range f.dbTypeOfResource {
+		exists := false
+		for index := range res {
+			if sameResource(res[index].resource, resource) {
+				if res[index].dbType == "" {
+					res[index].dbType = dbType
+				}
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			res = append(res, namedResource{
+				alias:    dbType.String(),
+				dbType:   dbType,
+				resource: resource,
+			})
+		}
+	}
+
+	return res
+}
+
+func ping(ctx context.Context, resource IResource) (res Health) {
+	pinger, ok := as[IPinger](resource)
+	if !ok {
+		res.Status = healthstatus.Unknown
+		return
+	}
+
+	start := time.Now()
+	res.Err = pinger.Ping(ctx)
+	res.Latency = time.Since(start)
+	res.Status = healthstatus.Up
+	if res.Err != nil {
+		res.Status = healthstatus.Down
+	}
+
+	return
+}
+
+func sameResource(a, b IResource) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
@@ -0,0 +1,88 @@
+package goresource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/healthstatus"
+)
+
+type testPingResource struct {
+	IResource
+	pingErr error
+	closed  int
+}
+
+func (r *testPingResource) Ping(ctx context.Context) error {
+	return r.pingErr
+}
+
+func (r *testPingResource) Close() error {
+	r.closed++
+	return nil
+}
+
+func Test_factory_Health(test *testing.T) {
+	up := &testPingResource{}
+	down := &testPingResource{pingErr: errors.New("connection refused")}
+	f := newFactory()
+	f.nameOfResource["up"] = up
+	f.nameOfResource["down"] = down
+	f.dbTypeOfResource[dbtype.Mongo] = up
+	f.dbTypeOfResource[dbtype.Memory] = &testResource{}
+	f.nameOfConfig["lazy"] = ResourceConfig{Alias: "lazy", Type: testDbType}
+
+	res := f.Health(context.Background())
+	a := assert.New(test)
+	a.Len(res, 4)
+	a.Equal(healthstatus.Up, res["up"].Status)
+	a.Equal(dbtype.Mongo, res["up"].Type)
+	a.Equal(healthstatus.Down, res["down"].Status)
+	a.Error(res["down"].Err)
+	a.Equal(healthstatus.Unknown, res[dbtype.Memory.String()].Status)
+	a.Equal(healthstatus.Idle, res["lazy"].Status)
+}
+
+func Test_factory_Close(test *testing.T) {
+	resource := &testPingResource{}
+	f := newFactory()
+	f.nameOfResource["main"] = resource
+	f.dbTypeOfResource[dbtype.Mongo] = resource
+
+	a := assert.New(test)
+	a.NoError(f.Close())
+	a.Equal(1, resource.closed)
+	a.NoError(f.Close())
+	a.Equal(1, resource.closed)
+
+	_, err := f.BuildByType(dbtype.Mongo)
+	a.Equal(errs.FactoryClosed, err)
+	_, err = f.BuildByName("main")
+	a.Equal(errs.FactoryClosed, err)
+}
+
+// testBuildFactory 只实现 IFactory 的工厂
+type testBuildFactory struct {
+	IFactory
+}
+
+func Test_FactoryHealth(test *testing.T) {
+	test.Run("optional", func(t *testing.T) {
+		a := assert.New(t)
+		a.Nil(FactoryHealth(context.Background(), testBuildFactory{}))
+		a.NoError(CloseFactory(testBuildFactory{}))
+	})
+
+	test.Run("factory", func(t *testing.T) {
+		resource := &testPingResource{}
+		f := NewByName(map[string]IResource{"main": resource})
+		a := assert.New(t)
+		a.Equal(healthstatus.Up, FactoryHealth(context.Background(), f)["main"].Status)
+		a.NoError(CloseFactory(f))
+		a.Equal(1, resource.closed)
+	})
+}
@@ -0,0 +1,15 @@
+package healthstatus
+
+// Value 资源健康状态
+type Value string
+
+func (v Value) String() string {
+	return string(v)
+}
+
+const (
+	Up      Value = "up"      // 正常
+	Down    Value = "down"    // 不可用
+	Unknown Value = "unknown" // 资源未实现 IPinger
+	Idle    Value = "idle"    // 延迟打开的资源尚未打开
+)
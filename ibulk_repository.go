@@ -0,0 +1,89 @@
+package goresource
+
+import (
+	"errors"
+
+	"github.com/xm-chentl/goresource/errs"
+)
+
+// DefaultBatchSize 批量操作默认每批数量
+const DefaultBatchSize = 500
+
+// IBulkRepository 批量操作(可选), batchSize 为每批数量, 小于 1 时为 DefaultBatchSize
+// 失败时返回 *errs.BulkError(失败项的索引及错误), 其他项正常执行; 工作单元中加入队列, 提交时整体成功或失败
+type IBulkRepository interface {
+	CreateMany(entries []IDbModel, batchSize int) error
+	UpdateMany(entries []IDbModel, batchSize int) error
+	DeleteMany(entries []IDbModel, batchSize int) error
+}
+
+// CreateMany repository 为 IBulkRepository 时批量创建, 否则逐个创建
+func CreateMany(repository IRepository, entries []IDbModel, batchSize int) error {
+	if bulk, ok := repository.(IBulkRepository); ok {
+		return bulk.CreateMany(entries, batchSize)
+	}
+
+	return each(entries, repository.Create)
+}
+
+// UpdateMany repository 为 IBulkRepository 时批量更新, 否则逐个更新
+func UpdateMany(repository IRepository, entries []IDbModel, batchSize int) error {
+	if bulk, ok := repository.(IBulkRepository); ok {
+		return bulk.UpdateMany(entries, batchSize)
+	}
+
+	return each(entries, repository.Update)
+}
+
+// DeleteMany repository 为 IBulkRepository 时批量删除, 否则逐个删除
+func DeleteMany(repository IRepository, entries []IDbModel, batchSize int) error {
+	if bulk, ok := repository.(IBulkRepository); ok {
+		return bulk.DeleteMany(entries, batchSize)
+	}
+
+	return each(entries, repository.Delete)
+}
+
+func each(entries []IDbModel, fn func(entry IDbModel, args ...interface{}) error) error {
+	bulkErr := &errs.BulkError{}
+	for index, entry := range entries {
+		if err := fn(entry); err != nil {
+			bulkErr.Add(index, err)
+		}
+	}
+
+	return bulkErr.OrNil()
+}
+
+// Batches 按 batchSize 分批执行 fn(start, end), 合并各批的失败项
+// fn 返回 *errs.BulkError 时索引为批内索引, 返回其他错误时整批失败
+func Batches(count, batchSize int, fn func(start, end int) error) error {
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+
+	bulkErr := &errs.BulkError{}
+	for start := 0; start < count; start += batchSize {
+		end := start + batchSize
+		if end > count {
+			end = count
+		}
+		err := fn(start, end)
+		if err == nil {
+			continue
+		}
+
+		var batchErr *errs.BulkError
+		if errors.As(err, &batchErr) {
+			for _, failure := range batchErr.Failures {
+				bulkErr.Add(start+failure.Index, failure.Err)
+			}
+			continue
+		}
+		for index := start; index < end; index++ {
+			bulkErr.Add(index, err)
+		}
+	}
+
+	return bulkErr.OrNil()
+}
@@ -0,0 +1,11 @@
+package goresource
+
+// ICursor 游标, 逐行读取查询结果, 使用后须 Close
+type ICursor interface {
+	// Next 读取下一行, 无数据或出错时返回 false (错误由 Err 返回)
+	Next() bool
+	// Scan 当前行写入 res (模型指针)
+	Scan(res interface{}) error
+	Err() error
+	Close() error
+}
@@ -0,0 +1,114 @@
+package goresource
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/xm-chentl/goresource/tools"
+)
+
+// IDGenerator 主键生成器, 实现见 idgen 包
+type IDGenerator interface {
+	NextID(ctx context.Context, entry IDbModel) (interface{}, error)
+}
+
+// IDGeneratorFunc 函数形式的主键生成器
+type IDGeneratorFunc func(ctx context.Context, entry IDbModel) (interface{}, error)
+
+func (f IDGeneratorFunc) NextID(ctx context.Context, entry IDbModel) (interface{}, error) {
+	return f(ctx, entry)
+}
+
+var (
+	idGeneratorsRw    sync.RWMutex
+	modelIDGenerators = make(map[reflect.Type]IDGenerator)
+	tableIDGenerators = make(map[string]IDGenerator)
+)
+
+// RegisterIDGenerator 按模型类型绑定主键生成器(优先于按表名绑定), 模型或生成器为空时 panic
+func RegisterIDGenerator(model IDbModel, generator IDGenerator) {
+	if model == nil || generator == nil {
+		panic("goresource: RegisterIDGenerator model or generator is nil")
+	}
+
+	idGeneratorsRw.Lock()
+	defer idGeneratorsRw.Unlock()
+
+	modelIDGenerators[modelType(model)] = generator
+}
+
+// RegisterTableIDGenerator 按表名绑定主键生成器, 生成器为空时 panic
+func RegisterTableIDGenerator(table string, generator IDGenerator) {
+	if generator == nil {
+		panic("goresource: RegisterTableIDGenerator generator is nil")
+	}
+
+	idGeneratorsRw.Lock()
+	defer idGeneratorsRw.Unlock()
+
+	tableIDGenerators[table] = generator
+}
+
+// IDGeneratorOf 模型绑定的主键生成器, 其次为表名绑定的
+func IDGeneratorOf(entry IDbModel) (generator IDGenerator, ok bool) {
+	idGeneratorsRw.RLock()
+	defer idGeneratorsRw.RUnlock()
+
+	if generator, ok = modelIDGenerators[modelType(entry)]; ok {
+		return
+	}
+	generator, ok = tableIDGenerators[entry.Table()]
+
+	return
+}
+
+// GenerateID 主键为空(tools.IsEmpty)且绑定了生成器时生成并 SetID, 生成值按主键类型转换(数值之间、字符串之间)
+// 各资源的 Create、Upsert、批量创建在写入或加入工作单元队列前调用
+func GenerateID(ctx context.Context, entry IDbModel) error {
+	if !tools.IsEmpty(entry.GetID()) {
+		return nil
+	}
+	generator, ok := IDGeneratorOf(entry)
+	if !ok {
+		return nil
+	}
+
+	id, err := generator.NextID(ctx, entry)
+	if err != nil {
+		return err
+	}
+	entry.SetID(convertID(id, entry.GetID()))
+
+	return nil
+}
+
+// convertID id 转换为 current 的类型, 不同类时原样返回
+func convertID(id, current interface{}) interface{} {
+	if id == nil || current == nil {
+		return id
+	}
+
+	idRv, rt := reflect.ValueOf(id), reflect.TypeOf(current)
+	if idRv.Type() == rt || !idRv.Type().ConvertibleTo(rt) {
+		return id
+	}
+	if (isNumber(idRv.Kind()) && isNumber(rt.Kind())) || (idRv.Kind() == reflect.String && rt.Kind() == reflect.String) {
+		return idRv.Convert(rt).Interface()
+	}
+
+	return id
+}
+
+func isNumber(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Uint64
+}
+
+func modelType(model IDbModel) reflect.Type {
+	rt := reflect.TypeOf(model)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	return rt
+}
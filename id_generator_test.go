@@ -0,0 +1,78 @@
+package goresource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testIDModel struct {
+	ID string
+}
+
+func (m testIDModel) GetID() interface{} {
+	return m.ID
+}
+
+func (m *testIDModel) SetID(v interface{}) {
+	if vv, ok := v.(string); ok {
+		m.ID = vv
+	}
+}
+
+func (m testIDModel) Table() string {
+	return "test_id_model"
+}
+
+func Test_GenerateID(test *testing.T) {
+	RegisterTableIDGenerator("test_person", IDGeneratorFunc(func(ctx context.Context, entry IDbModel) (interface{}, error) {
+		return 10, nil
+	}))
+	defer delete(tableIDGenerators, "test_person")
+
+	test.Run("table", func(t *testing.T) {
+		entry := &testPerson{}
+		a := assert.New(t)
+		a.NoError(GenerateID(context.Background(), entry))
+		a.Equal(int64(10), entry.ID)
+	})
+
+	test.Run("not.empty", func(t *testing.T) {
+		entry := &testPerson{ID: 1}
+		a := assert.New(t)
+		a.NoError(GenerateID(context.Background(), entry))
+		a.Equal(int64(1), entry.ID)
+	})
+
+	test.Run("model", func(t *testing.T) {
+		RegisterIDGenerator(&testPerson{}, IDGeneratorFunc(func(ctx context.Context, entry IDbModel) (interface{}, error) {
+			return uint8(20), nil
+		}))
+		defer delete(modelIDGenerators, modelType(&testPerson{}))
+
+		entry := &testPerson{}
+		a := assert.New(t)
+		a.NoError(GenerateID(context.Background(), entry))
+		a.Equal(int64(20), entry.ID)
+	})
+
+	test.Run("unbound", func(t *testing.T) {
+		entry := &testIDModel{}
+		a := assert.New(t)
+		a.NoError(GenerateID(context.Background(), entry))
+		a.Empty(entry.ID)
+	})
+
+	test.Run("error", func(t *testing.T) {
+		err := errors.New("next id")
+		RegisterIDGenerator(&testIDModel{}, IDGeneratorFunc(func(ctx context.Context, entry IDbModel) (interface{}, error) {
+			return nil, err
+		}))
+		defer delete(modelIDGenerators, modelType(&testIDModel{}))
+
+		a := assert.New(t)
+		a.Equal(err, GenerateID(context.Background(), &testIDModel{}))
+	})
+}
@@ -1,8 +1,49 @@
 package goresource
 
+import "reflect"
+
 // IDbModel 数据库模型
 type IDbModel interface {
 	GetID() interface{}
 	Table() string
 	SetID(v interface{})
 }
+
+// ISoftDeletable 软删除模型, Delete 写入删除时间(可为空的时间字段), 查询默认排除已删除
+// DeletedAtField 返回各资源的字段名(postgres 列名、bson 名、gorm 列名)
+type ISoftDeletable interface {
+	DeletedAtField() string
+}
+
+// ModelOf 结果(模型、模型指针、模型切片指针)对应的新模型
+func ModelOf(res interface{}) (entry IDbModel, ok bool) {
+	if res == nil {
+		return
+	}
+
+	rt := reflect.TypeOf(res)
+	for rt.Kind() == reflect.Ptr || rt.Kind() == reflect.Slice {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return
+	}
+	entry, ok = reflect.New(rt).Interface().(IDbModel)
+
+	return
+}
+
+// DeletedAtFieldOf 结果对应模型的软删除字段
+func DeletedAtFieldOf(res interface{}) (field string, ok bool) {
+	entry, ok := ModelOf(res)
+	if !ok {
+		return
+	}
+	softDeletable, ok := entry.(ISoftDeletable)
+	if !ok {
+		return
+	}
+	field = softDeletable.DeletedAtField()
+
+	return
+}
@@ -0,0 +1,123 @@
+package idgen
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrSequenceEmpty 序列语句未返回数据
+var ErrSequenceEmpty = errors.New("idgen: sequence returned no rows")
+
+// crockford ULID 使用的 base32 字符
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID 26 位字符串主键: 48 位毫秒时间 + 80 位随机, 按时间有序
+var ULID goresource.IDGenerator = goresource.IDGeneratorFunc(func(ctx context.Context, entry goresource.IDbModel) (interface{}, error) {
+	return NewULID()
+})
+
+// UUIDv4 随机 UUID 字符串
+var UUIDv4 goresource.IDGenerator = goresource.IDGeneratorFunc(func(ctx context.Context, entry goresource.IDbModel) (interface{}, error) {
+	return NewUUIDv4()
+})
+
+// UUIDv7 按时间有序的 UUID 字符串
+var UUIDv7 goresource.IDGenerator = goresource.IDGeneratorFunc(func(ctx context.Context, entry goresource.IDbModel) (interface{}, error) {
+	return NewUUIDv7()
+})
+
+// ObjectID mongo ObjectID
+var ObjectID goresource.IDGenerator = goresource.IDGeneratorFunc(func(ctx context.Context, entry goresource.IDbModel) (interface{}, error) {
+	return primitive.NewObjectID(), nil
+})
+
+func NewULID() (string, error) {
+	var data [16]byte
+	putMillis(data[:6], time.Now())
+	if _, err := rand.Read(data[6:]); err != nil {
+		return "", err
+	}
+
+	// 128 位按 5 位一组编码为 26 个字符(首字符 3 位)
+	res := make([]byte, 26)
+	hi, lo := binary.BigEndian.Uint64(data[:8]), binary.BigEndian.Uint64(data[8:])
+	for index := 25; index >= 0; index-- {
+		res[index] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(res), nil
+}
+
+func NewUUIDv4() (string, error) {
+	var data [16]byte
+	if _, err := rand.Read(data[:]); err != nil {
+		return "", err
+	}
+
+	return formatUUID(data, 4), nil
+}
+
+func NewUUIDv7() (string, error) {
+	var data [16]byte
+	if _, err := rand.Read(data[6:]); err != nil {
+		return "", err
+	}
+	putMillis(data[:6], time.Now())
+
+	return formatUUID(data, 7), nil
+}
+
+// Sequence 数据库序列, sql 返回一行 id 列(如 postgres: SELECT nextval('user_id_seq') AS id), 使用 IQuery.Exec 执行
+func Sequence(resource goresource.IResource, sql string, args ...interface{}) goresource.IDGenerator {
+	return goresource.IDGeneratorFunc(func(ctx context.Context, entry goresource.IDbModel) (interface{}, error) {
+		var rows []struct {
+			ID int64
+		}
+		if err := resource.Db(ctx).Query().Exec(&rows, append([]interface{}{sql}, args...)...); err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			return nil, ErrSequenceEmpty
+		}
+
+		return rows[0].ID, nil
+	})
+}
+
+// putMillis 48 位毫秒时间(大端)
+func putMillis(dst []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for index := 5; index >= 0; index-- {
+		dst[index] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// formatUUID 设置版本、变体并格式化为 8-4-4-4-12
+func formatUUID(data [16]byte, version byte) string {
+	data[6] = data[6]&0x0f | version<<4
+	data[8] = data[8]&0x3f | 0x80
+
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], data[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], data[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], data[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], data[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], data[10:])
+
+	return string(buf)
+}
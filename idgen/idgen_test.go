@@ -0,0 +1,65 @@
+package idgen
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_Snowflake(test *testing.T) {
+	test.Run("node", func(t *testing.T) {
+		_, err := NewSnowflake(maxNode + 1)
+		assert.Error(t, err)
+	})
+
+	test.Run("increase", func(t *testing.T) {
+		snowflake, err := NewSnowflake(3)
+		a := assert.New(t)
+		a.NoError(err)
+
+		last := int64(0)
+		for i := 0; i < 10000; i++ {
+			id := snowflake.Next()
+			a.Greater(id, last)
+			a.Equal(int64(3), id>>sequenceBits&maxNode)
+			last = id
+		}
+	})
+}
+
+func Test_ULID(t *testing.T) {
+	a := assert.New(t)
+	first, err := NewULID()
+	a.NoError(err)
+	a.Regexp(regexp.MustCompile("^[0-9A-HJKMNP-TV-Z]{26}$"), first)
+
+	second, err := NewULID()
+	a.NoError(err)
+	a.NotEqual(first, second)
+}
+
+func Test_UUID(test *testing.T) {
+	test.Run("v4", func(t *testing.T) {
+		id, err := UUIDv4.NextID(context.Background(), nil)
+		a := assert.New(t)
+		a.NoError(err)
+		a.Regexp(regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"), id)
+	})
+
+	test.Run("v7", func(t *testing.T) {
+		id, err := UUIDv7.NextID(context.Background(), nil)
+		a := assert.New(t)
+		a.NoError(err)
+		a.Regexp(regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"), id)
+	})
+}
+
+func Test_ObjectID(t *testing.T) {
+	id, err := ObjectID.NextID(context.Background(), nil)
+	a := assert.New(t)
+	a.NoError(err)
+	a.False(id.(primitive.ObjectID).IsZero())
+}
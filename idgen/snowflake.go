@@ -0,0 +1,66 @@
+package idgen
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+)
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+	maxNode      = -1 ^ (-1 << nodeBits)
+	maxSequence  = -1 ^ (-1 << sequenceBits)
+)
+
+// Epoch snowflake 时间起点
+var Epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake 64 位整型主键: 41 位毫秒时间(自 Epoch) + 10 位节点 + 12 位序号, 同一节点内递增
+type Snowflake struct {
+	mutex    sync.Mutex
+	node     int64
+	last     int64
+	sequence int64
+}
+
+// NewSnowflake node 范围 0 - 1023, 多实例部署时须各不相同
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > maxNode {
+		return nil, fmt.Errorf("idgen: snowflake node must be between 0 and %d", maxNode)
+	}
+
+	return &Snowflake{node: node}, nil
+}
+
+// Next 下一个主键, 同一毫秒序号用尽或时钟回拨时等待
+func (s *Snowflake) Next() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Since(Epoch).Milliseconds()
+	if now < s.last {
+		now = s.last
+	}
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 {
+			for now <= s.last {
+				time.Sleep(time.Millisecond)
+				now = time.Since(Epoch).Milliseconds()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = now
+
+	return now<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.sequence
+}
+
+func (s *Snowflake) NextID(ctx context.Context, entry goresource.IDbModel) (interface{}, error) {
+	return s.Next(), nil
+}
@@ -1,9 +1,37 @@
 package goresource
 
-import "github.com/xm-chentl/goresource/dbtype"
+import (
+	"context"
+	"io"
+
+	"github.com/xm-chentl/goresource/dbtype"
+)
 
 // IDbFactory 数据库实例工厂
 type IFactory interface {
 	BuildByType(dbtype.Value) (IResource, error) // 类型与数据库 一对一
 	BuildByName(string) (IResource, error)       // 名称与数据库 一对一
 }
+
+// IHealthChecker 可选, 工厂各资源的健康状态
+type IHealthChecker interface {
+	Health(ctx context.Context) map[string]Health // 名称 -> 健康状态
+}
+
+// CloseFactory 关闭工厂已打开的资源(实现 io.Closer 的资源), 工厂未实现 io.Closer 时忽略
+func CloseFactory(f IFactory) error {
+	if closer, ok := f.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// FactoryHealth 工厂各资源的健康状态, 工厂未实现 IHealthChecker 时为 nil
+func FactoryHealth(ctx context.Context, f IFactory) map[string]Health {
+	if checker, ok := f.(IHealthChecker); ok {
+		return checker.Health(ctx)
+	}
+
+	return nil
+}
@@ -0,0 +1,303 @@
+package goresource
+
+import (
+	"context"
+	"time"
+
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/optype"
+)
+
+// Invocation 仓储调用信息, 拦截器可在调用 next 前修改 Entry、Res、Args、Query
+type Invocation struct {
+	Ctx     context.Context
+	Op      optype.Value
+	Table   string // IDbModel.Table()
+	DbType  dbtype.Value
+	Entry   IDbModel      // Create、Delete、Update、Count、First 的模型
+	Entries []IDbModel    // CreateMany、UpdateMany、DeleteMany 的模型
+	Res     interface{}   // Find、First、Exec 的结果
+	Args    []interface{} // Create、Delete、Update、Exec 的参数
+	Query   IQuery        // 查询操作执行的查询, 可追加条件
+
+	Duration time.Duration // 资源调用耗时(next 返回后有效)
+	Err      error         // 资源调用错误(next 返回后有效)
+}
+
+// Handler 执行调用
+type Handler func(inv *Invocation) error
+
+// Interceptor 拦截器, 不调用 next 即短路
+type Interceptor func(inv *Invocation, next Handler) error
+
+// WithInterceptors 为资源添加拦截器(按顺序由外到内), Uow 不拦截
+func WithInterceptors(resource IResource, interceptors ...Interceptor) IResource {
+	if len(interceptors) == 0 {
+		return resource
+	}
+	if inner, ok := resource.(*interceptResource); ok {
+		return &interceptResource{
+			resource:     inner.resource,
+			interceptors: append(append([]Interceptor{}, inner.interceptors...), interceptors...),
+		}
+	}
+
+	return &interceptResource{
+		resource:     resource,
+		interceptors: interceptors,
+	}
+}
+
+type interceptResource struct {
+	resource     IResource
+	interceptors []Interceptor
+}
+
+func (r *interceptResource) Db(args ...interface{}) IRepository {
+	return &interceptRepository{
+		ctx:          ParseDbArgs(args...).Ctx,
+		dbType:       TypeOf(r.resource),
+		interceptors: r.interceptors,
+		repository:   r.resource.Db(args...),
+	}
+}
+
+func (r *interceptResource) Uow() IUnitOfWork {
+	return r.resource.Uow()
+}
+
+func (r *interceptResource) Unwrap() IResource {
+	return r.resource
+}
+
+type interceptRepository struct {
+	ctx          context.Context
+	dbType       dbtype.Value
+	interceptors []Interceptor
+	repository   IRepository
+}
+
+func (r *interceptRepository) Create(entry IDbModel, args ...interface{}) error {
+	return r.invoke(&Invocation{Op: optype.Create, Entry: entry, Args: args}, func(inv *Invocation) error {
+		return r.repository.Create(inv.Entry, inv.Args...)
+	})
+}
+
+func (r *interceptRepository) Delete(entry IDbModel, args ...interface{}) error {
+	return r.invoke(&Invocation{Op: optype.Delete, Entry: entry, Args: args}, func(inv *Invocation) error {
+		return r.repository.Delete(inv.Entry, inv.Args...)
+	})
+}
+
+func (r *interceptRepository) Update(entry IDbModel, args ...interface{}) error {
+	return r.invoke(&Invocation{Op: optype.Update, Entry: entry, Args: args}, func(inv *Invocation) error {
+		return r.repository.Update(inv.Entry, inv.Args...)
+	})
+}
+
+// Upsert Args 0 为 UpsertOptions
+func (r *interceptRepository) Upsert(entry IDbModel, opts UpsertOptions) (inserted bool, err error) {
+	err = r.invoke(&Invocation{Op: optype.Upsert, Entry: entry, Args: []interface{}{opts}}, func(inv *Invocation) (err error) {
+		if len(inv.Args) > 0 {
+			opts, _ = inv.Args[0].(UpsertOptions)
+		}
+		inserted, err = Upsert(r.repository, inv.Entry, opts)
+		return
+	})
+
+	return
+}
+
+// CreateMany Args 0 为 batchSize
+func (r *interceptRepository) CreateMany(entries []IDbModel, batchSize int) error {
+	return r.invokeMany(optype.CreateMany, entries, batchSize, CreateMany)
+}
+
+func (r *interceptRepository) UpdateMany(entries []IDbModel, batchSize int) error {
+	return r.invokeMany(optype.UpdateMany, entries, batchSize, UpdateMany)
+}
+
+func (r *interceptRepository) DeleteMany(entries []IDbModel, batchSize int) error {
+	return r.invokeMany(optype.DeleteMany, entries, batchSize, DeleteMany)
+}
+
+func (r *interceptRepository) invokeMany(op optype.Value, entries []IDbModel, batchSize int, fn func(IRepository, []IDbModel, int) error) error {
+	inv := &Invocation{Op: op, Entries: entries, Args: []interface{}{batchSize}}
+	if len(entries) > 0 {
+		inv.Table = entries[0].Table()
+	}
+
+	return r.invoke(inv, func(inv *Invocation) error {
+		if len(inv.Args) > 0 {
+			batchSize, _ = inv.Args[0].(int)
+		}
+		return fn(r.repository, inv.Entries, batchSize)
+	})
+}
+
+func (r *interceptRepository) Query() IQuery {
+	return &interceptQuery{
+		query:      r.repository.Query(),
+		repository: r,
+	}
+}
+
+// invoke 由外到内执行拦截器, 最内层调用资源
+func (r *interceptRepository) invoke(inv *Invocation, handler Handler) error {
+	inv.Ctx = r.ctx
+	inv.DbType = r.dbType
+	if inv.Table == "" && inv.Entry != nil {
+		inv.Table = inv.Entry.Table()
+	}
+
+	next := func(inv *Invocation) error {
+		start := time.Now()
+		inv.Err = handler(inv)
+		inv.Duration = time.Since(start)
+		return inv.Err
+	}
+	for index := len(r.interceptors) - 1; index >= 0; index-- {
+		interceptor, inner := r.interceptors[index], next
+		next = func(inv *Invocation) error {
+			return interceptor(inv, inner)
+		}
+	}
+
+	return next(inv)
+}
+
+type interceptQuery struct {
+	query      IQuery
+	repository *interceptRepository
+}
+
+func (q *interceptQuery) Asc(fields ...string) IQuery {
+	q.query = q.query.Asc(fields...)
+	return q
+}
+
+func (q *interceptQuery) Count(entry IDbModel) (count int64, err error) {
+	err = q.repository.invoke(&Invocation{Op: optype.Count, Entry: entry, Query: q.query}, func(inv *Invocation) (err error) {
+		count, err = inv.Query.Count(inv.Entry)
+		return
+	})
+
+	return
+}
+
+func (q *interceptQuery) Desc(fields ...string) IQuery {
+	q.query = q.query.Desc(fields...)
+	return q
+}
+
+func (q *interceptQuery) Exec(res interface{}, args ...interface{}) error {
+	return q.repository.invoke(&Invocation{Op: optype.Exec, Table: tableOf(res), Res: res, Args: args, Query: q.query}, func(inv *Invocation) error {
+		return inv.Query.Exec(inv.Res, inv.Args...)
+	})
+}
+
+func (q *interceptQuery) Fields(args ...interface{}) IQuery {
+	q.query = q.query.Fields(args...)
+	return q
+}
+
+func (q *interceptQuery) Find(res interface{}) error {
+	return q.repository.invoke(&Invocation{Op: optype.Find, Table: tableOf(res), Res: res, Query: q.query}, func(inv *Invocation) error {
+		return inv.Query.Find(inv.Res)
+	})
+}
+
+func (q *interceptQuery) FindPage(res interface{}) (info PageInfo, err error) {
+	err = q.repository.invoke(&Invocation{Op: optype.Find, Table: tableOf(res), Res: res, Query: q.query}, func(inv *Invocation) (err error) {
+		info, err = inv.Query.FindPage(inv.Res)
+		return
+	})
+
+	return
+}
+
+func (q *interceptQuery) First(res interface{}) error {
+	entry, _ := res.(IDbModel)
+	return q.repository.invoke(&Invocation{Op: optype.First, Table: tableOf(res), Entry: entry, Res: res, Query: q.query}, func(inv *Invocation) error {
+		return inv.Query.First(inv.Res)
+	})
+}
+
+func (q *interceptQuery) MustFirst(res interface{}) error {
+	entry, _ := res.(IDbModel)
+	return q.repository.invoke(&Invocation{Op: optype.First, Table: tableOf(res), Entry: entry, Res: res, Query: q.query}, func(inv *Invocation) error {
+		return inv.Query.MustFirst(inv.Res)
+	})
+}
+
+func (q *interceptQuery) Page(page int) IQuery {
+	q.query = q.query.Page(page)
+	return q
+}
+
+func (q *interceptQuery) PageSize(pageSize int) IQuery {
+	q.query = q.query.PageSize(pageSize)
+	return q
+}
+
+func (q *interceptQuery) SetOpts(opts ...interface{}) IQuery {
+	q.query = q.query.SetOpts(opts...)
+	return q
+}
+
+func (q *interceptQuery) ToArray(res interface{}) error {
+	return q.Find(res)
+}
+
+func (q *interceptQuery) Where(args ...interface{}) IQuery {
+	q.query = q.query.Where(args...)
+	return q
+}
+
+func (q *interceptQuery) WithDeleted() IQuery {
+	q.query = q.query.WithDeleted()
+	return q
+}
+
+func (q *interceptQuery) OnlyDeleted() IQuery {
+	q.query = q.query.OnlyDeleted()
+	return q
+}
+
+func (q *interceptQuery) BatchSize(size int) IQuery {
+	q.query = q.query.BatchSize(size)
+	return q
+}
+
+func (q *interceptQuery) After(token string) IQuery {
+	q.query = q.query.After(token)
+	return q
+}
+
+func (q *interceptQuery) Before(token string) IQuery {
+	q.query = q.query.Before(token)
+	return q
+}
+
+func (q *interceptQuery) PageToken() (next, prev string) {
+	return q.query.PageToken()
+}
+
+// Cursor 拦截打开游标, 不拦截逐行读取
+func (q *interceptQuery) Cursor(entry IDbModel) (cursor ICursor, err error) {
+	err = q.repository.invoke(&Invocation{Op: optype.Cursor, Entry: entry, Query: q.query}, func(inv *Invocation) (err error) {
+		cursor, err = inv.Query.Cursor(inv.Entry)
+		return
+	})
+
+	return
+}
+
+// tableOf 结果(模型、模型切片)对应的表名
+func tableOf(res interface{}) string {
+	if entry, ok := ModelOf(res); ok {
+		return entry.Table()
+	}
+
+	return ""
+}
@@ -0,0 +1,152 @@
+package goresource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/optype"
+)
+
+type testRepoResource struct {
+	IResource
+	repository IRepository
+}
+
+func (r testRepoResource) Db(args ...interface{}) IRepository {
+	return r.repository
+}
+
+func (r testRepoResource) DbType() dbtype.Value {
+	return testDbType
+}
+
+// testBulkRepository 记录批量创建的批次
+type testBulkRepository struct {
+	testRepository
+	batches [][]IDbModel
+}
+
+func (r *testBulkRepository) CreateMany(entries []IDbModel, batchSize int) error {
+	return Batches(len(entries), batchSize, func(start, end int) error {
+		r.batches = append(r.batches, entries[start:end])
+		return nil
+	})
+}
+
+func (r *testBulkRepository) UpdateMany(entries []IDbModel, batchSize int) error {
+	return nil
+}
+
+func (r *testBulkRepository) DeleteMany(entries []IDbModel, batchSize int) error {
+	return nil
+}
+
+func Test_WithInterceptors(test *testing.T) {
+	rows := []testPerson{
+		{ID: 1, Name: "name-001"},
+	}
+
+	test.Run("invocation", func(t *testing.T) {
+		invs := make([]Invocation, 0)
+		resource := WithInterceptors(testRepoResource{
+			repository: &testRepository{query: &testQuery{rows: rows}},
+		}, func(inv *Invocation, next Handler) error {
+			err := next(inv)
+			invs = append(invs, *inv)
+			return err
+		})
+
+		ctx := context.WithValue(context.Background(), testDbType, "ctx")
+		db := resource.Db(ctx)
+		var res []testPerson
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 2}, "arg"))
+		a.NoError(db.Query().Where("id = ?", 1).Find(&res))
+		count, err := db.Query().Count(&testPerson{})
+		a.NoError(err)
+		a.Equal(int64(1), count)
+
+		a.Len(invs, 3)
+		a.Equal([]optype.Value{optype.Create, optype.Find, optype.Count}, []optype.Value{invs[0].Op, invs[1].Op, invs[2].Op})
+		for _, inv := range invs {
+			a.Equal("test_person", inv.Table)
+			a.Equal(testDbType, inv.DbType)
+			a.Equal(ctx, inv.Ctx)
+		}
+		a.Equal([]interface{}{"arg"}, invs[0].Args)
+		a.Equal(rows, res)
+	})
+
+	test.Run("bulk", func(t *testing.T) {
+		inner := &testBulkRepository{}
+		invs := make([]Invocation, 0)
+		resource := WithInterceptors(testRepoResource{repository: inner}, func(inv *Invocation, next Handler) error {
+			invs = append(invs, *inv)
+			inv.Args = []interface{}{1}
+			return next(inv)
+		})
+
+		entries := []IDbModel{&testPerson{ID: 1}, &testPerson{ID: 2}}
+		a := assert.New(t)
+		a.NoError(CreateMany(resource.Db(), entries, 10))
+		a.Len(invs, 1)
+		a.Equal(optype.CreateMany, invs[0].Op)
+		a.Equal("test_person", invs[0].Table)
+		a.Equal(entries, invs[0].Entries)
+		a.Equal([]interface{}{10}, invs[0].Args)
+		a.Len(inner.batches, 2)
+		a.Empty(inner.created)
+	})
+
+	test.Run("order and short-circuit", func(t *testing.T) {
+		inner := &testRepository{}
+		calls := make([]string, 0)
+		errDenied := errors.New("denied")
+		resource := WithInterceptors(testRepoResource{repository: inner}, func(inv *Invocation, next Handler) error {
+			calls = append(calls, "outer")
+			return next(inv)
+		})
+		resource = WithInterceptors(resource, func(inv *Invocation, next Handler) error {
+			calls = append(calls, "inner")
+			if inv.Entry.GetID() == int64(0) {
+				return errDenied
+			}
+			return next(inv)
+		})
+
+		a := assert.New(t)
+		a.Equal(errDenied, resource.Db().Create(&testPerson{}))
+		a.Empty(inner.created)
+		a.Equal([]string{"outer", "inner"}, calls)
+	})
+
+	test.Run("modify", func(t *testing.T) {
+		query := &testQuery{rows: rows}
+		resource := WithInterceptors(testRepoResource{
+			repository: &testRepository{query: query},
+		}, func(inv *Invocation, next Handler) error {
+			inv.Query = inv.Query.Where("tenant = ?", 1)
+			return next(inv)
+		})
+
+		var res []testPerson
+		a := assert.New(t)
+		a.NoError(resource.Db().Query().Find(&res))
+		a.Equal([]interface{}{"tenant = ?", 1}, query.where)
+	})
+
+	test.Run("unwrap", func(t *testing.T) {
+		inner := &testPingResource{}
+		resource := WithInterceptors(inner, func(inv *Invocation, next Handler) error {
+			return next(inv)
+		})
+		f := NewByName(map[string]IResource{"main": resource}).(*factory)
+		a := assert.New(t)
+		a.Equal("up", f.Health(context.Background())["main"].Status.String())
+		a.NoError(f.Close())
+		a.Equal(1, inner.closed)
+	})
+}
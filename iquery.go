@@ -6,12 +6,32 @@ type IQuery interface {
 	// Fields 指定筛选字段（根据各资源的使用方式 如: mysql "field1", "field2" mongo bson.M{"field1":1, "field2":1}）
 	Fields(args ...interface{}) IQuery
 	Find(res interface{}) error
+	// FindPage 按 Page、PageSize 查询数据并返回总数(同一筛选条件), res 同 Find
+	FindPage(res interface{}) (PageInfo, error)
+	// First 第一条数据, 未找到时 res 不变且不返回错误
 	First(res interface{}) error
+	// MustFirst 同 First, 未找到时返回 errs.ErrNotFound
+	MustFirst(res interface{}) error
 	Asc(fields ...string) IQuery
 	Desc(fields ...string) IQuery
 	Page(page int) IQuery
 	PageSize(pageSize int) IQuery
 	ToArray(res interface{}) error
+	// Where 筛选条件（各资源原生条件 或 与资源无关的 expr.Expr，由各资源编译为原生形式）
 	Where(args ...interface{}) IQuery
 	SetOpts(opts ...interface{}) IQuery
+	// WithDeleted 包含软删除的数据 (ISoftDeletable)
+	WithDeleted() IQuery
+	// OnlyDeleted 仅软删除的数据 (ISoftDeletable)
+	OnlyDeleted() IQuery
+	// BatchSize 游标每次从资源读取的行数
+	BatchSize(size int) IQuery
+	// Cursor 游标逐行读取结果(不一次加载全部结果), entry 为结果模型
+	Cursor(entry IDbModel) (ICursor, error)
+	// After keyset 分页, 读取 token 之后的一页(按 Asc/Desc 字段 + 主键 排序), token 为空时为第一页
+	After(token string) IQuery
+	// Before keyset 分页, 读取 token 之前的一页
+	Before(token string) IQuery
+	// PageToken 最近一次 keyset 分页 Find 的下一页、上一页 token, 没有时为空
+	PageToken() (next, prev string)
 }
@@ -1,7 +1,53 @@
 package goresource
 
+import (
+	"context"
+
+	"github.com/xm-chentl/goresource/dbtype"
+)
+
 // IFactory 数据库实现
 type IResource interface {
 	Db(...interface{}) IRepository
 	Uow() IUnitOfWork
 }
+
+// IPinger 可选, 检测资源连接
+type IPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// IDbTyper 可选, 资源类型
+type IDbTyper interface {
+	DbType() dbtype.Value
+}
+
+// IUnwrapper 包装资源(拦截器等)返回被包装的资源
+type IUnwrapper interface {
+	Unwrap() IResource
+}
+
+// TypeOf 资源类型, 未实现 IDbTyper 时为空
+func TypeOf(resource IResource) dbtype.Value {
+	if typer, ok := as[IDbTyper](resource); ok {
+		return typer.DbType()
+	}
+
+	return ""
+}
+
+// as 沿包装链查找实现 T 的资源
+func as[T any](resource IResource) (res T, ok bool) {
+	for resource != nil {
+		if res, ok = resource.(T); ok {
+			return
+		}
+		unwrapper, isWrapper := resource.(IUnwrapper)
+		if !isWrapper {
+			return
+		}
+		resource = unwrapper.Unwrap()
+	}
+
+	return
+}
@@ -1,5 +1,59 @@
 package goresource
 
+import (
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/repositorytype"
+	"github.com/xm-chentl/goresource/uowstatus"
+)
+
 type IUnitOfWork interface {
+	// Commit 提交队列，Discard/Rollback 后提交返回 errs.UowDiscarded
+	// 支持事务的资源提交失败时保留队列，可再次 Commit 在新事务中重放
 	Commit() error
+	// Rollback 放弃队列，已 Prepare 的事务回滚
+	Rollback() error
+	// Discard 放弃队列(同 Rollback，忽略回滚错误)
+	Discard()
+	// Pending 待提交数量 (按操作类型)
+	Pending() map[repositorytype.Value]int
+	Status() uowstatus.Value
+}
+
+// IPrepareUnitOfWork 支持两阶段提交的工作单元
+// Prepare 开启事务并执行队列(不提交)，不支持事务时返回 errs.UowPrepareNotSupported
+type IPrepareUnitOfWork interface {
+	Prepare() error
+	CommitPrepared() error
+	RollbackPrepared() error
+}
+
+// CompensateFunc 补偿操作
+type CompensateFunc func() error
+
+// ICompensable 支持补偿的工作单元，资源已提交而整体失败时执行对应资源的补偿操作
+type ICompensable interface {
+	Compensate(dbType dbtype.Value, fn CompensateFunc)
+}
+
+// ICommitHook 支持提交回调的工作单元, fn 在提交成功后执行
+type ICommitHook interface {
+	OnCommitted(fn func())
+}
+
+// CommitHooks 工作单元嵌入以实现 ICommitHook
+type CommitHooks struct {
+	fns []func()
+}
+
+func (h *CommitHooks) OnCommitted(fn func()) {
+	h.fns = append(h.fns, fn)
+}
+
+// RunCommitted 提交成功后调用, 执行后清空
+func (h *CommitHooks) RunCommitted() {
+	fns := h.fns
+	h.fns = nil
+	for _, fn := range fns {
+		fn()
+	}
 }
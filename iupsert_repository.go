@@ -0,0 +1,33 @@
+package goresource
+
+import "github.com/xm-chentl/goresource/errs"
+
+// UpsertOptions 插入或更新选项, 字段为各资源的字段名(postgres 列名、bson 名、gorm 列名)
+type UpsertOptions struct {
+	// ConflictFields 判断已存在的字段(唯一约束), 为空时为主键; mysql 按表的任一唯一键判断
+	ConflictFields []string
+	// UpdateFields 已存在时更新的字段, 为空时更新冲突字段以外的全部字段
+	UpdateFields []string
+}
+
+// IUpsertRepository 插入或更新(可选), 不存在时插入, 已存在时更新 UpdateFields; inserted 为 true 时为插入
+// 工作单元中加入队列, 提交时执行, inserted 为 false; 不校验乐观锁版本
+type IUpsertRepository interface {
+	Upsert(entry IDbModel, opts UpsertOptions) (inserted bool, err error)
+}
+
+// Upsert repository 为 IUpsertRepository 时插入或更新, 否则返回 errs.UpsertNotSupported
+func Upsert(repository IRepository, entry IDbModel, opts ...UpsertOptions) (inserted bool, err error) {
+	upsert, ok := repository.(IUpsertRepository)
+	if !ok {
+		err = errs.UpsertNotSupported
+		return
+	}
+
+	opt := UpsertOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	return upsert.Upsert(entry, opt)
+}
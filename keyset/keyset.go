@@ -0,0 +1,253 @@
+package keyset
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/tools"
+)
+
+var (
+	rw     sync.RWMutex
+	secret []byte
+)
+
+// SetSecret 设置 token 签名密钥(多实例须相同), 未设置时生成、解析 token 返回 errs.KeysetSecretRequired
+func SetSecret(key []byte) {
+	rw.Lock()
+	defer rw.Unlock()
+
+	secret = append([]byte{}, key...)
+}
+
+func sign(payload []byte) ([]byte, error) {
+	rw.RLock()
+	defer rw.RUnlock()
+
+	if len(secret) == 0 {
+		return nil, errs.KeysetSecretRequired
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+// Key 排序字段, Field 为 expr.PK 时为主键
+type Key struct {
+	Field string
+	Desc  bool
+}
+
+// Page keyset 分页, 按排序字段 + 主键 读取 token 之后(之前)的数据
+type Page struct {
+	Table  string
+	Keys   []Key
+	Values []interface{} // token 中的值, 为空时为第一页
+	Before bool
+}
+
+// tokenPayload 表名、排序字段与值一起签名, 用于其他表或排序时 token 无效
+type tokenPayload struct {
+	Table  string            `json:"t"`
+	Keys   string            `json:"k"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// New 排序字段追加主键, token 不为空时校验并解析为 model 对应字段类型的值
+func New(orders []Key, token string, before bool, model goresource.IDbModel) (page *Page, err error) {
+	page = &Page{
+		Table:  model.Table(),
+		Keys:   append(append(make([]Key, 0, len(orders)+1), orders...), Key{Field: expr.PK}),
+		Before: before,
+	}
+	if token == "" {
+		return
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, errs.KeysetTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errs.KeysetTokenInvalid
+	}
+	expected, err := sign(payload)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(mac, expected) {
+		return nil, errs.KeysetTokenInvalid
+	}
+
+	var p tokenPayload
+	if err = json.Unmarshal(payload, &p); err != nil || p.Table != page.Table || p.Keys != page.signature() || len(p.Values) != len(page.Keys) {
+		return nil, errs.KeysetTokenInvalid
+	}
+	rv := reflect.New(reflect.Indirect(reflect.ValueOf(model)).Type())
+	for index, key := range page.Keys {
+		fieldRv, ok := valueOf(rv.Interface().(goresource.IDbModel), key.Field)
+		if !ok {
+			return nil, errs.KeysetTokenInvalid
+		}
+		value := reflect.New(fieldRv.Type())
+		if string(p.Values[index]) == "null" {
+			return nil, errs.KeysetNullKey
+		}
+		if err = json.Unmarshal(p.Values[index], value.Interface()); err != nil {
+			return nil, errs.KeysetTokenInvalid
+		}
+		page.Values = append(page.Values, value.Elem().Interface())
+	}
+
+	return
+}
+
+// Orders 查询的排序 (Before 时反向, 结果须 Reverse)
+func (p *Page) Orders() []Key {
+	res := make([]Key, 0, len(p.Keys))
+	for _, key := range p.Keys {
+		res = append(res, Key{Field: key.Field, Desc: key.Desc != p.Before})
+	}
+
+	return res
+}
+
+// Cond token 对应的条件, 第一页为 nil
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) ... 降序时为 <
+func (p *Page) Cond() expr.Expr {
+	if len(p.Values) == 0 {
+		return nil
+	}
+
+	items := make([]expr.Expr, 0, len(p.Keys))
+	orders := p.Orders()
+	for index, key := range orders {
+		conds := make([]expr.Expr, 0, index+1)
+		for prev := 0; prev < index; prev++ {
+			conds = append(conds, expr.Eq(orders[prev].Field, p.Values[prev]))
+		}
+		if key.Desc {
+			conds = append(conds, expr.Lt(key.Field, p.Values[index]))
+		} else {
+			conds = append(conds, expr.Gt(key.Field, p.Values[index]))
+		}
+		items = append(items, expr.And(conds...))
+	}
+
+	return expr.Or(items...)
+}
+
+// Limit 查询的数量, 多读一条用于判断是否还有数据; pageSize 为 0 时不限制
+func Limit(pageSize int) int {
+	if pageSize <= 0 {
+		return 0
+	}
+
+	return pageSize + 1
+}
+
+// Result 处理按 Orders、Limit 查询的结果(切片指针): 去掉多读的一条, Before 时恢复原顺序, 返回翻页 token, 没有更多数据时为空
+func (p *Page) Result(rows interface{}, pageSize int) (next, prev string, err error) {
+	rv := reflect.ValueOf(rows).Elem()
+	hasMore := pageSize > 0 && rv.Len() > pageSize
+	if hasMore {
+		rv.Set(rv.Slice(0, pageSize))
+	}
+	if p.Before {
+		reverse(rv)
+	}
+	if rv.Len() == 0 {
+		return
+	}
+
+	// Before 时 hasMore 为之前还有数据, 有 token 时 token 所在的一侧有数据
+	if hasMore && !p.Before || p.Before && len(p.Values) > 0 {
+		if next, err = p.encode(rv.Index(rv.Len() - 1).Interface()); err != nil {
+			return
+		}
+	}
+	if hasMore && p.Before || !p.Before && len(p.Values) > 0 {
+		prev, err = p.encode(rv.Index(0).Interface())
+	}
+
+	return
+}
+
+func (p *Page) encode(row interface{}) (token string, err error) {
+	entry, ok := row.(goresource.IDbModel)
+	if !ok {
+		rv := reflect.ValueOf(row)
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		if entry, ok = ptr.Interface().(goresource.IDbModel); !ok {
+			return "", errs.ResIsNotIDbModel
+		}
+	}
+
+	payload := tokenPayload{Table: p.Table, Keys: p.signature()}
+	for _, key := range p.Keys {
+		rv, ok := valueOf(entry, key.Field)
+		if !ok {
+			return "", errs.KeysetTokenInvalid
+		}
+		// NULL 无法比较大小, 排序字段须非空
+		if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil() {
+			return "", errs.KeysetNullKey
+		}
+		raw, marshalErr := json.Marshal(rv.Interface())
+		if marshalErr != nil {
+			return "", marshalErr
+		}
+		payload.Values = append(payload.Values, raw)
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	mac, err := sign(b)
+	if err != nil {
+		return
+	}
+	token = base64.RawURLEncoding.EncodeToString(b) + "." + base64.RawURLEncoding.EncodeToString(mac)
+
+	return
+}
+
+// signature 排序字段签名, 排序变化后 token 无效
+func (p *Page) signature() string {
+	fields := make([]string, 0, len(p.Keys))
+	for _, key := range p.Keys {
+		if key.Desc {
+			fields = append(fields, "-"+key.Field)
+		} else {
+			fields = append(fields, key.Field)
+		}
+	}
+
+	return strings.Join(fields, ",")
+}
+
+func reverse(rv reflect.Value) {
+	swap := reflect.Swapper(rv.Interface())
+	for i, j := 0, rv.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
+
+func valueOf(entry goresource.IDbModel, field string) (reflect.Value, bool) {
+	if field == expr.PK {
+		return reflect.ValueOf(entry.GetID()), entry.GetID() != nil
+	}
+
+	return tools.FieldValue(entry, field)
+}
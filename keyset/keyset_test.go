@@ -0,0 +1,104 @@
+package keyset
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPerson struct {
+	ID  int64
+	Age int
+}
+
+func (t testPerson) GetID() interface{} {
+	return t.ID
+}
+
+func (t *testPerson) SetID(v interface{}) {
+	if vv, ok := v.(int64); ok {
+		t.ID = vv
+	}
+}
+
+func (t testPerson) Table() string {
+	return "test_person"
+}
+
+type testOrder struct {
+	testPerson
+	PaidAt *time.Time
+}
+
+func (t testOrder) Table() string {
+	return "test_order"
+}
+
+func Test_New(test *testing.T) {
+	SetSecret([]byte("test"))
+	orders := []Key{{Field: "age", Desc: true}}
+	page, _ := New(orders, "", false, &testPerson{})
+	rows := []testPerson{{ID: 1, Age: 30}, {ID: 2, Age: 20}, {ID: 3, Age: 20}}
+	next, _, err := page.Result(&rows, 2)
+	if err != nil {
+		test.Fatal("err", err)
+	}
+
+	test.Run("cond", func(t *testing.T) {
+		page, err := New(orders, next, false, &testPerson{})
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal([]interface{}{20, int64(2)}, page.Values)
+		a.Equal(expr.Or(
+			expr.And(expr.Lt("age", 20)),
+			expr.And(expr.Eq("age", 20), expr.Gt(expr.PK, int64(2))),
+		), page.Cond())
+	})
+
+	test.Run("before", func(t *testing.T) {
+		page, err := New(orders, next, true, &testPerson{})
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal([]Key{{Field: "age"}, {Field: expr.PK, Desc: true}}, page.Orders())
+	})
+
+	test.Run("tampered", func(t *testing.T) {
+		payload := strings.Split(next, ".")[0]
+		_, err := New(orders, payload+"."+strings.Split(next, ".")[0], false, &testPerson{})
+		assert.ErrorIs(t, err, errs.KeysetTokenInvalid)
+	})
+
+	test.Run("orders changed", func(t *testing.T) {
+		_, err := New([]Key{{Field: "age"}}, next, false, &testPerson{})
+		assert.ErrorIs(t, err, errs.KeysetTokenInvalid)
+	})
+
+	test.Run("table changed", func(t *testing.T) {
+		_, err := New(orders, next, false, &testOrder{})
+		assert.ErrorIs(t, err, errs.KeysetTokenInvalid)
+	})
+
+	test.Run("null key", func(t *testing.T) {
+		page, _ := New([]Key{{Field: "paidAt"}}, "", false, &testOrder{})
+		rows := []testOrder{{testPerson: testPerson{ID: 1}}, {testPerson: testPerson{ID: 2}}}
+		_, _, err := page.Result(&rows, 1)
+		assert.ErrorIs(t, err, errs.KeysetNullKey)
+	})
+
+	test.Run("secret required", func(t *testing.T) {
+		SetSecret(nil)
+		defer SetSecret([]byte("test"))
+
+		a := assert.New(t)
+		_, err := New(orders, next, false, &testPerson{})
+		a.ErrorIs(err, errs.KeysetSecretRequired)
+		rows := []testPerson{{ID: 1, Age: 30}, {ID: 2, Age: 20}, {ID: 3, Age: 20}}
+		_, _, err = page.Result(&rows, 2)
+		a.ErrorIs(err, errs.KeysetSecretRequired)
+	})
+}
@@ -0,0 +1,97 @@
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type item struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+// Cache 带过期时间的 LRU 缓存(并发安全), 实现 goresource.ICache
+type Cache struct {
+	mutex sync.Mutex
+	size  int
+	ttl   time.Duration
+	items *list.List
+	keys  map[string]*list.Element
+	now   func() time.Time
+}
+
+func (c *Cache) Get(key string) (value interface{}, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.keys[key]
+	if !ok {
+		return
+	}
+	v := element.Value.(*item)
+	if c.ttl > 0 && !c.now().Before(v.expireAt) {
+		c.remove(element)
+		return nil, false
+	}
+	c.items.MoveToFront(element)
+
+	return v.value, true
+}
+
+func (c *Cache) Set(key string, value interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expireAt := c.now().Add(c.ttl)
+	if element, ok := c.keys[key]; ok {
+		v := element.Value.(*item)
+		v.value = value
+		v.expireAt = expireAt
+		c.items.MoveToFront(element)
+		return
+	}
+
+	c.keys[key] = c.items.PushFront(&item{
+		key:      key,
+		value:    value,
+		expireAt: expireAt,
+	})
+	if c.size > 0 && c.items.Len() > c.size {
+		c.remove(c.items.Back())
+	}
+}
+
+func (c *Cache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.keys[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Len 缓存数量(含未清理的过期项)
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.items.Len()
+}
+
+func (c *Cache) remove(element *list.Element) {
+	c.items.Remove(element)
+	delete(c.keys, element.Value.(*item).key)
+}
+
+// New size 最大数量(0 不限制) ttl 过期时间(0 不过期)
+func New(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:  size,
+		ttl:   ttl,
+		items: list.New(),
+		keys:  make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
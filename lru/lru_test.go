@@ -0,0 +1,51 @@
+package lru
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Cache(test *testing.T) {
+	test.Run("evict least recently used", func(t *testing.T) {
+		c := New(2, 0)
+		c.Set("a", 1)
+		c.Set("b", 2)
+		c.Get("a")
+		c.Set("c", 3)
+
+		a := assert.New(t)
+		_, ok := c.Get("b")
+		a.False(ok)
+		v, ok := c.Get("a")
+		a.True(ok)
+		a.Equal(1, v)
+		a.Equal(2, c.Len())
+	})
+
+	test.Run("ttl", func(t *testing.T) {
+		c := New(0, time.Minute)
+		now := time.Now()
+		c.now = func() time.Time {
+			return now
+		}
+		c.Set("a", 1)
+
+		a := assert.New(t)
+		_, ok := c.Get("a")
+		a.True(ok)
+		now = now.Add(time.Minute)
+		_, ok = c.Get("a")
+		a.False(ok)
+		a.Equal(0, c.Len())
+	})
+
+	test.Run("delete", func(t *testing.T) {
+		c := New(0, 0)
+		c.Set("a", 1)
+		c.Delete("a")
+		_, ok := c.Get("a")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,156 @@
+package memoryex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/lru"
+	"github.com/xm-chentl/goresource/optype"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithCache(test *testing.T) {
+	test.Run("hit", func(t *testing.T) {
+		inner := New()
+		resource := goresource.WithCache(inner, lru.New(100, time.Minute))
+		a := assert.New(t)
+		a.NoError(resource.Db().Create(&testPerson{ID: 1, Name: "cache_001"}))
+
+		res := testPerson{ID: 1}
+		a.NoError(resource.Db().Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal("cache_001", res.Name)
+
+		// 绕过缓存修改
+		a.NoError(inner.Db().Update(&testPerson{ID: 1, Name: "changed"}))
+		res = testPerson{}
+		a.NoError(resource.Db().Query().Where(expr.ByID(int64(1))).First(&res))
+		a.Equal("cache_001", res.Name)
+		a.Equal(goresource.CacheStats{Hits: 1, Misses: 1}, resource.Stats())
+
+		// 其他条件不使用缓存
+		res = testPerson{}
+		a.NoError(resource.Db().Query().Where(expr.Eq("name", "changed")).First(&res))
+		a.Equal("changed", res.Name)
+	})
+
+	test.Run("entry id without where", func(t *testing.T) {
+		inner := New()
+		resource := goresource.WithCache(inner, lru.New(100, time.Minute))
+		a := assert.New(t)
+		a.NoError(resource.Db().Create(&testPerson{ID: 1, Name: "cache_001"}))
+		a.NoError(resource.Db().Create(&testPerson{ID: 2, Name: "cache_002"}))
+
+		// 与未包装的资源一致, 不使用缓存
+		res, expected := testPerson{ID: 2}, testPerson{ID: 2}
+		a.NoError(resource.Db().Query().First(&res))
+		a.NoError(inner.Db().Query().First(&expected))
+		a.Equal(expected, res)
+		a.Equal(goresource.CacheStats{}, resource.Stats())
+	})
+
+	test.Run("invalidate during read", func(t *testing.T) {
+		var resource goresource.ICacheResource
+		writing := false
+		inner := goresource.WithInterceptors(New(), func(inv *goresource.Invocation, next goresource.Handler) error {
+			err := next(inv)
+			// 读取后、写入缓存前有更新
+			if inv.Op == optype.First && !writing {
+				writing = true
+				_ = resource.Db().Update(&testPerson{ID: 1, Name: "update"})
+			}
+			return err
+		})
+		resource = goresource.WithCache(inner, lru.New(100, time.Minute))
+		a := assert.New(t)
+		a.NoError(inner.Db().Create(&testPerson{ID: 1, Name: "cache_001"}))
+
+		res := testPerson{}
+		a.NoError(resource.Db().Query().Where(expr.ByID(int64(1))).First(&res))
+		a.Equal("cache_001", res.Name)
+		res = testPerson{}
+		a.NoError(resource.Db().Query().Where(expr.ByID(int64(1))).First(&res))
+		a.Equal("update", res.Name)
+	})
+
+	test.Run("not found", func(t *testing.T) {
+		resource := goresource.WithCache(New(), lru.New(100, time.Minute))
+		res := testPerson{ID: 1}
+		a := assert.New(t)
+		a.NoError(resource.Db().Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal(testPerson{ID: 1}, res)
+		a.ErrorIs(resource.Db().Query().Where(expr.ByID(res.ID)).MustFirst(&res), errs.ErrNotFound)
+
+		a.NoError(resource.Db().Create(&testPerson{ID: 1, Name: "cache_001"}))
+		a.NoError(resource.Db().Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal("cache_001", res.Name)
+	})
+
+	test.Run("invalidate", func(t *testing.T) {
+		resource := goresource.WithCache(New(), lru.New(100, time.Minute))
+		db := resource.Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 1, Name: "cache_001"}))
+		a.NoError(db.Create(&testPerson{ID: 2, Name: "cache_002", Age: 11}))
+		a.NoError(db.Query().Where(expr.ByID(int64(1))).First(&testPerson{}))
+		a.NoError(db.Query().Where(expr.ByID(int64(2))).First(&testPerson{}))
+
+		a.NoError(db.Update(&testPerson{ID: 1, Name: "update"}))
+		res := testPerson{ID: 1}
+		a.NoError(db.Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal("update", res.Name)
+
+		a.NoError(db.Delete(&testPerson{}, expr.Eq("age", 11)))
+		res = testPerson{ID: 2}
+		a.NoError(db.Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal("", res.Name)
+	})
+
+	test.Run("invalidate after commit", func(t *testing.T) {
+		resource := goresource.WithCache(New(), lru.New(100, time.Minute))
+		a := assert.New(t)
+		a.NoError(resource.Db().Create(&testPerson{ID: 1, Name: "cache_001"}))
+		a.NoError(resource.Db().Query().Where(expr.ByID(int64(1))).First(&testPerson{}))
+
+		uow := goresource.Uow()
+		a.NoError(resource.Db(uow).Update(&testPerson{ID: 1, Name: "update"}))
+		res := testPerson{ID: 1}
+		a.NoError(resource.Db().Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal("cache_001", res.Name)
+
+		a.NoError(uow.Commit())
+		res = testPerson{ID: 1}
+		a.NoError(resource.Db().Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal("update", res.Name)
+	})
+
+	test.Run("invalidate bulk", func(t *testing.T) {
+		resource := goresource.WithCache(New(), lru.New(100, time.Minute))
+		db := resource.Db()
+		a := assert.New(t)
+		a.NoError(goresource.CreateMany(db, []goresource.IDbModel{
+			&testPerson{ID: 1, Name: "cache_001"},
+			&testPerson{ID: 2, Name: "cache_002"},
+		}, 10))
+		a.NoError(db.Query().Where(expr.ByID(int64(1))).First(&testPerson{}))
+		a.NoError(db.Query().Where(expr.ByID(int64(2))).First(&testPerson{}))
+
+		uow := goresource.Uow()
+		a.NoError(goresource.UpdateMany(resource.Db(uow), []goresource.IDbModel{&testPerson{ID: 1, Name: "update"}}, 10))
+		a.NoError(goresource.DeleteMany(resource.Db(uow), []goresource.IDbModel{&testPerson{ID: 2}}, 10))
+		res := testPerson{ID: 1}
+		a.NoError(db.Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal("cache_001", res.Name)
+
+		a.NoError(uow.Commit())
+		res = testPerson{ID: 1}
+		a.NoError(db.Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal("update", res.Name)
+		res = testPerson{ID: 2}
+		a.NoError(db.Query().Where(expr.ByID(res.ID)).First(&res))
+		a.Equal("", res.Name)
+	})
+}
@@ -0,0 +1,13 @@
+package memoryex
+
+import (
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+)
+
+// 内存资源忽略 dsn 与连接池配置
+func init() {
+	goresource.Register(dbtype.Memory, goresource.DriverFunc(func(cfg goresource.ResourceConfig) (goresource.IResource, error) {
+		return New(), nil
+	}))
+}
@@ -0,0 +1,14 @@
+package memoryex
+
+import (
+	"errors"
+
+	"github.com/xm-chentl/goresource/errs"
+)
+
+var (
+	ErrDuplicateID      = errs.Wrap(errs.ErrDuplicateKey, errors.New("memoryex: duplicate id"))
+	ErrIDEmpty          = errors.New("memoryex: id is empty and can not be generated")
+	ErrExecNotSupported = errors.New("memoryex: exec not supported")
+	ErrDeletedAtType    = errors.New("memoryex: deleted at field must be time.Time or *time.Time")
+)
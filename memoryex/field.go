@@ -0,0 +1,171 @@
+package memoryex
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/tools"
+)
+
+func match(entry interface{}, e expr.Expr) bool {
+	switch v := e.(type) {
+	case expr.Cond:
+		return matchCond(entry, v)
+	case expr.Logic:
+		for _, item := range v.Items {
+			ok := match(entry, item)
+			if v.Op == expr.OpOr && ok {
+				return true
+			}
+			if v.Op == expr.OpAnd && !ok {
+				return false
+			}
+		}
+		return v.Op == expr.OpAnd
+	case expr.Negation:
+		return !match(entry, v.Item)
+	}
+
+	return true
+}
+
+func matchCond(entry interface{}, c expr.Cond) bool {
+	rv, ok := tools.FieldValue(entry, c.Field)
+	if model, isModel := entry.(goresource.IDbModel); isModel && c.Field == expr.PK {
+		rv, ok = reflect.ValueOf(model.GetID()), model.GetID() != nil
+	}
+	isNull := !ok || ((rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil())
+	if c.Op == expr.OpIsNull {
+		return isNull
+	}
+	if isNull {
+		return false
+	}
+
+	value := reflect.Indirect(rv).Interface()
+	switch c.Op {
+	case expr.OpIn:
+		values, _ := c.Value.([]interface{})
+		for _, item := range values {
+			if res, ok := compare(value, item); ok && res == 0 {
+				return true
+			}
+		}
+		return false
+	case expr.OpLike:
+		pattern, _ := c.Value.(string)
+		matched, _ := regexp.MatchString(expr.LikeToRegexp(pattern), fmt.Sprint(value))
+		return matched
+	}
+
+	res, ok := compare(value, c.Value)
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case expr.OpEq:
+		return res == 0
+	case expr.OpNe:
+		return res != 0
+	case expr.OpGt:
+		return res > 0
+	case expr.OpGte:
+		return res >= 0
+	case expr.OpLt:
+		return res < 0
+	case expr.OpLte:
+		return res <= 0
+	}
+
+	return false
+}
+
+// compare 比较两个值 ok 为 false 时不可比较
+func compare(a, b interface{}) (res int, ok bool) {
+	if a == nil || b == nil {
+		return 0, a == b
+	}
+
+	av := reflect.Indirect(reflect.ValueOf(a))
+	bv := reflect.Indirect(reflect.ValueOf(b))
+	if !av.IsValid() || !bv.IsValid() {
+		return 0, !av.IsValid() && !bv.IsValid()
+	}
+	if at, isTime := av.Interface().(time.Time); isTime {
+		bt, isTime := bv.Interface().(time.Time)
+		if !isTime {
+			return
+		}
+		return compareOrdered(at.UnixNano(), bt.UnixNano()), true
+	}
+
+	switch {
+	case isInt(av.Kind()) && isInt(bv.Kind()):
+		return compareOrdered(av.Int(), bv.Int()), true
+	case isUint(av.Kind()) && isUint(bv.Kind()):
+		return compareOrdered(av.Uint(), bv.Uint()), true
+	case isNumber(av.Kind()) && isNumber(bv.Kind()):
+		return compareOrdered(toFloat(av), toFloat(bv)), true
+	case av.Kind() == reflect.String && bv.Kind() == reflect.String:
+		return strings.Compare(av.String(), bv.String()), true
+	case av.Kind() == reflect.Bool && bv.Kind() == reflect.Bool:
+		if av.Bool() == bv.Bool() {
+			return 0, true
+		}
+		if !av.Bool() {
+			return -1, true
+		}
+		return 1, true
+	}
+	if av.Type() == bv.Type() && av.Type().Comparable() {
+		if av.Interface() == bv.Interface() {
+			return 0, true
+		}
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b)), true
+	}
+
+	return
+}
+
+type ordered interface {
+	~int64 | ~uint64 | ~float64
+}
+
+func compareOrdered[T ordered](a, b T) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+
+	return 0
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isNumber(k reflect.Kind) bool {
+	return isInt(k) || isUint(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+func toFloat(rv reflect.Value) float64 {
+	switch {
+	case isInt(rv.Kind()):
+		return float64(rv.Int())
+	case isUint(rv.Kind()):
+		return float64(rv.Uint())
+	}
+
+	return rv.Float()
+}
@@ -0,0 +1,424 @@
+package memoryex
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/deletedscope"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/keyset"
+	"github.com/xm-chentl/goresource/tools"
+)
+
+type order struct {
+	field string
+	desc  bool
+}
+
+type cursor struct {
+	rows  []goresource.IDbModel
+	index int
+}
+
+func (c *cursor) Next() bool {
+	if c.index+1 >= len(c.rows) {
+		return false
+	}
+	c.index++
+
+	return true
+}
+
+func (c *cursor) Scan(res interface{}) error {
+	rv := reflect.ValueOf(res)
+	if rv.Kind() != reflect.Ptr || c.index < 0 || c.index >= len(c.rows) {
+		return errs.ResIsNotPtr
+	}
+
+	row := reflect.ValueOf(c.rows[c.index]).Elem()
+	if rv.Elem().Type() != row.Type() {
+		return errs.ResIsNotIDbModel
+	}
+	rv.Elem().Set(row)
+
+	return nil
+}
+
+func (c *cursor) Err() error {
+	return nil
+}
+
+func (c *cursor) Close() error {
+	c.rows = nil
+	return nil
+}
+
+type pageToken struct {
+	token  string
+	before bool
+}
+
+type query struct {
+	ctx        context.Context
+	store      *store
+	filter     func(goresource.IDbModel) bool
+	err        error
+	page       int
+	pageSize   int
+	orders     []order
+	deleted    deletedscope.Value
+	pageToken  *pageToken
+	next, prev string
+}
+
+func (q *query) Asc(fields ...string) goresource.IQuery {
+	for _, field := range fields {
+		if field != "" {
+			q.orders = append(q.orders, order{field: field})
+		}
+	}
+
+	return q
+}
+
+func (q *query) Count(entry goresource.IDbModel) (count int64, err error) {
+	defer q.reset()
+
+	if err = q.err; err != nil {
+		return
+	}
+	q.store.read(func(s state) {
+		count = int64(len(s.rows(entry.Table(), q.scoped(entry))))
+	})
+
+	return
+}
+
+func (q *query) Desc(fields ...string) goresource.IQuery {
+	for _, field := range fields {
+		if field != "" {
+			q.orders = append(q.orders, order{field: field, desc: true})
+		}
+	}
+
+	return q
+}
+
+// Exec 内存资源不支持原生语句
+func (q query) Exec(res interface{}, args ...interface{}) error {
+	return ErrExecNotSupported
+}
+
+// Fields 内存资源返回完整数据
+func (q *query) Fields(args ...interface{}) goresource.IQuery {
+	return q
+}
+
+func (q *query) Find(res interface{}) (err error) {
+	defer q.reset()
+
+	_, err = q.find(res)
+
+	return
+}
+
+// FindPage 同一次读取的筛选结果计算总数
+func (q *query) FindPage(res interface{}) (info goresource.PageInfo, err error) {
+	defer q.reset()
+
+	total, err := q.find(res)
+	if err != nil {
+		return
+	}
+	info = goresource.NewPageInfo(total, q.page, q.pageSize)
+
+	return
+}
+
+// find total 为分页前的数量
+func (q *query) find(res interface{}) (total int64, err error) {
+	resRt := reflect.TypeOf(res)
+	if resRt.Kind() != reflect.Ptr {
+		err = errs.ResIsNotPtr
+		return
+	}
+	if resRt.Elem().Kind() != reflect.Slice {
+		err = errs.ResIsNotSlice
+		return
+	}
+	if err = q.err; err != nil {
+		return
+	}
+
+	elemRt := resRt.Elem().Elem()
+	structRt := elemRt
+	if structRt.Kind() == reflect.Ptr {
+		structRt = structRt.Elem()
+	}
+	entry, ok := reflect.New(structRt).Interface().(goresource.IDbModel)
+	if !ok {
+		err = errs.ResIsNotIDbModel
+		return
+	}
+
+	var page *keyset.Page
+	if q.pageToken != nil {
+		q.next, q.prev = "", ""
+		if page, err = keyset.New(q.keys(), q.pageToken.token, q.pageToken.before, entry); err != nil {
+			return
+		}
+	}
+
+	rows, total := q.rows(entry, page)
+	results := reflect.MakeSlice(resRt.Elem(), 0, len(rows))
+	for _, row := range rows {
+		rowRv := reflect.ValueOf(clone(row))
+		if elemRt.Kind() != reflect.Ptr {
+			rowRv = rowRv.Elem()
+		}
+		results = reflect.Append(results, rowRv)
+	}
+	reflect.ValueOf(res).Elem().Set(results)
+	if page != nil {
+		q.next, q.prev, err = page.Result(res, q.pageSize)
+	}
+
+	return
+}
+
+// First 未设置条件且模型主键有值时按主键查询
+func (q *query) First(res interface{}) (err error) {
+	if err = q.MustFirst(res); errors.Is(err, errs.ErrNotFound) {
+		err = nil
+	}
+
+	return
+}
+
+func (q *query) MustFirst(res interface{}) (err error) {
+	defer q.reset()
+
+	entry, ok := res.(goresource.IDbModel)
+	if !ok || reflect.TypeOf(res).Kind() != reflect.Ptr {
+		err = errs.ResIsNotIDbModel
+		return
+	}
+	if err = q.err; err != nil {
+		return
+	}
+
+	var row goresource.IDbModel
+	if q.filter == nil && !tools.IsEmpty(entry.GetID()) {
+		q.store.read(func(s state) {
+			if t, ok := s[entry.Table()]; ok {
+				row = t.rows[idKey(entry.GetID())]
+			}
+			if filter := q.scoped(entry); row != nil && filter != nil && !filter(row) {
+				row = nil
+			}
+		})
+	} else {
+		q.pageSize = 1
+		if rows, _ := q.rows(entry, nil); len(rows) > 0 {
+			row = rows[0]
+		}
+	}
+	if row == nil {
+		err = errs.ErrNotFound
+		return
+	}
+	reflect.ValueOf(res).Elem().Set(reflect.ValueOf(row).Elem())
+
+	return
+}
+
+func (q *query) Page(page int) goresource.IQuery {
+	q.page = page
+	if q.page < 1 {
+		q.page = 1
+	}
+
+	return q
+}
+
+func (q *query) PageSize(pageSize int) goresource.IQuery {
+	q.pageSize = pageSize
+	if q.pageSize < 0 {
+		q.pageSize = 0
+	}
+
+	return q
+}
+
+func (q *query) SetOpts(opts ...interface{}) goresource.IQuery {
+	return q
+}
+
+func (q *query) ToArray(res interface{}) error {
+	return q.Find(res)
+}
+
+// Where args 0 expr.Expr 或 func(goresource.IDbModel) bool
+func (q *query) Where(args ...interface{}) goresource.IQuery {
+	if len(args) > 0 {
+		q.filter, q.err = toFilter(args[0])
+	}
+
+	return q
+}
+
+// BatchSize 内存资源不分批
+func (q *query) BatchSize(size int) goresource.IQuery {
+	return q
+}
+
+// Cursor 读取时的数据快照
+func (q *query) Cursor(entry goresource.IDbModel) (res goresource.ICursor, err error) {
+	defer q.reset()
+
+	if err = q.err; err != nil {
+		return
+	}
+	rows, _ := q.rows(entry, nil)
+	res = &cursor{
+		rows:  rows,
+		index: -1,
+	}
+
+	return
+}
+
+func (q *query) After(token string) goresource.IQuery {
+	q.pageToken = &pageToken{token: token}
+	return q
+}
+
+func (q *query) Before(token string) goresource.IQuery {
+	q.pageToken = &pageToken{token: token, before: true}
+	return q
+}
+
+func (q *query) PageToken() (next, prev string) {
+	return q.next, q.prev
+}
+
+func (q *query) WithDeleted() goresource.IQuery {
+	q.deleted = deletedscope.Include
+	return q
+}
+
+func (q *query) OnlyDeleted() goresource.IQuery {
+	q.deleted = deletedscope.Only
+	return q
+}
+
+// scoped 筛选条件加上删除范围 (entry 为 ISoftDeletable 时)
+func (q *query) scoped(entry goresource.IDbModel) func(goresource.IDbModel) bool {
+	softDeletable, ok := entry.(goresource.ISoftDeletable)
+	if !ok || q.deleted == deletedscope.Include {
+		return q.filter
+	}
+
+	filter, field := q.filter, softDeletable.DeletedAtField()
+	return func(row goresource.IDbModel) bool {
+		if isDeleted(row, field) != (q.deleted == deletedscope.Only) {
+			return false
+		}
+		return filter == nil || filter(row)
+	}
+}
+
+// keys 排序字段 (keyset 分页)
+func (q *query) keys() []keyset.Key {
+	keys := make([]keyset.Key, 0, len(q.orders))
+	for _, o := range q.orders {
+		keys = append(keys, keyset.Key{Field: o.field, Desc: o.desc})
+	}
+
+	return keys
+}
+
+// rows 筛选、排序、分页, page 不为空时按 keyset 分页; total 为分页前的数量
+func (q *query) rows(entry goresource.IDbModel, keysetPage *keyset.Page) (rows []goresource.IDbModel, total int64) {
+	filter, orders, offset, limit := q.scoped(entry), q.orders, true, q.pageSize
+	if keysetPage != nil {
+		orders, offset, limit = make([]order, 0, len(keysetPage.Keys)), false, keyset.Limit(q.pageSize)
+		for _, key := range keysetPage.Orders() {
+			orders = append(orders, order{field: key.Field, desc: key.Desc})
+		}
+		if cond := keysetPage.Cond(); cond != nil {
+			scoped := filter
+			filter = func(row goresource.IDbModel) bool {
+				return (scoped == nil || scoped(row)) && match(row, cond)
+			}
+		}
+	}
+	q.store.read(func(s state) {
+		rows = s.rows(entry.Table(), filter)
+	})
+	if len(orders) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, o := range orders {
+				res, _ := compare(orderValue(rows[i], o.field), orderValue(rows[j], o.field))
+				if res == 0 {
+					continue
+				}
+				if o.desc {
+					return res > 0
+				}
+				return res < 0
+			}
+			return false
+		})
+	}
+	total = int64(len(rows))
+	if limit > 0 {
+		page := q.page
+		if page < 1 || !offset {
+			page = 1
+		}
+		start := (page - 1) * limit
+		if start > len(rows) {
+			start = len(rows)
+		}
+		end := start + limit
+		if end > len(rows) {
+			end = len(rows)
+		}
+		rows = rows[start:end]
+	}
+
+	return
+}
+
+func (q *query) reset() {
+	q.filter = nil
+	q.err = nil
+	q.page = 0
+	q.pageSize = 0
+	q.orders = make([]order, 0)
+	q.deleted = deletedscope.Exclude
+	q.pageToken = nil
+}
+
+// orderValue 排序字段的值, expr.PK 为主键
+func orderValue(row goresource.IDbModel, field string) interface{} {
+	if field == expr.PK {
+		return row.GetID()
+	}
+
+	rv, _ := tools.FieldValue(row, field)
+	return valueOf(rv)
+}
+
+func valueOf(rv reflect.Value) interface{} {
+	if !rv.IsValid() {
+		return nil
+	}
+
+	return rv.Interface()
+}
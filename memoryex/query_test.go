@@ -0,0 +1,233 @@
+package memoryex
+
+import (
+	"testing"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/keyset"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDb(t *testing.T) goresource.IRepository {
+	db := New().Db()
+	entries := []testPerson{
+		{ID: 1, Name: "query_001", Age: 21},
+		{ID: 2, Name: "query_002", Age: 11},
+		{ID: 3, Name: "query_003", Age: 11},
+		{ID: 4, Name: "query_004", Age: 31},
+		{ID: 5, Name: "other_005", Age: 31},
+	}
+	for index := range entries {
+		if err := db.Create(&entries[index]); err != nil {
+			t.Fatal("err", err)
+		}
+	}
+
+	return db
+}
+
+func Test_query_Find(test *testing.T) {
+	test.Run("filter", func(t *testing.T) {
+		var res []testPerson
+		err := newTestDb(t).Query().Where(expr.And(
+			expr.Gte("age", 11),
+			expr.Like("name", "query%"),
+			expr.Not(expr.In("id", 1, 4)),
+		)).Find(&res)
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal([]testPerson{
+			{ID: 2, Name: "query_002", Age: 11},
+			{ID: 3, Name: "query_003", Age: 11},
+		}, res)
+	})
+
+	test.Run("func filter", func(t *testing.T) {
+		var res []testPerson
+		err := newTestDb(t).Query().Where(func(entry goresource.IDbModel) bool {
+			return entry.(*testPerson).Age == 21
+		}).Find(&res)
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal([]testPerson{{ID: 1, Name: "query_001", Age: 21}}, res)
+	})
+
+	test.Run("order.page", func(t *testing.T) {
+		var res []*testPerson
+		err := newTestDb(t).Query().Desc("age").Asc("id").Page(2).PageSize(2).Find(&res)
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal([]*testPerson{
+			{ID: 1, Name: "query_001", Age: 21},
+			{ID: 2, Name: "query_002", Age: 11},
+		}, res)
+	})
+}
+
+func Test_query_First(test *testing.T) {
+	test.Run("filter", func(t *testing.T) {
+		var res testPerson
+		err := newTestDb(t).Query().Where(expr.Eq("age", 31)).Desc("id").First(&res)
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal(testPerson{ID: 5, Name: "other_005", Age: 31}, res)
+	})
+
+	test.Run("not found", func(t *testing.T) {
+		res := testPerson{ID: 9}
+		err := newTestDb(t).Query().First(&res)
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal(testPerson{ID: 9}, res)
+	})
+}
+
+func Test_query_MustFirst(test *testing.T) {
+	test.Run("not found", func(t *testing.T) {
+		res := testPerson{ID: 9}
+		err := newTestDb(t).Query().MustFirst(&res)
+		a := assert.New(t)
+		a.ErrorIs(err, errs.ErrNotFound)
+		a.Equal(testPerson{ID: 9}, res)
+	})
+
+	test.Run("generic", func(t *testing.T) {
+		resource := New()
+		a := assert.New(t)
+		a.NoError(resource.Db().Create(&testPerson{ID: 1, Name: "query_001"}))
+
+		res, err := goresource.Db[*testPerson](resource).Query().Where(expr.Eq("name", "query_001")).MustFirst()
+		a.NoError(err)
+		a.Equal(int64(1), res.ID)
+		_, err = goresource.Db[*testPerson](resource).Query().Where(expr.Eq("name", "query_002")).MustFirst()
+		a.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func Test_query_Count(t *testing.T) {
+	count, err := newTestDb(t).Query().Where(expr.Eq("age", 11)).Count(&testPerson{})
+	a := assert.New(t)
+	a.NoError(err)
+	a.Equal(int64(2), count)
+}
+
+func Test_query_Cursor(test *testing.T) {
+	test.Run("iterate", func(t *testing.T) {
+		names := make([]string, 0)
+		err := goresource.Wrap[*testPerson](newTestDb(t)).Query().Where(expr.Eq("age", 31)).BatchSize(1).Iterate(func(row *testPerson) error {
+			names = append(names, row.Name)
+			return nil
+		})
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal([]string{"query_004", "other_005"}, names)
+	})
+
+	test.Run("stop", func(t *testing.T) {
+		count := 0
+		err := goresource.Wrap[*testPerson](newTestDb(t)).Query().Iterate(func(row *testPerson) error {
+			count++
+			if count == 2 {
+				return errs.StopIteration
+			}
+			return nil
+		})
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal(2, count)
+	})
+
+	test.Run("scan", func(t *testing.T) {
+		cursor, err := newTestDb(t).Query().Desc("id").Cursor(&testPerson{})
+		a := assert.New(t)
+		a.NoError(err)
+		defer cursor.Close()
+
+		a.True(cursor.Next())
+		var row testPerson
+		a.NoError(cursor.Scan(&row))
+		a.Equal(testPerson{ID: 5, Name: "other_005", Age: 31}, row)
+	})
+}
+
+func Test_query_After(test *testing.T) {
+	keyset.SetSecret([]byte("test"))
+	ids := func(rows []*testPerson) []int64 {
+		res := make([]int64, 0, len(rows))
+		for _, row := range rows {
+			res = append(res, row.ID)
+		}
+		return res
+	}
+
+	test.Run("pages", func(t *testing.T) {
+		db := newTestDb(t)
+		q := goresource.Wrap[*testPerson](db).Query()
+		a := assert.New(t)
+
+		res, err := q.Desc("age").After("").PageSize(2).Find()
+		a.NoError(err)
+		a.Equal([]int64{4, 5}, ids(res))
+		next, prev := q.PageToken()
+		a.NotEmpty(next)
+		a.Empty(prev)
+
+		res, err = q.Desc("age").After(next).PageSize(2).Find()
+		a.NoError(err)
+		a.Equal([]int64{1, 2}, ids(res))
+		next, prev = q.PageToken()
+		a.NotEmpty(prev)
+
+		res, err = q.Desc("age").After(next).PageSize(2).Find()
+		a.NoError(err)
+		a.Equal([]int64{3}, ids(res))
+		next, prev = q.PageToken()
+		a.Empty(next)
+
+		res, err = q.Desc("age").Before(prev).PageSize(2).Find()
+		a.NoError(err)
+		a.Equal([]int64{1, 2}, ids(res))
+		_, prev = q.PageToken()
+
+		res, err = q.Desc("age").Before(prev).PageSize(2).Find()
+		a.NoError(err)
+		a.Equal([]int64{4, 5}, ids(res))
+		next, prev = q.PageToken()
+		a.NotEmpty(next)
+		a.Empty(prev)
+	})
+
+	test.Run("invalid token", func(t *testing.T) {
+		db := newTestDb(t)
+		var res []testPerson
+		a := assert.New(t)
+		q := db.Query()
+		a.NoError(q.Asc("age").After("").PageSize(2).Find(&res))
+		next, _ := q.PageToken()
+
+		a.ErrorIs(db.Query().Asc("age").After(next+"x").PageSize(2).Find(&res), errs.KeysetTokenInvalid)
+		a.ErrorIs(db.Query().Desc("age").After(next).PageSize(2).Find(&res), errs.KeysetTokenInvalid)
+	})
+}
+
+func Test_query_FindPage(test *testing.T) {
+	test.Run("page", func(t *testing.T) {
+		var res []testPerson
+		info, err := newTestDb(t).Query().Where(expr.Like("name", "query%")).Asc("id").Page(2).PageSize(3).FindPage(&res)
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal([]testPerson{{ID: 4, Name: "query_004", Age: 31}}, res)
+		a.Equal(goresource.PageInfo{Total: 4, Page: 2, PageSize: 3}, info)
+	})
+
+	test.Run("has next", func(t *testing.T) {
+		res, info, err := goresource.Wrap[*testPerson](newTestDb(t)).Query().Where(expr.Eq("age", 31)).PageSize(1).FindPage()
+		a := assert.New(t)
+		a.NoError(err)
+		a.Len(res, 1)
+		a.Equal(goresource.PageInfo{Total: 2, Page: 1, PageSize: 1, HasNext: true}, info)
+	})
+}
@@ -0,0 +1,198 @@
+package memoryex
+
+import (
+	"context"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/repositorytype"
+)
+
+type repository struct {
+	ctx            context.Context
+	store          *store
+	repositoryBase *goresource.RepositoryBase
+	uow            *unitOfWork
+}
+
+func (r *repository) Create(entry goresource.IDbModel, args ...interface{}) (err error) {
+	goresource.AuditCreate(r.ctx, entry)
+	if err = goresource.GenerateID(r.ctx, entry); err != nil {
+		return
+	}
+	if r.uow != nil {
+		r.enlist(repositorytype.Create, entry, args...)
+		return
+	}
+
+	err = r.store.write(func(s state) error {
+		return s.create(entry)
+	})
+
+	return
+}
+
+// Delete args 0 筛选条件(expr.Expr、func(goresource.IDbModel) bool) 为空时按主键删除
+func (r *repository) Delete(entry goresource.IDbModel, args ...interface{}) (err error) {
+	if r.uow != nil {
+		r.enlist(repositorytype.Delete, entry, args...)
+		return
+	}
+
+	err = r.store.write(func(s state) error {
+		return applyDelete(s, entry, args...)
+	})
+
+	return
+}
+
+// Update args 0 更新字段 []string 为空时整行更新
+func (r *repository) Update(entry goresource.IDbModel, args ...interface{}) (err error) {
+	goresource.AuditUpdate(r.ctx, entry)
+	if r.uow != nil {
+		r.enlist(repositorytype.Update, entry, args...)
+		return
+	}
+
+	err = r.store.write(func(s state) error {
+		return applyUpdate(s, entry, args...)
+	})
+
+	return
+}
+
+// Upsert 工作单元中提交时执行
+func (r *repository) Upsert(entry goresource.IDbModel, opts goresource.UpsertOptions) (inserted bool, err error) {
+	goresource.AuditCreate(r.ctx, entry)
+	if err = goresource.GenerateID(r.ctx, entry); err != nil {
+		return
+	}
+	if r.uow != nil {
+		r.enlist(repositorytype.Upsert, entry, opts)
+		return
+	}
+
+	err = r.store.write(func(s state) (err error) {
+		inserted, err = s.upsert(entry, opts)
+		return
+	})
+
+	return
+}
+
+func (r *repository) CreateMany(entries []goresource.IDbModel, batchSize int) error {
+	for _, entry := range entries {
+		goresource.AuditCreate(r.ctx, entry)
+		if err := goresource.GenerateID(r.ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return r.bulk(repositorytype.Create, entries, batchSize, func(s state, entry goresource.IDbModel) error {
+		return s.create(entry)
+	})
+}
+
+func (r *repository) UpdateMany(entries []goresource.IDbModel, batchSize int) error {
+	for _, entry := range entries {
+		goresource.AuditUpdate(r.ctx, entry)
+	}
+
+	return r.bulk(repositorytype.Update, entries, batchSize, func(s state, entry goresource.IDbModel) error {
+		return applyUpdate(s, entry)
+	})
+}
+
+func (r *repository) DeleteMany(entries []goresource.IDbModel, batchSize int) error {
+	return r.bulk(repositorytype.Delete, entries, batchSize, func(s state, entry goresource.IDbModel) error {
+		return applyDelete(s, entry)
+	})
+}
+
+// bulk 每批在一次写入中执行, 失败项不影响同批其他项
+func (r *repository) bulk(rt repositorytype.Value, entries []goresource.IDbModel, batchSize int, apply func(s state, entry goresource.IDbModel) error) error {
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		if r.uow != nil {
+			for _, entry := range entries[start:end] {
+				r.enlist(rt, entry)
+			}
+			return nil
+		}
+
+		bulkErr := &errs.BulkError{}
+		err := r.store.write(func(s state) error {
+			for index, entry := range entries[start:end] {
+				if err := apply(s, entry); err != nil {
+					bulkErr.Add(index, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return bulkErr.OrNil()
+	})
+}
+
+func (r *repository) Query() goresource.IQuery {
+	return &query{
+		ctx:    r.ctx,
+		store:  r.store,
+		orders: make([]order, 0),
+	}
+}
+
+func (r *repository) enlist(rt repositorytype.Value, entry goresource.IDbModel, args ...interface{}) {
+	r.uow.commitQueues = append(r.uow.commitQueues, commitQueueItem{
+		rt:       rt,
+		entry:    entry,
+		snapshot: clone(entry),
+		args:     args,
+	})
+	if r.repositoryBase != nil {
+		r.repositoryBase.SetUow(dbtype.Memory, r.uow)
+	}
+}
+
+func applyDelete(s state, entry goresource.IDbModel, args ...interface{}) (err error) {
+	var filter func(goresource.IDbModel) bool
+	if len(args) > 0 {
+		if filter, err = toFilter(args[0]); err != nil {
+			return
+		}
+	}
+	err = s.remove(entry, filter)
+
+	return
+}
+
+// applyUpdate IVersioned 按版本更新, 版本不一致时返回冲突
+func applyUpdate(s state, entry goresource.IDbModel, args ...interface{}) (err error) {
+	var fields []string
+	if len(args) > 0 {
+		fields, _ = args[0].([]string)
+	}
+	if len(fields) > 0 {
+		// 指定字段时追加更新审计字段
+		fields = append(append(make([]string, 0, len(fields)+2), fields...), goresource.UpdateAuditFields(entry)...)
+	}
+
+	guard, ok := goresource.NextVersion(entry)
+	if !ok {
+		return s.update(entry, fields)
+	}
+	if row, exists := s.table(entry.Table()).rows[idKey(entry.GetID())]; !exists || row.(goresource.IVersioned).GetVersion() != guard.Old {
+		return guard.Conflict()
+	}
+	if len(fields) > 0 {
+		fields = append(fields, guard.Field)
+	}
+	if err = s.update(entry, fields); err != nil {
+		guard.Restore()
+	}
+
+	return
+}
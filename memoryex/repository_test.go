@@ -0,0 +1,478 @@
+package memoryex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/repositorytype"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPerson struct {
+	ID   int64  `postgres:"id" pk:""`
+	Name string `postgres:"name"`
+	Age  int16  `postgres:"age"`
+}
+
+func (t testPerson) GetID() interface{} {
+	return t.ID
+}
+
+func (t *testPerson) SetID(v interface{}) {
+	if vv, ok := v.(int64); ok {
+		t.ID = vv
+	}
+}
+
+func (t testPerson) Table() string {
+	return "test_person"
+}
+
+// testIDPerson 绑定了主键生成器的表
+type testIDPerson struct {
+	testPerson
+}
+
+func (t testIDPerson) Table() string {
+	return "test_person_id"
+}
+
+func Test_repository_Create(test *testing.T) {
+	test.Run("success", func(t *testing.T) {
+		db := New().Db()
+		entry := testPerson{ID: 1, Name: "create_001", Age: 21}
+		a := assert.New(t)
+		a.NoError(db.Create(&entry))
+
+		res := testPerson{ID: 1}
+		a.NoError(db.Query().First(&res))
+		a.Equal(entry, res)
+	})
+
+	test.Run("auto.id", func(t *testing.T) {
+		db := New().Db()
+		entries := []testPerson{{Name: "create_001"}, {Name: "create_002"}}
+		a := assert.New(t)
+		for index := range entries {
+			a.NoError(db.Create(&entries[index]))
+		}
+		a.Equal(int64(1), entries[0].ID)
+		a.Equal(int64(2), entries[1].ID)
+	})
+
+	test.Run("id.generator", func(t *testing.T) {
+		next := int64(100)
+		goresource.RegisterTableIDGenerator("test_person_id", goresource.IDGeneratorFunc(func(ctx context.Context, entry goresource.IDbModel) (interface{}, error) {
+			next++
+			return next, nil
+		}))
+
+		resource, uow := New(), goresource.Uow()
+		entries := []testIDPerson{{testPerson: testPerson{Name: "create_001"}}, {testPerson: testPerson{Name: "create_002"}}}
+		a := assert.New(t)
+		a.NoError(resource.Db().Create(&entries[0]))
+		a.NoError(resource.Db(uow).Create(&entries[1]))
+		a.Equal(int64(101), entries[0].ID)
+		a.Equal(int64(102), entries[1].ID)
+		a.NoError(uow.Commit())
+
+		res := testIDPerson{testPerson: testPerson{ID: 102}}
+		a.NoError(resource.Db().Query().First(&res))
+		a.Equal("create_002", res.Name)
+	})
+
+	test.Run(ErrDuplicateID.Error(), func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 1}))
+		a.Equal(ErrDuplicateID, db.Create(&testPerson{ID: 1}))
+	})
+
+	test.Run("copy", func(t *testing.T) {
+		db := New().Db()
+		entry := testPerson{ID: 1, Name: "create_001"}
+		a := assert.New(t)
+		a.NoError(db.Create(&entry))
+		entry.Name = "changed"
+
+		res := testPerson{ID: 1}
+		a.NoError(db.Query().First(&res))
+		a.Equal("create_001", res.Name)
+	})
+}
+
+func Test_repository_Delete(test *testing.T) {
+	test.Run(errs.DeleteFullNotAllowed.Error(), func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.Equal(errs.DeleteFullNotAllowed, db.Delete(&testPerson{}))
+	})
+
+	test.Run("by id", func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 1}))
+		a.NoError(db.Create(&testPerson{ID: 2}))
+		a.NoError(db.Delete(&testPerson{ID: 1}))
+
+		count, err := db.Query().Count(&testPerson{})
+		a.NoError(err)
+		a.Equal(int64(1), count)
+	})
+
+	test.Run("by filter", func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 1, Age: 11}))
+		a.NoError(db.Create(&testPerson{ID: 2, Age: 11}))
+		a.NoError(db.Create(&testPerson{ID: 3, Age: 21}))
+		a.NoError(db.Delete(&testPerson{}, expr.Eq("age", 11)))
+
+		var res []testPerson
+		a.NoError(db.Query().Find(&res))
+		a.Equal([]testPerson{{ID: 3, Age: 21}}, res)
+	})
+
+	test.Run("soft", func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testArticle{ID: 1}))
+		a.NoError(db.Create(&testArticle{ID: 2}))
+		a.NoError(db.Create(&testArticle{ID: 3}))
+		a.NoError(db.Delete(&testArticle{ID: 1}))
+		a.NoError(db.Delete(&testArticle{}, expr.Eq("id", 2)))
+
+		var res []testArticle
+		a.NoError(db.Query().Find(&res))
+		a.Len(res, 1)
+		a.Equal(int64(3), res[0].ID)
+
+		count, err := db.Query().WithDeleted().Count(&testArticle{})
+		a.NoError(err)
+		a.Equal(int64(3), count)
+
+		res = nil
+		a.NoError(db.Query().OnlyDeleted().Asc("id").Find(&res))
+		a.Len(res, 2)
+		a.NotNil(res[0].DeletedAt)
+		a.NotNil(res[1].DeletedAt)
+
+		entry := testArticle{ID: 1}
+		a.NoError(db.Query().First(&entry))
+		a.Nil(entry.DeletedAt)
+		a.NoError(db.Query().WithDeleted().First(&entry))
+		a.NotNil(entry.DeletedAt)
+	})
+}
+
+type testArticle struct {
+	ID        int64
+	CreatedBy string
+	CreatedAt time.Time
+	UpdatedBy string
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+	Version   int64
+}
+
+func (t *testArticle) SetCreated(by string, at time.Time) {
+	t.CreatedBy, t.CreatedAt = by, at
+}
+
+func (t testArticle) CreatedFields() []string {
+	return []string{"createdBy", "createdAt"}
+}
+
+func (t *testArticle) SetUpdated(by string, at time.Time) {
+	t.UpdatedBy, t.UpdatedAt = by, at
+}
+
+func (t testArticle) UpdatedFields() []string {
+	return []string{"updatedBy", "updatedAt"}
+}
+
+func (t testArticle) GetID() interface{} {
+	return t.ID
+}
+
+func (t *testArticle) SetID(v interface{}) {
+	if vv, ok := v.(int64); ok {
+		t.ID = vv
+	}
+}
+
+func (t testArticle) Table() string {
+	return "test_article"
+}
+
+func (t testArticle) DeletedAtField() string {
+	return "deletedAt"
+}
+
+func (t testArticle) VersionField() string {
+	return "version"
+}
+
+func (t testArticle) GetVersion() int64 {
+	return t.Version
+}
+
+func (t *testArticle) SetVersion(version int64) {
+	t.Version = version
+}
+
+func Test_repository_Audit(test *testing.T) {
+	now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	goresource.Now = func() time.Time {
+		return now
+	}
+	defer func() {
+		goresource.Now = time.Now
+	}()
+
+	test.Run("uow", func(t *testing.T) {
+		res := New()
+		uow := goresource.Uow()
+		ctx := goresource.ContextWithActor(context.Background(), "ctl")
+		a := assert.New(t)
+		a.NoError(res.Db(ctx, uow).Create(&testArticle{ID: 1}))
+		a.NoError(uow.Commit())
+
+		entry := testArticle{ID: 1}
+		a.NoError(res.Db().Query().First(&entry))
+		a.Equal("ctl", entry.CreatedBy)
+		a.Equal(now, entry.CreatedAt)
+		a.Equal("ctl", entry.UpdatedBy)
+
+		now = now.Add(time.Hour)
+		ctx = goresource.ContextWithActor(context.Background(), "other")
+		a.NoError(res.Db(ctx).Update(&entry))
+		a.NoError(res.Db().Query().First(&entry))
+		a.Equal("ctl", entry.CreatedBy)
+		a.Equal(now.Add(-time.Hour), entry.CreatedAt)
+		a.Equal("other", entry.UpdatedBy)
+		a.Equal(now, entry.UpdatedAt)
+	})
+
+	test.Run("fields", func(t *testing.T) {
+		res := New()
+		ctx := goresource.ContextWithActor(context.Background(), "ctl")
+		a := assert.New(t)
+		a.NoError(res.Db(ctx).Create(&testArticle{ID: 1}))
+
+		ctx = goresource.ContextWithActor(context.Background(), "other")
+		a.NoError(res.Db(ctx).Update(&testArticle{ID: 1, CreatedBy: "changed"}, []string{"version"}))
+
+		entry := testArticle{ID: 1}
+		a.NoError(res.Db().Query().First(&entry))
+		a.Equal("ctl", entry.CreatedBy)
+		a.Equal("other", entry.UpdatedBy)
+		a.Equal(int64(1), entry.Version)
+	})
+}
+
+func Test_repository_Update(test *testing.T) {
+	test.Run("full", func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 1, Name: "update_001", Age: 11}))
+		a.NoError(db.Update(&testPerson{ID: 1, Name: "update-set-name"}))
+
+		res := testPerson{ID: 1}
+		a.NoError(db.Query().First(&res))
+		a.Equal(testPerson{ID: 1, Name: "update-set-name"}, res)
+	})
+
+	test.Run("fields", func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 1, Name: "update_001", Age: 11}))
+		a.NoError(db.Update(&testPerson{ID: 1, Name: "update-set-name"}, []string{"name"}))
+
+		res := testPerson{ID: 1}
+		a.NoError(db.Query().First(&res))
+		a.Equal(testPerson{ID: 1, Name: "update-set-name", Age: 11}, res)
+	})
+
+	test.Run("version", func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testArticle{ID: 1}))
+
+		first, second := testArticle{ID: 1}, testArticle{ID: 1}
+		a.NoError(db.Update(&first))
+		a.Equal(int64(1), first.Version)
+
+		var conflict *errs.ConcurrencyConflict
+		a.ErrorAs(db.Update(&second, []string{"createdBy"}), &conflict)
+		a.Equal(int64(0), conflict.Version)
+		a.Equal(int64(0), second.Version)
+
+		res := testArticle{ID: 1}
+		a.NoError(db.Query().First(&res))
+		a.Equal(int64(1), res.Version)
+	})
+
+	test.Run("version uow", func(t *testing.T) {
+		res := New()
+		a := assert.New(t)
+		a.NoError(res.Db().Create(&testArticle{ID: 1}))
+		a.NoError(res.Db().Create(&testArticle{ID: 2, Version: 3}))
+
+		uow := goresource.Uow()
+		a.NoError(res.Db(uow).Update(&testArticle{ID: 1, CreatedBy: "ctl"}))
+		a.NoError(res.Db(uow).Update(&testArticle{ID: 2, Version: 2}))
+		var conflict *errs.ConcurrencyConflict
+		a.ErrorAs(uow.Commit(), &conflict)
+
+		entry := testArticle{ID: 1}
+		a.NoError(res.Db().Query().First(&entry))
+		a.Equal("", entry.CreatedBy)
+		a.Equal(int64(0), entry.Version)
+	})
+}
+
+func Test_repository_Upsert(test *testing.T) {
+	test.Run("pk", func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		inserted, err := goresource.Upsert(db, &testPerson{ID: 1, Name: "upsert_001", Age: 11})
+		a.NoError(err)
+		a.True(inserted)
+
+		inserted, err = goresource.Upsert(db, &testPerson{ID: 1, Name: "upsert-set-name", Age: 21}, goresource.UpsertOptions{
+			UpdateFields: []string{"name"},
+		})
+		a.NoError(err)
+		a.False(inserted)
+
+		res := testPerson{ID: 1}
+		a.NoError(db.Query().First(&res))
+		a.Equal(testPerson{ID: 1, Name: "upsert-set-name", Age: 11}, res)
+	})
+
+	test.Run("conflict", func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 1, Name: "upsert_001", Age: 11}))
+
+		entry := &testPerson{Name: "upsert_001", Age: 21}
+		inserted, err := goresource.Upsert(db, entry, goresource.UpsertOptions{ConflictFields: []string{"name"}})
+		a.NoError(err)
+		a.False(inserted)
+		a.Equal(int64(1), entry.ID)
+
+		inserted, err = goresource.Upsert(db, &testPerson{ID: 2, Name: "upsert_002"}, goresource.UpsertOptions{ConflictFields: []string{"name"}})
+		a.NoError(err)
+		a.True(inserted)
+
+		res := testPerson{ID: 1}
+		a.NoError(db.Query().First(&res))
+		a.Equal(int16(21), res.Age)
+		count, _ := db.Query().Count(&testPerson{})
+		a.Equal(int64(2), count)
+	})
+
+	test.Run("create audit", func(t *testing.T) {
+		resource := New()
+		a := assert.New(t)
+		a.NoError(resource.Db(goresource.ContextWithActor(context.Background(), "ctl")).Create(&testArticle{ID: 1}))
+
+		entry := &testArticle{ID: 1}
+		inserted, err := goresource.Upsert(resource.Db(goresource.ContextWithActor(context.Background(), "other")), entry)
+		a.NoError(err)
+		a.False(inserted)
+		a.Equal("ctl", entry.CreatedBy)
+
+		res := testArticle{ID: 1}
+		a.NoError(resource.Db().Query().First(&res))
+		a.Equal("ctl", res.CreatedBy)
+		a.Equal("other", res.UpdatedBy)
+	})
+
+	test.Run("uow", func(t *testing.T) {
+		resource := New()
+		uow := resource.Uow()
+		a := assert.New(t)
+		_, err := goresource.Db[*testPerson](resource, uow).Upsert(&testPerson{ID: 1})
+		a.NoError(err)
+		a.Equal(1, uow.Pending()[repositorytype.Upsert])
+
+		count, _ := resource.Db().Query().Count(&testPerson{})
+		a.Equal(int64(0), count)
+		a.NoError(uow.Commit())
+		count, _ = resource.Db().Query().Count(&testPerson{})
+		a.Equal(int64(1), count)
+	})
+}
+
+func Test_repository_CreateMany(test *testing.T) {
+	test.Run("failures", func(t *testing.T) {
+		db := New().Db()
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 2, Name: "exists"}))
+
+		err := goresource.CreateMany(db, []goresource.IDbModel{
+			&testPerson{ID: 1, Name: "bulk_001"},
+			&testPerson{ID: 2, Name: "bulk_002"},
+			&testPerson{ID: 3, Name: "bulk_003"},
+		}, 2)
+		var bulkErr *errs.BulkError
+		a.ErrorAs(err, &bulkErr)
+		a.Equal([]errs.BulkFailure{{Index: 1, Err: ErrDuplicateID}}, bulkErr.Failures)
+		a.ErrorIs(bulkErr.Failures[0].Err, errs.ErrDuplicateKey)
+
+		count, err := db.Query().Count(&testPerson{})
+		a.NoError(err)
+		a.Equal(int64(3), count)
+	})
+
+	test.Run("uow", func(t *testing.T) {
+		resource := New()
+		uow := resource.Uow()
+		a := assert.New(t)
+		a.NoError(goresource.Db[*testPerson](resource, uow).CreateMany([]*testPerson{{ID: 1}, {ID: 2}}, 0))
+
+		count, _ := resource.Db().Query().Count(&testPerson{})
+		a.Equal(int64(0), count)
+		a.NoError(uow.Commit())
+		count, _ = resource.Db().Query().Count(&testPerson{})
+		a.Equal(int64(2), count)
+	})
+}
+
+func Test_repository_UpdateMany(t *testing.T) {
+	db := New().Db()
+	a := assert.New(t)
+	a.NoError(db.Create(&testArticle{ID: 1}))
+	a.NoError(db.Create(&testArticle{ID: 2}))
+
+	stale := &testArticle{ID: 2, Version: 5}
+	err := goresource.UpdateMany(db, []goresource.IDbModel{&testArticle{ID: 1}, stale}, 0)
+	var bulkErr *errs.BulkError
+	a.ErrorAs(err, &bulkErr)
+	a.Len(bulkErr.Failures, 1)
+	a.Equal(1, bulkErr.Failures[0].Index)
+	var conflict *errs.ConcurrencyConflict
+	a.ErrorAs(bulkErr.Failures[0].Err, &conflict)
+	a.Equal(int64(5), stale.Version)
+
+	res := testArticle{ID: 1}
+	a.NoError(db.Query().First(&res))
+	a.Equal(int64(1), res.Version)
+}
+
+func Test_repository_DeleteMany(t *testing.T) {
+	db := newTestDb(t)
+	a := assert.New(t)
+	a.NoError(goresource.DeleteMany(db, []goresource.IDbModel{&testPerson{ID: 1}, &testPerson{ID: 2}}, 1))
+
+	count, err := db.Query().Count(&testPerson{})
+	a.NoError(err)
+	a.Equal(int64(3), count)
+}
@@ -0,0 +1,70 @@
+package memoryex
+
+import (
+	"context"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+)
+
+type resource struct {
+	store *store
+}
+
+// Db 参数 ctx、uow (ctx 中的工作单元 goresource.ContextWithUow)
+func (f resource) Db(args ...interface{}) goresource.IRepository {
+	dbArgs := goresource.ParseDbArgs(args...)
+	repo := &repository{
+		ctx:   dbArgs.Ctx,
+		store: f.store,
+	}
+	if uow, ok := dbArgs.Uow.(*unitOfWork); ok {
+		repo.uow = uow
+	} else if dbArgs.Uow != nil {
+		if repo.repositoryBase = goresource.NewRepository(dbArgs.Uow); repo.repositoryBase == nil {
+			return goresource.NewErrRepository(errs.UowNotSupported)
+		}
+		if enlisted, ok := repo.repositoryBase.GetUow(dbtype.Memory, func(enlisted goresource.IUnitOfWork) bool {
+			uow, ok := enlisted.(*unitOfWork)
+			return ok && uow.store == f.store
+		}); ok {
+			repo.uow = enlisted.(*unitOfWork)
+		}
+		if repo.uow == nil {
+			repo.uow = newUnitOfWork(f.store)
+		}
+	}
+
+	return repo
+}
+
+func (f resource) Uow() goresource.IUnitOfWork {
+	return newUnitOfWork(f.store)
+}
+
+func (f resource) DbType() dbtype.Value {
+	return dbtype.Memory
+}
+
+func (f resource) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Close 清空数据
+func (f resource) Close() error {
+	f.store.write(func(s state) error {
+		for name := range s {
+			delete(s, name)
+		}
+		return nil
+	})
+	return nil
+}
+
+// New 内存资源, 以 IDbModel.Table() 与 GetID() 保存数据, 用于单元测试
+func New() goresource.IResource {
+	return &resource{
+		store: newStore(),
+	}
+}
@@ -0,0 +1,28 @@
+package memoryex
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewFromConfig(test *testing.T) {
+	f, err := goresource.NewFromConfig(strings.NewReader(`
+resources:
+  - alias: main
+    type: memory
+`))
+	a := assert.New(test)
+	a.NoError(err)
+
+	res, err := f.BuildByType(dbtype.Memory)
+	a.NoError(err)
+	a.NoError(res.Db().Create(&testPerson{ID: 1}))
+	count, err := res.Db().Query().Count(&testPerson{})
+	a.NoError(err)
+	a.Equal(int64(1), count)
+}
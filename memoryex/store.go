@@ -0,0 +1,359 @@
+package memoryex
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/tools"
+)
+
+// table 表数据 keys 保持写入顺序, shared 为已提交的表(写入前须复制)
+type table struct {
+	seq    int64
+	keys   []interface{}
+	rows   map[interface{}]goresource.IDbModel
+	shared bool
+}
+
+func (t *table) clone() *table {
+	res := &table{
+		seq:  t.seq,
+		keys: append(make([]interface{}, 0, len(t.keys)), t.keys...),
+		rows: make(map[interface{}]goresource.IDbModel, len(t.rows)),
+	}
+	for k, v := range t.rows {
+		res.rows[k] = v
+	}
+
+	return res
+}
+
+func (t *table) remove(key interface{}) {
+	delete(t.rows, key)
+	for index := range t.keys {
+		if t.keys[index] == key {
+			t.keys = append(t.keys[:index], t.keys[index+1:]...)
+			break
+		}
+	}
+}
+
+// state 数据集 table名 -> 表数据, 行数据写入后不可变(更新时替换)
+type state map[string]*table
+
+// fork 浅复制, 表在首次写入(table)时才复制
+func (s state) fork() state {
+	res := make(state, len(s))
+	for name, t := range s {
+		res[name] = t
+	}
+
+	return res
+}
+
+// table 返回可写的表, 已提交的表先复制
+func (s state) table(name string) *table {
+	t, ok := s[name]
+	if !ok {
+		t = &table{
+			keys: make([]interface{}, 0),
+			rows: make(map[interface{}]goresource.IDbModel),
+		}
+		s[name] = t
+	} else if t.shared {
+		t = t.clone()
+		s[name] = t
+	}
+
+	return t
+}
+
+// rows 按写入顺序返回满足条件的行
+func (s state) rows(name string, filter func(goresource.IDbModel) bool) (res []goresource.IDbModel) {
+	t, ok := s[name]
+	if !ok {
+		return
+	}
+	for _, key := range t.keys {
+		row := t.rows[key]
+		if filter == nil || filter(row) {
+			res = append(res, row)
+		}
+	}
+
+	return
+}
+
+func (s state) create(entry goresource.IDbModel) (err error) {
+	t := s.table(entry.Table())
+	if tools.IsEmpty(entry.GetID()) {
+		if err = t.nextID(entry); err != nil {
+			return
+		}
+	}
+
+	key := idKey(entry.GetID())
+	if _, ok := t.rows[key]; ok {
+		err = ErrDuplicateID
+		return
+	}
+	t.keys = append(t.keys, key)
+	t.rows[key] = clone(entry)
+
+	return
+}
+
+// update fields 不为空时只更新指定字段
+func (s state) update(entry goresource.IDbModel, fields []string) (err error) {
+	t := s.table(entry.Table())
+	key := idKey(entry.GetID())
+	row, ok := t.rows[key]
+	if !ok {
+		return
+	}
+	if len(fields) == 0 {
+		t.rows[key] = clone(entry)
+		return
+	}
+
+	newRow := clone(row)
+	for _, field := range fields {
+		src, ok := tools.FieldValue(entry, field)
+		if !ok {
+			continue
+		}
+		dst, _ := tools.FieldValue(newRow, field)
+		dst.Set(src)
+	}
+	t.rows[key] = newRow
+
+	return
+}
+
+// upsert 按冲突字段(为空时为主键)查找已存在的行, 不存在时插入, 存在时更新 opts.UpdateFields(为空时除创建审计字段外整行替换)
+func (s state) upsert(entry goresource.IDbModel, opts goresource.UpsertOptions) (inserted bool, err error) {
+	t := s.table(entry.Table())
+	var key interface{}
+	if len(opts.ConflictFields) == 0 {
+		if !tools.IsEmpty(entry.GetID()) {
+			if _, ok := t.rows[idKey(entry.GetID())]; ok {
+				key = idKey(entry.GetID())
+			}
+		}
+	} else {
+		for _, k := range t.keys {
+			if conflicts(t.rows[k], entry, opts.ConflictFields) {
+				key = k
+				break
+			}
+		}
+	}
+	if key == nil {
+		return true, s.create(entry)
+	}
+
+	entry.SetID(t.rows[key].GetID())
+	if len(opts.UpdateFields) == 0 {
+		for _, field := range goresource.CreateAuditFields(entry) {
+			src, _ := tools.FieldValue(t.rows[key], field)
+			dst, _ := tools.FieldValue(entry, field)
+			dst.Set(src)
+		}
+	}
+	err = s.update(entry, opts.UpdateFields)
+
+	return
+}
+
+// remove filter 为空时按主键删除, ISoftDeletable 写入删除时间
+func (s state) remove(entry goresource.IDbModel, filter func(goresource.IDbModel) bool) (err error) {
+	t := s.table(entry.Table())
+	keys := make([]interface{}, 0)
+	if filter == nil {
+		if tools.IsEmpty(entry.GetID()) {
+			err = errs.DeleteFullNotAllowed
+			return
+		}
+		keys = append(keys, idKey(entry.GetID()))
+	} else {
+		for _, row := range s.rows(entry.Table(), filter) {
+			keys = append(keys, idKey(row.GetID()))
+		}
+	}
+
+	softDeletable, ok := entry.(goresource.ISoftDeletable)
+	if !ok {
+		for _, key := range keys {
+			t.remove(key)
+		}
+		return
+	}
+
+	now := goresource.Now()
+	for _, key := range keys {
+		row, ok := t.rows[key]
+		if !ok || isDeleted(row, softDeletable.DeletedAtField()) {
+			continue
+		}
+		newRow := clone(row)
+		if err = setDeletedAt(newRow, softDeletable.DeletedAtField(), now); err != nil {
+			return
+		}
+		t.rows[key] = newRow
+	}
+
+	return
+}
+
+// nextID 整型主键自增
+func (t *table) nextID(entry goresource.IDbModel) (err error) {
+	id := entry.GetID()
+	if id == nil {
+		err = ErrIDEmpty
+		return
+	}
+	idRt := reflect.TypeOf(id)
+	if !isInt(idRt.Kind()) && !isUint(idRt.Kind()) {
+		err = ErrIDEmpty
+		return
+	}
+
+	t.seq++
+	entry.SetID(reflect.ValueOf(t.seq).Convert(idRt).Interface())
+
+	return
+}
+
+type store struct {
+	rw    sync.RWMutex
+	state state
+}
+
+// read 读锁下执行
+func (s *store) read(fn func(state)) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	fn(s.state)
+}
+
+// write 写锁下执行, 在副本上执行成功后替换 (全部成功或全部不生效)
+func (s *store) write(fn func(state) error) (err error) {
+	newState := s.begin()
+	if err = fn(newState); err != nil {
+		s.rollback()
+		return
+	}
+	s.commit(newState)
+
+	return
+}
+
+// begin 加写锁并返回数据副本, 须调用 commit 或 rollback 释放
+func (s *store) begin() state {
+	s.rw.Lock()
+	return s.state.fork()
+}
+
+func (s *store) commit(newState state) {
+	for _, t := range newState {
+		t.shared = true
+	}
+	s.state = newState
+	s.rw.Unlock()
+}
+
+func (s *store) rollback() {
+	s.rw.Unlock()
+}
+
+func newStore() *store {
+	return &store{
+		state: make(state),
+	}
+}
+
+// toFilter 支持 expr.Expr、func(goresource.IDbModel) bool
+func toFilter(arg interface{}) (filter func(goresource.IDbModel) bool, err error) {
+	switch v := arg.(type) {
+	case nil:
+	case expr.Expr:
+		filter = func(entry goresource.IDbModel) bool {
+			return match(entry, v)
+		}
+	case func(goresource.IDbModel) bool:
+		filter = v
+	default:
+		err = errs.QueryArgsError
+	}
+
+	return
+}
+
+// isDeleted 删除时间不为空
+func isDeleted(row goresource.IDbModel, field string) bool {
+	rv, ok := tools.FieldValue(row, field)
+	if !ok {
+		return false
+	}
+	if rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		return !rv.IsNil()
+	}
+
+	return !rv.IsZero()
+}
+
+func setDeletedAt(row goresource.IDbModel, field string, now time.Time) error {
+	rv, ok := tools.FieldValue(row, field)
+	if !ok {
+		return ErrDeletedAtType
+	}
+	switch rv.Type() {
+	case reflect.TypeOf(now):
+		rv.Set(reflect.ValueOf(now))
+	case reflect.TypeOf(&now):
+		rv.Set(reflect.ValueOf(&now))
+	default:
+		return ErrDeletedAtType
+	}
+
+	return nil
+}
+
+func idKey(id interface{}) interface{} {
+	if id == nil || !reflect.TypeOf(id).Comparable() {
+		return fmt.Sprint(id)
+	}
+
+	return id
+}
+
+// clone 复制模型 (以指针形式保存)
+func clone(entry goresource.IDbModel) goresource.IDbModel {
+	rv := reflect.ValueOf(entry)
+	newRv := reflect.New(reflect.Indirect(rv).Type())
+	newRv.Elem().Set(reflect.Indirect(rv))
+
+	return newRv.Interface().(goresource.IDbModel)
+}
+
+// conflicts row 与 entry 的各字段值是否都相同
+func conflicts(row, entry goresource.IDbModel, fields []string) bool {
+	for _, field := range fields {
+		a, ok := tools.FieldValue(row, field)
+		if !ok {
+			return false
+		}
+		b, _ := tools.FieldValue(entry, field)
+		if res, ok := compare(a.Interface(), b.Interface()); !ok || res != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,157 @@
+package memoryex
+
+import (
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/repositorytype"
+	"github.com/xm-chentl/goresource/uowstatus"
+)
+
+// commitQueueItem snapshot 为入队时的模型副本, 提交时在其副本上执行
+type commitQueueItem struct {
+	rt       repositorytype.Value
+	entry    goresource.IDbModel
+	snapshot goresource.IDbModel
+	args     []interface{}
+}
+
+// writeBack 提交成功后将主键、版本写回调用方的模型
+func (item commitQueueItem) writeBack(applied goresource.IDbModel) {
+	switch item.rt {
+	case repositorytype.Create, repositorytype.Upsert:
+		item.entry.SetID(applied.GetID())
+	case repositorytype.Update:
+		if versioned, ok := item.entry.(goresource.IVersioned); ok {
+			versioned.SetVersion(applied.(goresource.IVersioned).GetVersion())
+		}
+	}
+}
+
+type unitOfWork struct {
+	goresource.CommitHooks
+
+	store        *store
+	prepared     state
+	applied      []goresource.IDbModel
+	status       uowstatus.Value
+	commitQueues []commitQueueItem
+}
+
+// Commit 全部成功或全部不生效
+func (u *unitOfWork) Commit() (err error) {
+	if err = u.Prepare(); err != nil {
+		return
+	}
+	err = u.CommitPrepared()
+
+	return
+}
+
+// Rollback 放弃队列，已 Prepare 的数据副本丢弃
+func (u *unitOfWork) Rollback() (err error) {
+	err = u.RollbackPrepared()
+	u.reset()
+	u.status = uowstatus.Aborted
+
+	return
+}
+
+func (u *unitOfWork) Discard() {
+	_ = u.Rollback()
+}
+
+func (u *unitOfWork) Pending() map[repositorytype.Value]int {
+	res := map[repositorytype.Value]int{
+		repositorytype.Create: 0,
+		repositorytype.Update: 0,
+		repositorytype.Delete: 0,
+	}
+	for _, item := range u.commitQueues {
+		res[item.rt]++
+	}
+
+	return res
+}
+
+func (u *unitOfWork) Status() uowstatus.Value {
+	return u.status
+}
+
+// Prepare 锁定数据并在副本上执行队列, 须调用 CommitPrepared 或 RollbackPrepared
+func (u *unitOfWork) Prepare() (err error) {
+	if u.status == uowstatus.Aborted {
+		err = errs.UowDiscarded
+		return
+	}
+
+	newState := u.store.begin()
+	applied, err := u.exec(newState)
+	if err != nil {
+		u.store.rollback()
+		return
+	}
+	u.prepared, u.applied = newState, applied
+
+	return
+}
+
+func (u *unitOfWork) CommitPrepared() (err error) {
+	if u.prepared == nil {
+		return
+	}
+	u.store.commit(u.prepared)
+	for index, item := range u.commitQueues {
+		item.writeBack(u.applied[index])
+	}
+	u.prepared, u.applied = nil, nil
+	u.status = uowstatus.Committed
+	u.RunCommitted()
+	u.reset()
+
+	return
+}
+
+func (u *unitOfWork) RollbackPrepared() (err error) {
+	if u.prepared == nil {
+		return
+	}
+	u.store.rollback()
+	u.prepared, u.applied = nil, nil
+
+	return
+}
+
+// exec 在快照的副本上执行队列, 失败时调用方的模型不受影响
+func (u *unitOfWork) exec(s state) (applied []goresource.IDbModel, err error) {
+	applied = make([]goresource.IDbModel, 0, len(u.commitQueues))
+	for _, item := range u.commitQueues {
+		entry := clone(item.snapshot)
+		switch item.rt {
+		case repositorytype.Create:
+			err = s.create(entry)
+		case repositorytype.Delete:
+			err = applyDelete(s, entry, item.args...)
+		case repositorytype.Update:
+			err = applyUpdate(s, entry, item.args...)
+		case repositorytype.Upsert:
+			_, err = s.upsert(entry, item.args[0].(goresource.UpsertOptions))
+		}
+		if err != nil {
+			return
+		}
+		applied = append(applied, entry)
+	}
+
+	return
+}
+
+func (u *unitOfWork) reset() {
+	u.commitQueues = make([]commitQueueItem, 0)
+}
+
+func newUnitOfWork(s *store) *unitOfWork {
+	return &unitOfWork{
+		store:        s,
+		commitQueues: make([]commitQueueItem, 0),
+	}
+}
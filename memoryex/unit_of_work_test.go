@@ -0,0 +1,165 @@
+package memoryex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/repositorytype"
+	"github.com/xm-chentl/goresource/uowstatus"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_unitOfWork_Commit(test *testing.T) {
+	test.Run("success", func(t *testing.T) {
+		res := New()
+		uow := res.Uow()
+		db := res.Db(uow)
+		a := assert.New(t)
+		a.NoError(db.Create(&testPerson{ID: 1}))
+		a.NoError(db.Create(&testPerson{ID: 2}))
+
+		count, _ := res.Db().Query().Count(&testPerson{})
+		a.Equal(int64(0), count)
+		a.NoError(uow.Commit())
+		count, _ = res.Db().Query().Count(&testPerson{})
+		a.Equal(int64(2), count)
+	})
+
+	test.Run("all or nothing", func(t *testing.T) {
+		res := New()
+		a := assert.New(t)
+		a.NoError(res.Db().Create(&testPerson{ID: 1}))
+
+		uow := res.Uow()
+		db := res.Db(uow)
+		a.NoError(db.Create(&testPerson{ID: 2}))
+		a.NoError(db.Create(&testPerson{ID: 1}))
+		a.Equal(ErrDuplicateID, uow.Commit())
+
+		count, _ := res.Db().Query().Count(&testPerson{})
+		a.Equal(int64(1), count)
+	})
+
+	test.Run("goresource.Uow", func(t *testing.T) {
+		res := New()
+		uow := goresource.Uow()
+		a := assert.New(t)
+		a.NoError(res.Db(uow).Create(&testPerson{ID: 1}))
+		a.NoError(uow.Commit())
+
+		count, _ := res.Db().Query().Count(&testPerson{})
+		a.Equal(int64(1), count)
+	})
+
+	test.Run("snapshot at enqueue", func(t *testing.T) {
+		res := New()
+		uow := res.Uow()
+		entry := &testPerson{ID: 1, Name: "enqueued"}
+		a := assert.New(t)
+		a.NoError(res.Db(uow).Create(entry))
+		entry.Name = "changed"
+		a.NoError(uow.Commit())
+
+		row := testPerson{ID: 1}
+		a.NoError(res.Db().Query().First(&row))
+		a.Equal("enqueued", row.Name)
+	})
+
+	test.Run("failed commit keeps entry", func(t *testing.T) {
+		res := New()
+		a := assert.New(t)
+		a.NoError(res.Db().Create(&testPerson{ID: 1}))
+
+		uow := res.Uow()
+		entry := &testPerson{Name: "generated"}
+		a.NoError(res.Db(uow).Create(entry))
+		a.NoError(res.Db(uow).Create(&testPerson{ID: 1}))
+		a.Equal(ErrDuplicateID, uow.Commit())
+		a.Equal(int64(0), entry.ID)
+	})
+
+	test.Run("write back id", func(t *testing.T) {
+		res := New()
+		uow := res.Uow()
+		entry := &testPerson{Name: "generated"}
+		a := assert.New(t)
+		a.NoError(res.Db(uow).Create(entry))
+		a.NoError(uow.Commit())
+		a.Equal(int64(1), entry.ID)
+	})
+}
+
+func Test_unitOfWork_Discard(t *testing.T) {
+	res := New()
+	uow := res.Uow()
+	db := res.Db(uow)
+	a := assert.New(t)
+	a.NoError(db.Create(&testPerson{ID: 1}))
+	a.NoError(db.Update(&testPerson{ID: 1}))
+	a.Equal(map[repositorytype.Value]int{
+		repositorytype.Create: 1,
+		repositorytype.Update: 1,
+		repositorytype.Delete: 0,
+	}, uow.Pending())
+
+	uow.Discard()
+	a.Equal(uowstatus.Aborted, uow.Status())
+	a.Equal(0, uow.Pending()[repositorytype.Create])
+	a.Equal(errs.UowDiscarded, uow.Commit())
+
+	count, _ := res.Db().Query().Count(&testPerson{})
+	a.Equal(int64(0), count)
+}
+
+func Test_unitOfWork_Context(test *testing.T) {
+	test.Run("enlist by ctx", func(t *testing.T) {
+		res := New()
+		uow := goresource.Uow()
+		ctx := goresource.ContextWithUow(context.Background(), uow)
+		a := assert.New(t)
+		a.NoError(res.Db(ctx).Create(&testPerson{ID: 1}))
+		a.NoError(res.Db(ctx).Create(&testPerson{ID: 2}))
+
+		count, _ := res.Db().Query().Count(&testPerson{})
+		a.Equal(int64(0), count)
+		a.Equal(2, uow.Pending()[repositorytype.Create])
+		a.NoError(uow.Commit())
+		count, _ = res.Db().Query().Count(&testPerson{})
+		a.Equal(int64(2), count)
+	})
+
+	test.Run("resources of same type", func(t *testing.T) {
+		res, other := New(), New()
+		uow := goresource.Uow()
+		a := assert.New(t)
+		a.NoError(res.Db(uow).Create(&testPerson{ID: 1}))
+		a.NoError(res.Db(uow).Create(&testPerson{ID: 2}))
+		a.Equal(2, uow.Pending()[repositorytype.Create])
+
+		a.NoError(other.Db(uow).Create(&testPerson{ID: 3}))
+		a.Equal(3, uow.Pending()[repositorytype.Create])
+		a.NoError(uow.Commit())
+		count, _ := res.Db().Query().Count(&testPerson{})
+		a.Equal(int64(2), count)
+		count, _ = other.Db().Query().Count(&testPerson{})
+		a.Equal(int64(1), count)
+	})
+
+	test.Run("foreign uow", func(t *testing.T) {
+		res, other := New(), New()
+		ctx := goresource.ContextWithUow(context.Background(), &foreignUow{IUnitOfWork: other.Uow()})
+		a := assert.New(t)
+		a.ErrorIs(res.Db(ctx).Create(&testPerson{ID: 1}), errs.UowNotSupported)
+
+		count, _ := res.Db().Query().Count(&testPerson{})
+		a.Equal(int64(0), count)
+	})
+}
+
+// foreignUow 包装的工作单元, 既不是本资源的也不是 goresource.Uow()
+type foreignUow struct {
+	goresource.IUnitOfWork
+}
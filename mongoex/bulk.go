@@ -0,0 +1,175 @@
+package mongoex
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/tools"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateMany 每批一次 InsertMany (无序, 失败项不影响其他项)
+func (r *repository) CreateMany(entries []goresource.IDbModel, batchSize int) error {
+	for _, entry := range entries {
+		goresource.AuditCreate(r.ctx, entry)
+		if err := goresource.GenerateID(r.ctx, entry); err != nil {
+			return err
+		}
+		if v, ok := entry.GetID().(primitive.ObjectID); ok && v.IsZero() {
+			entry.SetID(primitive.NewObjectID())
+		}
+	}
+
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		batch := entries[start:end]
+		if r.uow != nil {
+			for _, entry := range batch {
+				r.uow.commitCreate(entry)
+			}
+			r.enlist()
+			return nil
+		}
+
+		docs := make([]interface{}, 0, len(batch))
+		for _, entry := range batch {
+			docs = append(docs, entry)
+		}
+		result, err := r.database.Collection(batch[0].Table()).InsertMany(r.ctx, docs, options.InsertMany().SetOrdered(false))
+		failed := writeErrors(err)
+		if err != nil && failed == nil {
+			return mapError(err)
+		}
+		if result != nil {
+			for index, id := range result.InsertedIDs {
+				if _, ok := failed[index]; !ok && index < len(batch) && id != nil {
+					batch[index].SetID(id)
+				}
+			}
+		}
+
+		return bulkError(failed)
+	})
+}
+
+// UpdateMany 每批一次 BulkWrite 整体更新; IVersioned 逐个按版本更新
+func (r *repository) UpdateMany(entries []goresource.IDbModel, batchSize int) error {
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		batch := entries[start:end]
+		for _, entry := range batch {
+			goresource.AuditUpdate(r.ctx, entry)
+		}
+		if r.uow != nil {
+			for _, entry := range batch {
+				r.uow.commitUpdate(entry)
+			}
+			r.enlist()
+			return nil
+		}
+
+		collectionDb := r.database.Collection(batch[0].Table())
+		if _, ok := batch[0].(goresource.IVersioned); ok {
+			bulkErr := &errs.BulkError{}
+			for index, entry := range batch {
+				if err := update(r.ctx, collectionDb, entry); err != nil {
+					bulkErr.Add(index, err)
+				}
+			}
+			return bulkErr.OrNil()
+		}
+
+		models := make([]mongo.WriteModel, 0, len(batch))
+		for _, entry := range batch {
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": entry.GetID()}).SetUpdate(bson.M{"$set": entry}))
+		}
+		_, err := collectionDb.BulkWrite(r.ctx, models, options.BulkWrite().SetOrdered(false))
+		failed := writeErrors(err)
+		if err != nil && failed == nil {
+			return mapError(err)
+		}
+
+		return bulkError(failed)
+	})
+}
+
+// DeleteMany 每批按 _id $in 删除, ISoftDeletable 写入删除时间
+func (r *repository) DeleteMany(entries []goresource.IDbModel, batchSize int) error {
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		batch := entries[start:end]
+		if r.uow != nil {
+			for _, entry := range batch {
+				if err := r.Delete(entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		bulkErr := &errs.BulkError{}
+		ids, indexes := make(bson.A, 0, len(batch)), make([]int, 0, len(batch))
+		for index, entry := range batch {
+			if tools.IsEmpty(entry.GetID()) {
+				bulkErr.Add(index, errs.DeleteFullNotAllowed)
+				continue
+			}
+			ids, indexes = append(ids, entry.GetID()), append(indexes, index)
+		}
+		if len(ids) == 0 {
+			return bulkErr.OrNil()
+		}
+
+		var err error
+		collectionDb, filter := r.database.Collection(batch[0].Table()), bson.M{"_id": bson.M{"$in": ids}}
+		if softDeletable, ok := batch[0].(goresource.ISoftDeletable); ok {
+			_, err = collectionDb.UpdateMany(r.ctx, filter, bson.M{"$set": bson.M{softDeletable.DeletedAtField(): goresource.Now()}})
+		} else {
+			_, err = collectionDb.DeleteMany(r.ctx, filter)
+		}
+		if err != nil {
+			for _, index := range indexes {
+				bulkErr.Add(index, mapError(err))
+			}
+		}
+
+		return bulkErr.OrNil()
+	})
+}
+
+func (r *repository) enlist() {
+	if r.repositoryBase != nil {
+		r.repositoryBase.SetUow(dbtype.Mongo, r.uow)
+	}
+}
+
+// writeErrors 批量写入错误中各项的错误(按批内索引), 不是批量写入错误时返回 nil
+func writeErrors(err error) map[int]error {
+	var bulkWriteErr mongo.BulkWriteException
+	if !errors.As(err, &bulkWriteErr) || len(bulkWriteErr.WriteErrors) == 0 {
+		return nil
+	}
+
+	res := make(map[int]error, len(bulkWriteErr.WriteErrors))
+	for _, writeErr := range bulkWriteErr.WriteErrors {
+		res[writeErr.Index] = mapError(writeErr)
+	}
+
+	return res
+}
+
+func bulkError(failed map[int]error) error {
+	bulkErr := &errs.BulkError{}
+	for index, err := range failed {
+		bulkErr.Add(index, err)
+	}
+	sort.Slice(bulkErr.Failures, func(i, j int) bool {
+		return bulkErr.Failures[i].Index < bulkErr.Failures[j].Index
+	})
+
+	return bulkErr.OrNil()
+}
@@ -0,0 +1,28 @@
+package mongoex
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type cursor struct {
+	ctx    context.Context
+	cursor *mongo.Cursor
+}
+
+func (c *cursor) Next() bool {
+	return c.cursor.Next(c.ctx)
+}
+
+func (c *cursor) Scan(res interface{}) error {
+	return c.cursor.Decode(res)
+}
+
+func (c *cursor) Err() error {
+	return mapError(c.cursor.Err())
+}
+
+func (c *cursor) Close() error {
+	return c.cursor.Close(c.ctx)
+}
@@ -0,0 +1,30 @@
+package mongoex
+
+import (
+	"fmt"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+func init() {
+	goresource.Register(dbtype.Mongo, goresource.DriverFunc(func(cfg goresource.ResourceConfig) (goresource.IResource, error) {
+		dbName := cfg.Database
+		if dbName == "" {
+			cs, err := connstring.ParseAndValidate(cfg.DSN)
+			if err != nil {
+				return nil, err
+			}
+			dbName = cs.Database
+		}
+		if dbName == "" {
+			return nil, fmt.Errorf("mongoex: database is empty")
+		}
+
+		return open(dbName, cfg.DSN, cfg.Pool)
+	}))
+	goresource.RegisterScheme("mongodb", dbtype.Mongo)
+	goresource.RegisterScheme("mongodb+srv", dbtype.Mongo)
+}
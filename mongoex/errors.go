@@ -0,0 +1,33 @@
+package mongoex
+
+import (
+	"errors"
+
+	"github.com/xm-chentl/goresource/errs"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// writeConflict 事务写冲突
+const writeConflict = 112
+
+// mapError 将驱动错误转换为 errs 中的分类
+func mapError(err error) error {
+	var serverErr mongo.ServerError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return errs.Wrap(errs.ErrNotFound, err)
+	case mongo.IsDuplicateKeyError(err):
+		return errs.Wrap(errs.ErrDuplicateKey, err)
+	case mongo.IsTimeout(err):
+		return errs.Wrap(errs.ErrTimeout, err)
+	case mongo.IsNetworkError(err):
+		return errs.Wrap(errs.ErrConnectionLost, err)
+	case errors.As(err, &serverErr) && (serverErr.HasErrorCode(writeConflict) || serverErr.HasErrorLabel("TransientTransactionError")):
+		return errs.Wrap(errs.ErrDeadlock, err)
+	}
+
+	return errs.Wrap(nil, err)
+}
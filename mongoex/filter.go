@@ -0,0 +1,72 @@
+package mongoex
+
+import (
+	"github.com/xm-chentl/goresource/expr"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toFilter 将 expr 编译为 mongo 筛选条件
+func toFilter(e expr.Expr) bson.M {
+	switch v := e.(type) {
+	case expr.Cond:
+		return condToFilter(v)
+	case expr.Logic:
+		if len(v.Items) == 0 {
+			if v.Op == expr.OpOr {
+				// 空 OR 不匹配任何文档
+				return bson.M{"_id": bson.M{"$exists": false}}
+			}
+			return bson.M{}
+		}
+		if len(v.Items) == 1 {
+			return toFilter(v.Items[0])
+		}
+		items := make(bson.A, 0, len(v.Items))
+		for _, item := range v.Items {
+			items = append(items, toFilter(item))
+		}
+		if v.Op == expr.OpOr {
+			return bson.M{"$or": items}
+		}
+		return bson.M{"$and": items}
+	case expr.Negation:
+		return bson.M{"$nor": bson.A{toFilter(v.Item)}}
+	}
+
+	return bson.M{}
+}
+
+// condToFilter expr.PK 映射为 _id
+func condToFilter(c expr.Cond) bson.M {
+	if c.Field == expr.PK {
+		c.Field = "_id"
+	}
+
+	switch c.Op {
+	case expr.OpEq:
+		return bson.M{c.Field: c.Value}
+	case expr.OpNe:
+		return bson.M{c.Field: bson.M{"$ne": c.Value}}
+	case expr.OpGt:
+		return bson.M{c.Field: bson.M{"$gt": c.Value}}
+	case expr.OpGte:
+		return bson.M{c.Field: bson.M{"$gte": c.Value}}
+	case expr.OpLt:
+		return bson.M{c.Field: bson.M{"$lt": c.Value}}
+	case expr.OpLte:
+		return bson.M{c.Field: bson.M{"$lte": c.Value}}
+	case expr.OpIn:
+		values, _ := c.Value.([]interface{})
+		return bson.M{c.Field: bson.M{"$in": bson.A(values)}}
+	case expr.OpLike:
+		pattern, _ := c.Value.(string)
+		return bson.M{c.Field: primitive.Regex{Pattern: expr.LikeToRegexp(pattern)}}
+	case expr.OpIsNull:
+		// 匹配 null 及不存在的字段
+		return bson.M{c.Field: nil}
+	}
+
+	return bson.M{}
+}
@@ -2,10 +2,14 @@ package mongoex
 
 import (
 	"context"
+	"errors"
 	"reflect"
 
 	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/deletedscope"
 	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/keyset"
 	"github.com/xm-chentl/goresource/tools"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -23,6 +27,15 @@ type query struct {
 	orders     []string // 1
 	orderBy    []string // -1
 	opts       []IOption
+	deleted    deletedscope.Value
+	batchSize  int
+	pageToken  *pageToken
+	next, prev string
+}
+
+type pageToken struct {
+	token  string
+	before bool
 }
 
 func (q *query) Asc(fields ...string) goresource.IQuery {
@@ -40,7 +53,10 @@ func (q *query) Asc(fields ...string) goresource.IQuery {
 }
 
 func (q *query) Count(entry goresource.IDbModel) (res int64, err error) {
-	res, err = q.database.Collection(entry.Table()).CountDocuments(q.ctx, q.filter)
+	defer q.reset()
+
+	res, err = q.database.Collection(entry.Table()).CountDocuments(q.ctx, q.getFilter(entry))
+	err = mapError(err)
 
 	return
 }
@@ -74,6 +90,29 @@ func (q *query) Fields(args ...interface{}) goresource.IQuery {
 func (q *query) Find(res interface{}) (err error) {
 	defer q.reset()
 
+	_, err = q.find(res, false)
+
+	return
+}
+
+// FindPage 同一筛选条件统计总数并查询数据
+func (q *query) FindPage(res interface{}) (info goresource.PageInfo, err error) {
+	defer q.reset()
+
+	if q.pageSize > 0 && q.page < 1 {
+		q.page = 1
+	}
+	total, err := q.find(res, true)
+	if err != nil {
+		return
+	}
+	info = goresource.NewPageInfo(total, q.page, q.pageSize)
+
+	return
+}
+
+// find count 为 true 时统计筛选条件的总数
+func (q *query) find(res interface{}, count bool) (total int64, err error) {
 	resRt := reflect.TypeOf(res)
 	resRv := reflect.ValueOf(res)
 	if resRt.Kind() == reflect.Ptr {
@@ -92,16 +131,92 @@ func (q *query) Find(res interface{}) (err error) {
 	for _, opt := range q.opts {
 		collectionDb = opt.Apply(q.database)
 	}
+	model, isModel := goresource.ModelOf(res)
 	if collectionDb == nil {
 		newEntry := reflect.New(resRt).Interface().(goresource.IDbModel)
 		collectionDb = q.database.Collection(newEntry.Table())
 	}
 
+	filter := q.getFilter(model)
+	var page *keyset.Page
+	if q.pageToken != nil {
+		q.next, q.prev = "", ""
+		if !isModel {
+			err = errs.ResIsNotIDbModel
+			return
+		}
+		if page, err = keyset.New(q.keys(), q.pageToken.token, q.pageToken.before, model); err != nil {
+			return
+		}
+		if cond := page.Cond(); cond != nil {
+			filter = bson.M{"$and": bson.A{filter, toFilter(cond)}}
+		}
+	}
+	if count {
+		if total, err = collectionDb.CountDocuments(q.ctx, filter); err != nil {
+			err = mapError(err)
+			return
+		}
+	}
+	cursor, err := collectionDb.Find(q.ctx, filter, q.findOptions(page))
+	if err != nil {
+		err = mapError(err)
+		return
+	}
+
+	tempSlice := reflect.MakeSlice(reflect.TypeOf(res).Elem(), 0, 0)
+	for cursor.Next(q.ctx) {
+		mappingInst := reflect.New(resRt).Interface()
+		err := cursor.Decode(mappingInst)
+		if err != nil {
+			break
+		}
+		tempSlice = reflect.Append(tempSlice, reflect.ValueOf(mappingInst).Elem())
+	}
+	resRv.Elem().Set(tempSlice)
+	if page != nil {
+		q.next, q.prev, err = page.Result(res, q.pageSize)
+	}
+
+	return
+}
+
+// keys 排序字段 (keyset 分页), 与排序一致先升序后降序
+func (q *query) keys() []keyset.Key {
+	keys := make([]keyset.Key, 0, len(q.orders)+len(q.orderBy))
+	for _, field := range q.orders {
+		keys = append(keys, keyset.Key{Field: field})
+	}
+	for _, field := range q.orderBy {
+		keys = append(keys, keyset.Key{Field: field, Desc: true})
+	}
+
+	return keys
+}
+
+// findOptions 分页、排序、字段、批次, page 不为空时按 keyset 排序、分页
+func (q *query) findOptions(page *keyset.Page) *options.FindOptions {
 	opt := &options.FindOptions{}
-	if q.page > 0 || q.pageSize > 0 {
+	if page != nil {
+		sort := make(bson.D, 0, len(page.Keys))
+		for _, key := range page.Orders() {
+			e := bson.E{Key: key.Field, Value: 1}
+			if key.Field == expr.PK {
+				e.Key = "_id"
+			}
+			if key.Desc {
+				e.Value = -1
+			}
+			sort = append(sort, e)
+		}
+		opt.SetSort(sort)
+		if limit := keyset.Limit(q.pageSize); limit > 0 {
+			opt.SetLimit(int64(limit))
+		}
+	} else if q.page > 0 || q.pageSize > 0 {
 		opt.SetSkip(int64((q.page - 1) * q.pageSize)).SetLimit(int64(q.pageSize))
 	}
-	if len(q.orders) > 0 || len(q.orderBy) > 0 {
+	if page == nil && (len(q.orders) > 0 || len(q.orderBy) > 0) {
 		sort := make(bson.D, 0)
 		for index := range q.orders {
 			sort = append(sort, bson.E{
@@ -120,27 +235,22 @@ func (q *query) Find(res interface{}) (err error) {
 	if q.projection != nil {
 		opt.SetProjection(q.projection)
 	}
-
-	cursor, err := collectionDb.Find(q.ctx, q.filter, opt)
-	if err != nil {
-		return
+	if q.batchSize > 0 {
+		opt.SetBatchSize(int32(q.batchSize))
 	}
 
-	tempSlice := reflect.MakeSlice(reflect.TypeOf(res).Elem(), 0, 0)
-	for cursor.Next(q.ctx) {
-		mappingInst := reflect.New(resRt).Interface()
-		err := cursor.Decode(mappingInst)
-		if err != nil {
-			break
-		}
-		tempSlice = reflect.Append(tempSlice, reflect.ValueOf(mappingInst).Elem())
+	return opt
+}
+
+func (q *query) First(res interface{}) (err error) {
+	if err = q.MustFirst(res); errors.Is(err, errs.ErrNotFound) {
+		err = nil
 	}
-	resRv.Elem().Set(tempSlice)
 
 	return
 }
 
-func (q *query) First(res interface{}) (err error) {
+func (q *query) MustFirst(res interface{}) (err error) {
 	defer q.reset()
 
 	entry, ok := res.(goresource.IDbModel)
@@ -174,13 +284,8 @@ func (q *query) First(res interface{}) (err error) {
 	}
 
 	collectionDb := q.database.Collection(entry.Table())
-	result := collectionDb.FindOne(q.ctx, q.filter, opt)
-	err = result.Err()
-	if err == mongo.ErrNoDocuments {
-		err = nil
-		return
-	}
-	if err != nil {
+	result := collectionDb.FindOne(q.ctx, q.getFilter(entry), opt)
+	if err = mapError(result.Err()); err != nil {
 		return
 	}
 	err = result.Decode(entry)
@@ -210,8 +315,13 @@ func (q query) ToArray(res interface{}) error {
 	return q.Find(res)
 }
 
+// Where args 0 bson.M 或 expr.Expr
 func (q *query) Where(args ...interface{}) goresource.IQuery {
 	if len(args) > 0 {
+		if e, ok := args[0].(expr.Expr); ok {
+			q.filter = toFilter(e)
+			return q
+		}
 		q.filter = args[0].(bson.M)
 	}
 
@@ -222,7 +332,81 @@ func (q *query) SetOpts(opts ...interface{}) goresource.IQuery {
 	return q
 }
 
+func (q *query) WithDeleted() goresource.IQuery {
+	q.deleted = deletedscope.Include
+	return q
+}
+
+func (q *query) OnlyDeleted() goresource.IQuery {
+	q.deleted = deletedscope.Only
+	return q
+}
+
+func (q *query) BatchSize(size int) goresource.IQuery {
+	q.batchSize = size
+	return q
+}
+
+func (q *query) After(token string) goresource.IQuery {
+	q.pageToken = &pageToken{token: token}
+	return q
+}
+
+func (q *query) Before(token string) goresource.IQuery {
+	q.pageToken = &pageToken{token: token, before: true}
+	return q
+}
+
+func (q *query) PageToken() (next, prev string) {
+	return q.next, q.prev
+}
+
+// Cursor BatchSize 为 mongo 游标每批返回的文档数
+func (q *query) Cursor(entry goresource.IDbModel) (res goresource.ICursor, err error) {
+	defer q.reset()
+
+	var collectionDb *mongo.Collection
+	for _, opt := range q.opts {
+		collectionDb = opt.Apply(q.database)
+	}
+	if collectionDb == nil {
+		collectionDb = q.database.Collection(entry.Table())
+	}
+	mongoCursor, err := collectionDb.Find(q.ctx, q.getFilter(entry), q.findOptions(nil))
+	if err != nil {
+		err = mapError(err)
+		return
+	}
+	res = &cursor{
+		ctx:    q.ctx,
+		cursor: mongoCursor,
+	}
+
+	return
+}
+
+// getFilter model 为 ISoftDeletable 时按范围筛选删除时间
+func (q query) getFilter(model interface{}) interface{} {
+	softDeletable, ok := model.(goresource.ISoftDeletable)
+	if !ok || q.deleted == deletedscope.Include {
+		return q.filter
+	}
+
+	deleted := bson.M{softDeletable.DeletedAtField(): nil}
+	if q.deleted == deletedscope.Only {
+		deleted = bson.M{softDeletable.DeletedAtField(): bson.M{"$ne": nil}}
+	}
+	if len(q.filter) == 0 {
+		return deleted
+	}
+
+	return bson.M{"$and": bson.A{q.filter, deleted}}
+}
+
 func (q *query) reset() {
 	q.filter = bson.M{}
 	q.projection = nil
+	q.deleted = deletedscope.Exclude
+	q.batchSize = 0
+	q.pageToken = nil
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/xm-chentl/goresource/expr"
+
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -177,6 +179,33 @@ func Test_query_Where(test *testing.T) {
 	})
 }
 
+func Test_query_getFilter(test *testing.T) {
+	test.Run("exclude", func(t *testing.T) {
+		q := query{filter: bson.M{"name": "ctl"}}
+		assert.Equal(t, bson.M{"$and": bson.A{bson.M{"name": "ctl"}, bson.M{"deletedAt": nil}}}, q.getFilter(&testSoftPerson{}))
+	})
+
+	test.Run("only", func(t *testing.T) {
+		q := query{filter: bson.M{}}
+		q.OnlyDeleted()
+		assert.Equal(t, bson.M{"deletedAt": bson.M{"$ne": nil}}, q.getFilter(&testSoftPerson{}))
+	})
+
+	test.Run("include", func(t *testing.T) {
+		q := query{filter: bson.M{}}
+		q.WithDeleted()
+		assert.Equal(t, bson.M{}, q.getFilter(&testSoftPerson{}))
+	})
+}
+
+type testSoftPerson struct {
+	testPerson
+}
+
+func (testSoftPerson) DeletedAtField() string {
+	return "deletedAt"
+}
+
 func Test_query_Find(test *testing.T) {
 	repo, err := getClient()
 	if err != nil {
@@ -627,3 +656,38 @@ func Test_query_First(test *testing.T) {
 		a.Equal(addEntries[0], queryEntry)
 	})
 }
+
+func Test_toFilter(test *testing.T) {
+	test.Run("eq", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal(bson.M{"name": "ctl"}, toFilter(expr.Eq("name", "ctl")))
+	})
+
+	test.Run("pk", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal(bson.M{"_id": "id-001"}, toFilter(expr.ByID("id-001")))
+	})
+
+	test.Run("and.or", func(t *testing.T) {
+		filter := toFilter(expr.And(
+			expr.Gte("age", 18),
+			expr.Or(expr.In("name", "a", "b"), expr.IsNull("name")),
+		))
+		a := assert.New(t)
+		a.Equal(bson.M{"$and": bson.A{
+			bson.M{"age": bson.M{"$gte": 18}},
+			bson.M{"$or": bson.A{
+				bson.M{"name": bson.M{"$in": bson.A{"a", "b"}}},
+				bson.M{"name": nil},
+			}},
+		}}, filter)
+	})
+
+	test.Run("not.like", func(t *testing.T) {
+		filter := toFilter(expr.Not(expr.Like("name", "c%")))
+		a := assert.New(t)
+		a.Equal(bson.M{"$nor": bson.A{
+			bson.M{"name": primitive.Regex{Pattern: "^c.*$"}},
+		}}, filter)
+	})
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/xm-chentl/goresource"
 	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/tools"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,6 +21,10 @@ type repository struct {
 }
 
 func (r *repository) Create(entry goresource.IDbModel, args ...interface{}) (err error) {
+	goresource.AuditCreate(r.ctx, entry)
+	if err = goresource.GenerateID(r.ctx, entry); err != nil {
+		return
+	}
 	if v, ok := entry.GetID().(primitive.ObjectID); ok {
 		if v.Hex() == "" || v.IsZero() {
 			entry.SetID(primitive.NewObjectID())
@@ -35,6 +41,7 @@ func (r *repository) Create(entry goresource.IDbModel, args ...interface{}) (err
 
 	result, err := r.database.Collection(entry.Table()).InsertOne(r.ctx, entry)
 	if err != nil {
+		err = mapError(err)
 		return
 	}
 	if result.InsertedID != nil {
@@ -44,8 +51,15 @@ func (r *repository) Create(entry goresource.IDbModel, args ...interface{}) (err
 	return
 }
 
-// delete args 0 -> 支持many
+// delete args 0 -> 支持many, ISoftDeletable 写入删除时间
 func (r *repository) Delete(entry goresource.IDbModel, args ...interface{}) (err error) {
+	if softDeletable, ok := entry.(goresource.ISoftDeletable); ok {
+		upset := bson.M{"$set": bson.M{softDeletable.DeletedAtField(): goresource.Now()}}
+		if len(args) == 0 {
+			return r.Update(entry, upset)
+		}
+		return r.Update(entry, upset, args[0])
+	}
 	if r.uow != nil {
 		r.uow.commitDelete(entry, args...)
 		if r.repositoryBase != nil {
@@ -59,12 +73,14 @@ func (r *repository) Delete(entry goresource.IDbModel, args ...interface{}) (err
 	} else {
 		_, err = r.database.Collection(entry.Table()).DeleteMany(r.ctx, args[0])
 	}
+	err = mapError(err)
 
 	return
 }
 
 // Update args 0 upset 1 filter
 func (r *repository) Update(entry goresource.IDbModel, args ...interface{}) (err error) {
+	goresource.AuditUpdate(r.ctx, entry)
 	if r.uow != nil {
 		r.uow.commitUpdate(entry, args...)
 		if r.repositoryBase != nil {
@@ -74,27 +90,109 @@ func (r *repository) Update(entry goresource.IDbModel, args ...interface{}) (err
 		return
 	}
 
-	collectionDb := r.database.Collection(entry.Table())
-	filter := bson.M{"_id": entry.GetID()}
-	// 默认更新完全
-	if len(args) == 0 {
-		_, err = collectionDb.UpdateOne(r.ctx, filter, bson.M{"$set": entry})
-		return
+	err = update(r.ctx, r.database.Collection(entry.Table()), entry, args...)
+
+	return
+}
+
+// update args 0 upset 1 filter, IVersioned 单条更新时按版本更新
+func update(ctx context.Context, collectionDb *mongo.Collection, entry goresource.IDbModel, args ...interface{}) (err error) {
+	guard := nextVersion(entry, args...)
+	if err = updateGuarded(ctx, collectionDb, entry, guard, args...); err != nil && guard != nil {
+		guard.Restore()
 	}
-	// one
-	if len(args) == 1 && args[0] != nil {
-		_, err = collectionDb.UpdateOne(r.ctx, filter, args[0])
-		return
+
+	return
+}
+
+// nextVersion IVersioned 单条更新时递增版本
+func nextVersion(entry goresource.IDbModel, args ...interface{}) *goresource.VersionGuard {
+	if len(args) > 1 || (len(args) == 1 && args[0] == nil) {
+		return nil
 	}
+
+	guard, _ := goresource.NextVersion(entry)
+	return guard
+}
+
+// updateGuarded 按 guard 的版本条件更新, 工作单元提交时使用入队时的 guard
+func updateGuarded(ctx context.Context, collectionDb *mongo.Collection, entry goresource.IDbModel, guard *goresource.VersionGuard, args ...interface{}) (err error) {
 	// many
 	if len(args) == 2 && args[0] != nil && args[1] != nil {
-		_, err = collectionDb.UpdateMany(r.ctx, args[1], args[0])
+		_, err = collectionDb.UpdateMany(ctx, args[1], args[0])
+		err = mapError(err)
+		return
+	}
+
+	filter := bson.M{"_id": entry.GetID()}
+	// 默认更新完全
+	var upset interface{} = bson.M{"$set": entry}
+	if len(args) == 1 && args[0] != nil {
+		// one, 加上更新审计字段
+		upset = args[0]
+		if audit := auditSet(entry); len(audit) > 0 {
+			if upset, err = withSet(upset, audit); err != nil {
+				return
+			}
+		}
+	} else if len(args) > 0 {
 		return
 	}
 
+	if guard != nil {
+		guard.Apply()
+		filter[guard.Field] = guard.Old
+		if len(args) > 0 {
+			if upset, err = withSet(upset, bson.M{guard.Field: guard.New()}); err != nil {
+				return
+			}
+		}
+	}
+	result, err := collectionDb.UpdateOne(ctx, filter, upset)
+	if err = mapError(err); err == nil && guard != nil && result.MatchedCount == 0 {
+		err = guard.Conflict()
+	}
+
 	return
 }
 
+// withSet upset 的 $set 中加上 fields
+func withSet(upset interface{}, fields bson.M) (interface{}, error) {
+	m, ok := upset.(bson.M)
+	if !ok {
+		return nil, errs.QueryArgsError
+	}
+
+	res := make(bson.M, len(m)+1)
+	for k, v := range m {
+		res[k] = v
+	}
+	set := bson.M{}
+	if v, ok := m["$set"].(bson.M); ok {
+		for k, v := range v {
+			set[k] = v
+		}
+	}
+	for k, v := range fields {
+		set[k] = v
+	}
+	res["$set"] = set
+
+	return res, nil
+}
+
+// auditSet 更新审计字段(bson 名)及模型中的值
+func auditSet(entry goresource.IDbModel) bson.M {
+	res := bson.M{}
+	for _, field := range goresource.UpdateAuditFields(entry) {
+		if rv, ok := tools.FieldValue(entry, field); ok {
+			res[field] = rv.Interface()
+		}
+	}
+
+	return res
+}
+
 func (r *repository) Query() goresource.IQuery {
 	return &query{
 		ctx:      r.ctx,
@@ -3,6 +3,7 @@ package mongoex
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
@@ -402,3 +403,32 @@ func Test_repository_Update(test *testing.T) {
 		a.Equal(expectedEntries, entries)
 	})
 }
+
+type testAuditedPerson struct {
+	testPerson `bson:",inline"`
+
+	UpdatedBy string `bson:"updatedBy"`
+}
+
+func (t *testAuditedPerson) SetUpdated(by string, at time.Time) {
+	t.UpdatedBy = by
+}
+
+func (t testAuditedPerson) UpdatedFields() []string {
+	return []string{"updatedBy"}
+}
+
+func Test_withSet(test *testing.T) {
+	test.Run("update audit", func(t *testing.T) {
+		entry := &testAuditedPerson{UpdatedBy: "ctl"}
+		upset, err := withSet(bson.M{"$set": bson.M{"name": "a"}, "$inc": bson.M{"age": 1}}, auditSet(entry))
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal(bson.M{"$set": bson.M{"name": "a", "updatedBy": "ctl"}, "$inc": bson.M{"age": 1}}, upset)
+	})
+
+	test.Run("not bson.M", func(t *testing.T) {
+		_, err := withSet(bson.D{}, bson.M{"version": 1})
+		assert.Error(t, err)
+	})
+}
@@ -2,34 +2,50 @@ package mongoex
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// pingTimeout 连接时 Ping 的超时时间
+const pingTimeout = 10 * time.Second
+
 type resource struct {
 	dbName   string
 	database *mongo.Database
+	isColony bool         // 是否为集群(副本集、分片), 集群支持事务
+	release  func() error // 共享客户端(多租户)时 Close 释放引用, 最后一个引用释放时断开
 }
 
+// Db 参数 ctx、uow (ctx 中的工作单元 goresource.ContextWithUow)
 func (f resource) Db(args ...interface{}) goresource.IRepository {
+	dbArgs := goresource.ParseDbArgs(args...)
 	repo := &repository{
+		ctx:      dbArgs.Ctx,
 		database: f.database,
 	}
-	for index := range args {
-		if ctx, ok := args[index].(context.Context); ok {
-			repo.ctx = ctx
-		} else if uow, ok := args[index].(*unitOfWork); ok {
-			repo.uow = uow
-		} else if uow, ok := args[index].(goresource.IUnitOfWork); ok {
-			repo.uow = newUnitOfWork(f.database)
-			repo.repositoryBase = goresource.NewRepository(uow)
+	if uow, ok := dbArgs.Uow.(*unitOfWork); ok {
+		repo.uow = uow
+	} else if dbArgs.Uow != nil {
+		if repo.repositoryBase = goresource.NewRepository(dbArgs.Uow); repo.repositoryBase == nil {
+			return goresource.NewErrRepository(errs.UowNotSupported)
+		}
+		if enlisted, ok := repo.repositoryBase.GetUow(dbtype.Mongo, func(enlisted goresource.IUnitOfWork) bool {
+			uow, ok := enlisted.(*unitOfWork)
+			return ok && uow.database == f.database
+		}); ok {
+			repo.uow = enlisted.(*unitOfWork)
+		}
+		if repo.uow == nil {
+			repo.uow = newUnitOfWork(f.database, f.isColony)
 		}
-	}
-	if repo.ctx == nil {
-		repo.ctx = context.Background()
 	}
 	if repo.uow != nil {
 		repo.uow.ctx = repo.ctx
@@ -39,21 +55,109 @@ func (f resource) Db(args ...interface{}) goresource.IRepository {
 }
 
 func (f resource) Uow() goresource.IUnitOfWork {
-	return newUnitOfWork(f.database)
+	return newUnitOfWork(f.database, f.isColony)
+}
+
+func (f resource) DbType() dbtype.Value {
+	return dbtype.Mongo
 }
 
+func (f resource) Ping(ctx context.Context) error {
+	return f.database.Client().Ping(ctx, nil)
+}
+
+// Close 断开客户端连接
+func (f resource) Close() error {
+	if f.release != nil {
+		return f.release()
+	}
+
+	return f.database.Client().Disconnect(context.Background())
+}
+
+// New 连接失败时 panic, 请使用 Open
 func New(dbName, dsn string) goresource.IResource {
-	opt := options.Client().ApplyURI(dsn)
-	client, err := mongo.NewClient(opt)
+	res, err := Open(dbName, dsn)
 	if err != nil {
-		panic("create connect to mongo faild err: " + err.Error())
+		panic(err.Error())
 	}
-	if err = client.Connect(context.Background()); err != nil {
-		panic("connect to mongo faild err: " + err.Error())
+
+	return res
+}
+
+// Open 连接数据库, pools 为连接池配置
+func Open(dbName, dsn string, pools ...goresource.PoolConfig) (goresource.IResource, error) {
+	poolCfg := goresource.PoolConfig{}
+	if len(pools) > 0 {
+		poolCfg = pools[0]
+	}
+
+	return open(dbName, dsn, poolCfg)
+}
+
+func open(dbName, dsn string, poolCfg goresource.PoolConfig) (res goresource.IResource, err error) {
+	client, err := connect(dsn, poolCfg)
+	if err != nil {
+		return
 	}
 
-	return &resource{
+	database := client.Database(dbName)
+	colony, err := isColony(database)
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return
+	}
+	res = &resource{
 		dbName:   dbName,
-		database: client.Database(dbName),
+		database: database,
+		isColony: colony,
+	}
+
+	return
+}
+
+func connect(dsn string, poolCfg goresource.PoolConfig) (client *mongo.Client, err error) {
+	opt := options.Client().ApplyURI(dsn)
+	if poolCfg.MaxOpenConns > 0 {
+		opt.SetMaxPoolSize(uint64(poolCfg.MaxOpenConns))
+	}
+	if poolCfg.MaxIdleConns > 0 {
+		opt.SetMinPoolSize(uint64(poolCfg.MaxIdleConns))
+	}
+	if poolCfg.MaxIdleTime > 0 {
+		opt.SetMaxConnIdleTime(poolCfg.MaxIdleTime)
+	}
+	if client, err = mongo.NewClient(opt); err != nil {
+		err = fmt.Errorf("create connect to mongo faild err: %w", err)
+		return
 	}
+	if err = client.Connect(context.Background()); err != nil {
+		err = fmt.Errorf("connect to mongo faild err: %w", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err = client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
+		err = fmt.Errorf("ping mongo faild err: %w", mapError(err))
+	}
+
+	return
+}
+
+// isColony 副本集或分片集群, 查询失败时返回错误(不能降级为单机提交)
+func isColony(database *mongo.Database) (colony bool, err error) {
+	var res bson.M
+	if err = database.RunCommand(context.Background(), bson.D{{Key: "isMaster", Value: 1}}).Decode(&res); err != nil {
+		err = fmt.Errorf("mongo isMaster faild err: %w", mapError(err))
+		return
+	}
+	if _, ok := res["setName"]; ok {
+		colony = true
+		return
+	}
+	colony = res["msg"] == "isdbgrid"
+
+	return
 }
@@ -2,6 +2,7 @@ package mongoex
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -35,3 +36,51 @@ func getClient() (repo repository, err error) {
 
 	return
 }
+
+func Test_Open(test *testing.T) {
+	test.Run("connect failed", func(t *testing.T) {
+		res, err := Open("testdb", "mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=200&connectTimeoutMS=200")
+		if err == nil {
+			t.Fatal("expect connect error")
+		}
+		if res != nil {
+			t.Fatal("expect nil resource")
+		}
+		if !strings.Contains(err.Error(), "ping mongo") {
+			t.Fatal("expect ping error", err)
+		}
+	})
+}
+
+func Test_sharedClient(t *testing.T) {
+	connects := 0
+	shared := &sharedClient{
+		connect: func() (client *mongo.Client, colony bool, err error) {
+			connects++
+			if client, err = mongo.NewClient(options.Client().ApplyURI("mongodb://127.0.0.1:1")); err != nil {
+				return
+			}
+			err = client.Connect(context.Background())
+			return
+		},
+	}
+	resolver := func() *resource {
+		client, _, err := shared.acquire()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &resource{database: client.Database("test"), release: shared.release}
+	}
+
+	first, second := resolver(), resolver()
+	if err := first.Close(); err != nil || shared.client == nil {
+		t.Fatal("expect client kept until last release", err)
+	}
+	if err := second.Close(); err != nil || shared.client != nil {
+		t.Fatal("expect client disconnected", err)
+	}
+	resolver()
+	if connects != 2 {
+		t.Fatal("expect reconnect after disconnected", connects)
+	}
+}
@@ -0,0 +1,92 @@
+package mongoex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/xm-chentl/goresource"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TenantDatabase 每租户数据库, 所有租户共享一个客户端
+// 客户端在第一个租户打开时连接, 所有租户资源关闭(工厂 Close、Evict、空闲关闭)后断开
+// format 数据库名格式, 如: app_%s
+func TenantDatabase(dsn, format string, pools ...goresource.PoolConfig) goresource.TenantResolver {
+	poolCfg := goresource.PoolConfig{}
+	if len(pools) > 0 {
+		poolCfg = pools[0]
+	}
+
+	shared := &sharedClient{
+		connect: func() (client *mongo.Client, colony bool, err error) {
+			if client, err = connect(dsn, poolCfg); err != nil {
+				return
+			}
+			if colony, err = isColony(client.Database("admin")); err != nil {
+				_ = client.Disconnect(context.Background())
+			}
+			return
+		},
+	}
+	return func(tenantID string) (goresource.IResource, error) {
+		client, colony, err := shared.acquire()
+		if err != nil {
+			return nil, err
+		}
+
+		var once sync.Once
+		dbName := fmt.Sprintf(format, tenantID)
+		return &resource{
+			dbName:   dbName,
+			database: client.Database(dbName),
+			isColony: colony,
+			release: func() (err error) {
+				once.Do(func() {
+					err = shared.release()
+				})
+				return
+			},
+		}, nil
+	}
+}
+
+// sharedClient 引用计数的共享客户端
+type sharedClient struct {
+	connect func() (*mongo.Client, bool, error)
+
+	mutex  sync.Mutex
+	client *mongo.Client
+	colony bool
+	refs   int
+}
+
+func (c *sharedClient) acquire() (client *mongo.Client, colony bool, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.client == nil {
+		if c.client, c.colony, err = c.connect(); err != nil {
+			c.client = nil
+			return
+		}
+	}
+	c.refs++
+
+	return c.client, c.colony, nil
+}
+
+// release 最后一个引用释放时断开
+func (c *sharedClient) release() (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.refs--; c.refs > 0 || c.client == nil {
+		return
+	}
+	err = c.client.Disconnect(context.Background())
+	c.client = nil
+
+	return
+}
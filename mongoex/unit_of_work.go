@@ -5,6 +5,9 @@ import (
 	"sync"
 
 	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/repositorytype"
+	"github.com/xm-chentl/goresource/uowstatus"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,29 +17,69 @@ import (
 type commitQueueInfo struct {
 	entry goresource.IDbModel
 	args  []interface{}
+	guard *goresource.VersionGuard // 乐观锁, 入队时递增版本
 }
 
 type unitOfWork struct {
+	goresource.CommitHooks
+
 	ctx      context.Context
 	database *mongo.Database
 
 	isColony      bool // 是否为集群
+	session       mongo.Session
+	status        uowstatus.Value
 	collectionMap sync.Map
 	createQueue   []commitQueueInfo
 	deleteQueue   []commitQueueInfo
 	updateQueue   []commitQueueInfo
+	upsertQueue   []commitQueueInfo
 }
 
 func (u *unitOfWork) Commit() (err error) {
+	if u.status == uowstatus.Aborted {
+		err = errs.UowDiscarded
+		return
+	}
 	if u.isColony {
 		err = u.commitByColony()
-		return
+	} else {
+		err = u.commitBySingle()
+	}
+	if err == nil {
+		u.status = uowstatus.Committed
+		u.RunCommitted()
 	}
-	err = u.commitBySingle()
 
 	return
 }
 
+// Rollback 放弃队列，已 Prepare 的事务回滚
+func (u *unitOfWork) Rollback() (err error) {
+	err = u.RollbackPrepared()
+	u.reset()
+	u.status = uowstatus.Aborted
+
+	return
+}
+
+func (u *unitOfWork) Discard() {
+	_ = u.Rollback()
+}
+
+func (u *unitOfWork) Pending() map[repositorytype.Value]int {
+	return map[repositorytype.Value]int{
+		repositorytype.Create: len(u.createQueue),
+		repositorytype.Update: len(u.updateQueue),
+		repositorytype.Delete: len(u.deleteQueue),
+		repositorytype.Upsert: len(u.upsertQueue),
+	}
+}
+
+func (u *unitOfWork) Status() uowstatus.Value {
+	return u.status
+}
+
 func (u *unitOfWork) commitCreate(entry goresource.IDbModel) {
 	u.createQueue = append(u.createQueue, commitQueueInfo{
 		entry: entry,
@@ -54,6 +97,14 @@ func (u *unitOfWork) commitUpdate(entry goresource.IDbModel, args ...interface{}
 	u.updateQueue = append(u.updateQueue, commitQueueInfo{
 		entry: entry,
 		args:  args,
+		guard: nextVersion(entry, args...),
+	})
+}
+
+func (u *unitOfWork) commitUpsert(entry goresource.IDbModel, opts goresource.UpsertOptions) {
+	u.upsertQueue = append(u.upsertQueue, commitQueueInfo{
+		entry: entry,
+		args:  []interface{}{opts},
 	})
 }
 
@@ -72,78 +123,95 @@ func (u *unitOfWork) reset() {
 	u.createQueue = make([]commitQueueInfo, 0)
 	u.deleteQueue = make([]commitQueueInfo, 0)
 	u.updateQueue = make([]commitQueueInfo, 0)
+	u.upsertQueue = make([]commitQueueInfo, 0)
 	u.collectionMap.Range(func(key, _ interface{}) bool {
 		u.collectionMap.Delete(key)
 		return true
 	})
 }
 
-func (u *unitOfWork) commitByColony() error {
-	// 暂时不使用事务
-	return u.database.Client().UseSession(u.ctx, func(sessionCtx mongo.SessionContext) (err error) {
-		if err = sessionCtx.StartTransaction(); err != nil {
-			return
-		}
+func (u *unitOfWork) commitByColony() (err error) {
+	if err = u.Prepare(); err != nil {
+		return
+	}
+	err = u.CommitPrepared()
 
-		var collectionDb *mongo.Collection
-		for index := range u.createQueue {
-			item := u.createQueue[index]
-			if v, ok := item.entry.GetID().(primitive.ObjectID); ok {
-				if v.IsZero() {
-					item.entry.SetID(primitive.NewObjectID())
-				}
-			}
+	return
+}
 
-			collectionDb = u.getCollection(item.entry)
-			_, err = collectionDb.InsertOne(sessionCtx, item.entry)
-			if err != nil {
-				return
-			}
-		}
-		for index := range u.deleteQueue {
-			item := u.deleteQueue[index]
-			collectionDb = u.getCollection(item.entry)
-			if item.args != nil {
-				_, err = collectionDb.DeleteOne(sessionCtx, item.args)
-			} else {
-				_, err = collectionDb.DeleteOne(sessionCtx, bson.M{"_id": item.entry.GetID()})
-			}
-			if err != nil {
-				return
-			}
-		}
-		for index := range u.updateQueue {
-			item := u.updateQueue[index]
-			collectionDb = u.getCollection(item.entry)
-			filter := bson.M{"_id": item.entry.GetID()}
-			// 默认更新完全
-			if len(item.args) == 0 {
-				_, err = collectionDb.UpdateOne(sessionCtx, filter, bson.M{"$set": item.entry})
-				continue
-			}
-			// one
-			if len(item.args) == 1 && item.args[0] != nil {
-				_, err = collectionDb.UpdateOne(sessionCtx, filter, item.args[0])
-				continue
-			}
-			// many
-			if len(item.args) == 2 && item.args[0] != nil && item.args[1] != nil {
-				_, err = collectionDb.UpdateMany(sessionCtx, item.args[1], item.args[0])
-				continue
-			}
-			if err != nil {
-				return
-			}
-		}
-		err = sessionCtx.CommitTransaction(context.Background())
+// commitBySingle 单机无事务, 失败时可能已部分执行, 同样清空队列(不可重放)
+func (u *unitOfWork) commitBySingle() (err error) {
+	defer u.reset()
 
+	err = u.exec(u.ctx)
+
+	return
+}
+
+// Prepare 集群(副本集)开启事务并执行队列(不提交)，单机不支持事务
+func (u *unitOfWork) Prepare() (err error) {
+	if u.status == uowstatus.Aborted {
+		err = errs.UowDiscarded
 		return
-	})
+	}
+	if !u.isColony {
+		err = errs.UowPrepareNotSupported
+		return
+	}
+
+	session, err := u.database.Client().StartSession()
+	if err != nil {
+		err = mapError(err)
+		return
+	}
+	if err = session.StartTransaction(); err != nil {
+		err = mapError(err)
+		session.EndSession(u.ctx)
+		return
+	}
+	u.session = session
+	if err = u.exec(mongo.NewSessionContext(u.ctx, session)); err != nil {
+		_ = u.RollbackPrepared()
+	}
+
+	return
 }
 
-func (u *unitOfWork) commitBySingle() (err error) {
-	defer u.reset()
+func (u *unitOfWork) CommitPrepared() (err error) {
+	defer u.release()
+
+	if u.session == nil {
+		return
+	}
+	if err = u.session.CommitTransaction(u.ctx); err == nil {
+		u.reset()
+		u.status = uowstatus.Committed
+		u.RunCommitted()
+	}
+	err = mapError(err)
 
+	return
+}
+
+func (u *unitOfWork) RollbackPrepared() (err error) {
+	defer u.release()
+
+	if u.session == nil {
+		return
+	}
+	err = u.session.AbortTransaction(u.ctx)
+
+	return
+}
+
+func (u *unitOfWork) release() {
+	if u.session != nil {
+		u.session.EndSession(u.ctx)
+	}
+	u.session = nil
+}
+
+func (u *unitOfWork) exec(ctx context.Context) (err error) {
 	var collectionDb *mongo.Collection
 	for index := range u.createQueue {
 		item := u.createQueue[index]
@@ -154,43 +222,33 @@ func (u *unitOfWork) commitBySingle() (err error) {
 		}
 
 		collectionDb = u.getCollection(item.entry)
-		_, err = collectionDb.InsertOne(u.ctx, item.entry)
-		if err != nil {
+		if _, err = collectionDb.InsertOne(ctx, item.entry); err != nil {
+			err = mapError(err)
 			return
 		}
 	}
 	for index := range u.deleteQueue {
 		item := u.deleteQueue[index]
 		collectionDb = u.getCollection(item.entry)
-		if item.args != nil {
-			_, err = collectionDb.DeleteOne(u.ctx, item.args)
+		if len(item.args) > 0 && item.args[0] != nil {
+			_, err = collectionDb.DeleteMany(ctx, item.args[0])
 		} else {
-			_, err = collectionDb.DeleteOne(u.ctx, bson.M{"_id": item.entry.GetID()})
+			_, err = collectionDb.DeleteOne(ctx, bson.M{"_id": item.entry.GetID()})
 		}
 		if err != nil {
+			err = mapError(err)
 			return
 		}
 	}
 	for index := range u.updateQueue {
 		item := u.updateQueue[index]
-		collectionDb = u.getCollection(item.entry)
-		filter := bson.M{"_id": item.entry.GetID()}
-		// 默认更新完全
-		if len(item.args) == 0 {
-			_, err = collectionDb.UpdateOne(u.ctx, filter, bson.M{"$set": item.entry})
-			continue
-		}
-		// one
-		if len(item.args) == 1 && item.args[0] != nil {
-			_, err = collectionDb.UpdateOne(u.ctx, filter, item.args[0])
-			continue
-		}
-		// many
-		if len(item.args) == 2 && item.args[0] != nil && item.args[1] != nil {
-			_, err = collectionDb.UpdateMany(u.ctx, item.args[1], item.args[0])
-			continue
+		if err = updateGuarded(ctx, u.getCollection(item.entry), item.entry, item.guard, item.args...); err != nil {
+			return
 		}
-		if err != nil {
+	}
+	for index := range u.upsertQueue {
+		item := u.upsertQueue[index]
+		if _, err = upsert(ctx, u.getCollection(item.entry), item.entry, item.args[0].(goresource.UpsertOptions)); err != nil {
 			return
 		}
 	}
@@ -261,11 +319,14 @@ func (u *unitOfWork) commitBySingle() (err error) {
 // 	return
 // }
 
-func newUnitOfWork(database *mongo.Database) *unitOfWork {
+func newUnitOfWork(database *mongo.Database, isColony bool) *unitOfWork {
 	return &unitOfWork{
+		ctx:         context.Background(),
 		database:    database,
+		isColony:    isColony,
 		createQueue: make([]commitQueueInfo, 0),
 		deleteQueue: make([]commitQueueInfo, 0),
 		updateQueue: make([]commitQueueInfo, 0),
+		upsertQueue: make([]commitQueueInfo, 0),
 	}
 }
@@ -0,0 +1,135 @@
+package mongoex
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/xm-chentl/goresource"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Upsert UpdateOne(upsert), 冲突字段为筛选条件, UpdateFields 为 $set, 其余字段为 $setOnInsert
+// 按冲突字段更新已存在的数据时读取已存在数据的主键
+func (r *repository) Upsert(entry goresource.IDbModel, opts goresource.UpsertOptions) (inserted bool, err error) {
+	goresource.AuditCreate(r.ctx, entry)
+	if err = goresource.GenerateID(r.ctx, entry); err != nil {
+		return
+	}
+	if v, ok := entry.GetID().(primitive.ObjectID); ok && v.IsZero() {
+		entry.SetID(primitive.NewObjectID())
+	}
+	if r.uow != nil {
+		r.uow.commitUpsert(entry, opts)
+		r.enlist()
+		return
+	}
+
+	inserted, err = upsert(r.ctx, r.database.Collection(entry.Table()), entry, opts)
+
+	return
+}
+
+func upsert(ctx context.Context, collectionDb *mongo.Collection, entry goresource.IDbModel, opts goresource.UpsertOptions) (inserted bool, err error) {
+	filter, update, err := upsertDoc(entry, opts)
+	if err != nil {
+		return
+	}
+
+	result, err := collectionDb.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		err = mapError(err)
+		return
+	}
+	if inserted = result.UpsertedCount > 0; inserted && result.UpsertedID != nil {
+		entry.SetID(result.UpsertedID)
+	}
+	if !inserted && len(opts.ConflictFields) > 0 {
+		err = readID(ctx, collectionDb, filter, entry)
+	}
+
+	return
+}
+
+// readID 读取 filter 对应数据的主键
+func readID(ctx context.Context, collectionDb *mongo.Collection, filter bson.M, entry goresource.IDbModel) (err error) {
+	raw, err := collectionDb.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"_id": 1})).DecodeBytes()
+	if err != nil {
+		return mapError(err)
+	}
+
+	idValue := raw.Lookup("_id")
+	if current := entry.GetID(); current != nil {
+		id := reflect.New(reflect.TypeOf(current))
+		if err = idValue.Unmarshal(id.Interface()); err == nil {
+			entry.SetID(id.Elem().Interface())
+		}
+		return
+	}
+
+	var id interface{}
+	if err = idValue.Unmarshal(&id); err == nil {
+		entry.SetID(id)
+	}
+
+	return
+}
+
+// upsertDoc 筛选条件及更新文档, UpdateFields 为空时冲突字段、创建审计字段以外的全部字段为 $set
+func upsertDoc(entry goresource.IDbModel, opts goresource.UpsertOptions) (filter, update bson.M, err error) {
+	data, err := bson.Marshal(entry)
+	if err != nil {
+		return
+	}
+	doc := bson.M{}
+	if err = bson.Unmarshal(data, &doc); err != nil {
+		return
+	}
+
+	conflictFields := opts.ConflictFields
+	if len(conflictFields) == 0 {
+		conflictFields = []string{"_id"}
+	}
+	filter = bson.M{}
+	for _, field := range conflictFields {
+		filter[field] = doc[field]
+		delete(doc, field)
+	}
+
+	set, setOnInsert := bson.M{}, bson.M{}
+	if len(opts.UpdateFields) == 0 {
+		created := make(map[string]bool)
+		for _, key := range goresource.CreateAuditFields(entry) {
+			created[key] = true
+		}
+		for k, v := range doc {
+			if k != "_id" && !created[k] {
+				set[k] = v
+			}
+		}
+	} else {
+		for _, field := range opts.UpdateFields {
+			if v, ok := doc[field]; ok {
+				set[field] = v
+			}
+		}
+	}
+	for k, v := range doc {
+		if _, ok := set[k]; !ok {
+			setOnInsert[k] = v
+		}
+	}
+
+	update = bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(setOnInsert) > 0 {
+		update["$setOnInsert"] = setOnInsert
+	}
+
+	return
+}
@@ -0,0 +1,160 @@
+package mysqlex
+
+import (
+	"reflect"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/repositorytype"
+	"github.com/xm-chentl/goresource/tools"
+
+	"gorm.io/gorm"
+)
+
+// CreateMany 每批使用 CreateInBatches 一条 INSERT, 失败时逐个创建找出失败项
+// 工作单元中每批为一个队列项, 提交时一条 INSERT
+func (r repository) CreateMany(entries []goresource.IDbModel, batchSize int) error {
+	for _, entry := range entries {
+		goresource.AuditCreate(r.db.Statement.Context, entry)
+		if err := goresource.GenerateID(r.db.Statement.Context, entry); err != nil {
+			return err
+		}
+	}
+
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		batch := entries[start:end]
+		rows, err := rowsOf(batch)
+		if err != nil {
+			return err
+		}
+		if r.uow != nil {
+			r.uow.commitQueues = append(r.uow.commitQueues, commitQueueItem{
+				rt:    repositorytype.Create,
+				entry: batch[0],
+				rows:  rows,
+			})
+			if r.repositoryBase != nil {
+				r.repositoryBase.SetUow(dbtype.MySQL, r.uow)
+			}
+			return nil
+		}
+
+		if err = r.db.Model(batch[0]).CreateInBatches(rows, len(batch)).Error; err == nil {
+			return nil
+		}
+
+		bulkErr := &errs.BulkError{}
+		for index, entry := range batch {
+			if err := r.db.Model(entry).Create(entry).Error; err != nil {
+				bulkErr.Add(index, err)
+			}
+		}
+		return bulkErr.OrNil()
+	})
+}
+
+// UpdateMany 逐个更新(gorm 不支持多行不同值的更新)
+func (r repository) UpdateMany(entries []goresource.IDbModel, batchSize int) error {
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		batch := entries[start:end]
+		for _, entry := range batch {
+			goresource.AuditUpdate(r.db.Statement.Context, entry)
+		}
+		if r.uow != nil {
+			r.enlist(repositorytype.Update, batch)
+			return nil
+		}
+
+		bulkErr := &errs.BulkError{}
+		for index, entry := range batch {
+			if err := updateEntry(r.db, entry); err != nil {
+				bulkErr.Add(index, err)
+			}
+		}
+		return bulkErr.OrNil()
+	})
+}
+
+// DeleteMany 每批按主键 IN 删除, ISoftDeletable 写入删除时间
+func (r repository) DeleteMany(entries []goresource.IDbModel, batchSize int) error {
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		batch := entries[start:end]
+		if r.uow != nil {
+			r.enlist(repositorytype.Delete, batch)
+			return nil
+		}
+
+		bulkErr := &errs.BulkError{}
+		ids, indexes := make([]interface{}, 0, len(batch)), make([]int, 0, len(batch))
+		for index, entry := range batch {
+			if tools.IsEmpty(entry.GetID()) {
+				bulkErr.Add(index, errs.DeleteFullNotAllowed)
+				continue
+			}
+			ids, indexes = append(ids, entry.GetID()), append(indexes, index)
+		}
+		if len(ids) == 0 {
+			return bulkErr.OrNil()
+		}
+
+		if err := deleteByIDs(r.db, batch[0], ids); err != nil {
+			for _, index := range indexes {
+				bulkErr.Add(index, err)
+			}
+		}
+		return bulkErr.OrNil()
+	})
+}
+
+func (r repository) enlist(rt repositorytype.Value, entries []goresource.IDbModel, args ...interface{}) {
+	for _, entry := range entries {
+		item := commitQueueItem{
+			rt:    rt,
+			entry: entry,
+			args:  args,
+		}
+		if rt == repositorytype.Update {
+			item.guard = nextVersion(entry)
+		}
+		r.uow.commitQueues = append(r.uow.commitQueues, item)
+	}
+	if r.repositoryBase != nil {
+		r.repositoryBase.SetUow(dbtype.MySQL, r.uow)
+	}
+}
+
+// rowsOf 同类型模型的切片(CreateInBatches 的参数)
+func rowsOf(batch []goresource.IDbModel) (interface{}, error) {
+	rows := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(batch[0])), 0, len(batch))
+	for _, entry := range batch {
+		rv := reflect.ValueOf(entry)
+		if rv.Type() != rows.Type().Elem() {
+			return nil, errs.ResIsNotIDbModel
+		}
+		rows = reflect.Append(rows, rv)
+	}
+
+	return rows.Interface(), nil
+}
+
+// deleteByIDs 按主键删除, model 仅用于确定表及主键(使用空模型, 避免按 model 的主键筛选)
+func deleteByIDs(db *gorm.DB, model goresource.IDbModel, ids []interface{}) error {
+	empty := reflect.New(reflect.Indirect(reflect.ValueOf(model)).Type()).Interface()
+	softDeletable, ok := model.(goresource.ISoftDeletable)
+	if !ok {
+		return db.Delete(empty, ids).Error
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(empty); err != nil {
+		return err
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return errs.DeleteFullNotAllowed
+	}
+
+	return db.Unscoped().Model(empty).
+		Where(dialect.Field(stmt.Schema.PrioritizedPrimaryField.DBName)+" IN ?", ids).
+		UpdateColumn(softDeletable.DeletedAtField(), goresource.Now()).Error
+}
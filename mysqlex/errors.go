@@ -0,0 +1,73 @@
+package mysqlex
+
+import (
+	"errors"
+
+	"github.com/xm-chentl/goresource/errs"
+
+	sqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// mysql 错误码
+const (
+	errDuplicateEntry   = 1062
+	errLockWaitTimeout  = 1205
+	errDeadlock         = 1213
+	errRowIsReferenced  = 1451
+	errNoReferencedRow  = 1452
+	errQueryInterrupted = 3024
+)
+
+// mapError 将驱动错误转换为 errs 中的分类
+func mapError(err error) error {
+	var mysqlErr *sqldriver.MySQLError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return errs.Wrap(errs.ErrNotFound, err)
+	case errors.Is(err, sqldriver.ErrInvalidConn):
+		return errs.Wrap(errs.ErrConnectionLost, err)
+	case !errors.As(err, &mysqlErr):
+		return errs.Wrap(nil, err)
+	}
+
+	switch mysqlErr.Number {
+	case errDuplicateEntry:
+		return errs.Wrap(errs.ErrDuplicateKey, err)
+	case errRowIsReferenced, errNoReferencedRow:
+		return errs.Wrap(errs.ErrForeignKey, err)
+	case errDeadlock:
+		return errs.Wrap(errs.ErrDeadlock, err)
+	case errLockWaitTimeout, errQueryInterrupted:
+		return errs.Wrap(errs.ErrTimeout, err)
+	}
+
+	return err
+}
+
+// registerErrorCallbacks 各操作执行后转换 db.Error
+func registerErrorCallbacks(db *gorm.DB) (err error) {
+	callback := db.Callback()
+	translate := func(db *gorm.DB) {
+		db.Error = mapError(db.Error)
+	}
+	processors := []interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{
+		callback.Create(),
+		callback.Query(),
+		callback.Update(),
+		callback.Delete(),
+		callback.Row(),
+		callback.Raw(),
+	}
+	for _, processor := range processors {
+		if err = processor.Register("goresource:map_error", translate); err != nil {
+			return
+		}
+	}
+
+	return
+}
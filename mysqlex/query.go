@@ -1,33 +1,83 @@
 package mysqlex
 
 import (
+	"database/sql"
+	"errors"
 	"reflect"
 	"strings"
 
 	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/deletedscope"
 	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/keyset"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
+// dialect expr 编译方言
+var dialect = expr.Dialect{
+	Field: func(field string) string {
+		if strings.ContainsAny(field, "`.()") {
+			return field
+		}
+		return "`" + field + "`"
+	},
+	Placeholder: func(int) string {
+		return "?"
+	},
+}
+
 type query struct {
 	db        *gorm.DB
 	fields    []string
 	whereArgs []interface{}
+	whereExpr expr.Expr
 	order     string
 	whereSql  string
 	page      int
 	pageSize  int
 	opts      []interface{}
+	deleted   deletedscope.Value
+	keys      []keyset.Key // 排序字段 (keyset 分页)
+	pageToken *pageToken
+	next      string
+	prev      string
+}
+
+type pageToken struct {
+	token  string
+	before bool
+}
+
+// cursor 按行读取 (驱动逐行读取结果流)
+type cursor struct {
+	db   *gorm.DB
+	rows *sql.Rows
+}
+
+func (c *cursor) Next() bool {
+	return c.rows.Next()
+}
+
+func (c *cursor) Scan(res interface{}) error {
+	return c.db.ScanRows(c.rows, res)
+}
+
+func (c *cursor) Err() error {
+	return c.rows.Err()
+}
+
+func (c *cursor) Close() error {
+	return c.rows.Close()
 }
 
 func (q *query) Count(entry goresource.IDbModel) (count int64, err error) {
 	defer q.reset()
 
 	db := q.db.Model(entry)
-	if q.whereSql != "" {
-		db = db.Where(q.whereSql, q.whereArgs...)
-	}
+	db = q.applyWhere(db, entry)
 	if len(q.opts) > 0 {
 		for _, o := range q.opts {
 			if v, ok := o.(IOption); ok {
@@ -68,6 +118,29 @@ func (q *query) Fields(args ...interface{}) goresource.IQuery {
 func (q *query) Find(res interface{}) (err error) {
 	defer q.reset()
 
+	_, err = q.find(res, false)
+
+	return
+}
+
+// FindPage 同一筛选条件统计总数并查询数据
+func (q *query) FindPage(res interface{}) (info goresource.PageInfo, err error) {
+	defer q.reset()
+
+	if q.pageSize > 0 && q.page < 1 {
+		q.page = 1
+	}
+	total, err := q.find(res, true)
+	if err != nil {
+		return
+	}
+	info = goresource.NewPageInfo(total, q.page, q.pageSize)
+
+	return
+}
+
+// find count 为 true 时统计筛选条件的总数
+func (q *query) find(res interface{}, count bool) (total int64, err error) {
 	resRt := reflect.TypeOf(res)
 	if resRt.Kind() != reflect.Ptr {
 		err = errs.ResIsNotPtr
@@ -75,38 +148,61 @@ func (q *query) Find(res interface{}) (err error) {
 	}
 
 	resRt = resRt.Elem()
-	db := q.db.Model(reflect.New(resRt).Interface())
-	if q.order != "" {
-		db = db.Order(q.order)
-	}
-	if q.whereSql != "" {
-		db = db.Where(q.whereSql, q.whereArgs...)
+	db := q.applyWhere(q.db.Model(reflect.New(resRt).Interface()), res)
+	for _, o := range q.opts {
+		if v, ok := o.(IOption); ok {
+			db = v.Apply(db)
+		}
 	}
-	if q.page > 0 && q.pageSize > 0 {
-		db = db.Offset((q.page - 1) * q.pageSize).Limit(q.pageSize)
+	if count {
+		// Session 后 Count 与 Find 各自使用条件的副本
+		db = db.Session(&gorm.Session{})
+		if err = db.Count(&total).Error; err != nil {
+			return
+		}
 	}
-	if len(q.opts) > 0 {
-		for _, o := range q.opts {
-			if v, ok := o.(IOption); ok {
-				db = v.Apply(db)
-			}
+	var page *keyset.Page
+	if q.pageToken != nil {
+		q.next, q.prev = "", ""
+		model, ok := goresource.ModelOf(res)
+		if !ok {
+			err = errs.ResIsNotIDbModel
+			return
+		}
+		if page, err = keyset.New(q.keys, q.pageToken.token, q.pageToken.before, model); err != nil {
+			return
 		}
+		db = q.applyKeyset(db, model, page)
+	} else if q.order != "" {
+		db = db.Order(q.order)
+	}
+	if page == nil && q.page > 0 && q.pageSize > 0 {
+		db = db.Offset((q.page - 1) * q.pageSize).Limit(q.pageSize)
 	}
 
-	err = db.Find(res).Error
+	if err = db.Find(res).Error; err != nil || page == nil {
+		return
+	}
+	q.next, q.prev, err = page.Result(res, q.pageSize)
 
 	return
 }
 
 func (q *query) First(res interface{}) (err error) {
+	if err = q.MustFirst(res); errors.Is(err, errs.ErrNotFound) {
+		err = nil
+	}
+
+	return
+}
+
+func (q *query) MustFirst(res interface{}) (err error) {
 	defer q.reset()
 	db := q.db
 	if q.order != "" {
 		db = db.Order(q.order)
 	}
-	if q.whereSql != "" {
-		db = db.Where(q.whereSql, q.whereArgs...)
-	}
+	db = q.applyWhere(db, res)
 	if len(q.opts) > 0 {
 		for _, o := range q.opts {
 			if v, ok := o.(IOption); ok {
@@ -116,9 +212,6 @@ func (q *query) First(res interface{}) (err error) {
 	}
 
 	err = db.First(res).Error
-	if err == gorm.ErrRecordNotFound {
-		err = nil
-	}
 
 	return
 }
@@ -126,6 +219,9 @@ func (q *query) First(res interface{}) (err error) {
 func (q *query) Asc(fields ...string) goresource.IQuery {
 	if len(fields) > 0 {
 		q.genOrder(" ASC", fields...)
+		for _, field := range fields {
+			q.keys = append(q.keys, keyset.Key{Field: field})
+		}
 	}
 
 	return q
@@ -134,6 +230,9 @@ func (q *query) Asc(fields ...string) goresource.IQuery {
 func (q *query) Desc(fields ...string) goresource.IQuery {
 	if len(fields) > 0 {
 		q.genOrder(" DESC", fields...)
+		for _, field := range fields {
+			q.keys = append(q.keys, keyset.Key{Field: field, Desc: true})
+		}
 	}
 
 	return q
@@ -161,8 +260,16 @@ func (q *query) ToArray(res interface{}) (err error) {
 	return q.Find(res)
 }
 
+// Where args 0 where-sql > 1 where-args 或 args 0 expr.Expr
 func (q *query) Where(args ...interface{}) goresource.IQuery {
 	if len(args) > 0 {
+		if e, ok := args[0].(expr.Expr); ok {
+			q.whereExpr = e
+			q.whereSql = ""
+			q.whereArgs = make([]interface{}, 0)
+			return q
+		}
+		q.whereExpr = nil
 		q.whereSql = args[0].(string)
 		if len(args) > 1 {
 			q.whereArgs = args[1:]
@@ -182,6 +289,151 @@ func (q *query) SetOpts(opts ...interface{}) goresource.IQuery {
 	return q
 }
 
+// BatchSize mysql 驱动逐行读取结果流, 不分批
+func (q *query) BatchSize(size int) goresource.IQuery {
+	return q
+}
+
+func (q *query) Cursor(entry goresource.IDbModel) (res goresource.ICursor, err error) {
+	defer q.reset()
+
+	db := q.db.Model(entry)
+	if q.order != "" {
+		db = db.Order(q.order)
+	}
+	db = q.applyWhere(db, entry)
+	if q.page > 0 && q.pageSize > 0 {
+		db = db.Offset((q.page - 1) * q.pageSize).Limit(q.pageSize)
+	}
+	for _, o := range q.opts {
+		if v, ok := o.(IOption); ok {
+			db = v.Apply(db)
+		}
+	}
+	rows, err := db.Rows()
+	if err != nil {
+		return
+	}
+	res = &cursor{
+		db:   db,
+		rows: rows,
+	}
+
+	return
+}
+
+func (q *query) After(token string) goresource.IQuery {
+	q.pageToken = &pageToken{token: token}
+	return q
+}
+
+func (q *query) Before(token string) goresource.IQuery {
+	q.pageToken = &pageToken{token: token, before: true}
+	return q
+}
+
+func (q *query) PageToken() (next, prev string) {
+	return q.next, q.prev
+}
+
+func (q *query) WithDeleted() goresource.IQuery {
+	q.deleted = deletedscope.Include
+	return q
+}
+
+func (q *query) OnlyDeleted() goresource.IQuery {
+	q.deleted = deletedscope.Only
+	return q
+}
+
+// applyWhere expr.PK 映射为 model 的主键, 按范围筛选删除时间
+func (q *query) applyWhere(db *gorm.DB, model interface{}) *gorm.DB {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		stmt.Schema = nil
+	}
+	db = q.applyDeleted(db, stmt.Schema, model)
+	if q.whereExpr != nil {
+		e := q.whereExpr
+		if stmt.Schema != nil && stmt.Schema.PrioritizedPrimaryField != nil {
+			e = expr.ReplaceField(e, expr.PK, stmt.Schema.PrioritizedPrimaryField.DBName)
+		}
+		where, whereArgs := expr.ToSQL(e, dialect, 0)
+		return db.Where(where, whereArgs...)
+	}
+	if q.whereSql != "" {
+		db = db.Where(q.whereSql, q.whereArgs...)
+	}
+
+	return db
+}
+
+// applyDeleted ISoftDeletable 按删除时间筛选; gorm.DeletedAt 由 gorm 排除, 包含、仅已删除时使用 Unscoped
+func (q *query) applyDeleted(db *gorm.DB, modelSchema *schema.Schema, model interface{}) *gorm.DB {
+	field, scoped := "", false
+	if entry, ok := goresource.ModelOf(model); ok {
+		if softDeletable, ok := entry.(goresource.ISoftDeletable); ok {
+			field = dialect.Field(softDeletable.DeletedAtField())
+		}
+	}
+	if field == "" && modelSchema != nil {
+		for _, f := range modelSchema.Fields {
+			if f.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+				field, scoped = dialect.Field(f.DBName), true
+				break
+			}
+		}
+	}
+	if field == "" {
+		return db
+	}
+
+	switch q.deleted {
+	case deletedscope.Include:
+		db = db.Unscoped()
+	case deletedscope.Only:
+		db = db.Unscoped().Where(field + " IS NOT NULL")
+	default:
+		if !scoped {
+			db = db.Where(field + " IS NULL")
+		}
+	}
+
+	return db
+}
+
+// applyKeyset keyset 分页的条件、排序、数量, expr.PK 映射为 model 的主键
+func (q *query) applyKeyset(db *gorm.DB, model goresource.IDbModel, page *keyset.Page) *gorm.DB {
+	pk := ""
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err == nil && stmt.Schema.PrioritizedPrimaryField != nil {
+		pk = stmt.Schema.PrioritizedPrimaryField.DBName
+	}
+	if cond := page.Cond(); cond != nil {
+		if pk != "" {
+			cond = expr.ReplaceField(cond, expr.PK, pk)
+		}
+		where, whereArgs := expr.ToSQL(cond, dialect, 0)
+		db = db.Where(where, whereArgs...)
+	}
+	for _, key := range page.Orders() {
+		field := key.Field
+		if field == expr.PK {
+			field = pk
+		}
+		if key.Desc {
+			db = db.Order(dialect.Field(field) + " DESC")
+		} else {
+			db = db.Order(dialect.Field(field) + " ASC")
+		}
+	}
+	if limit := keyset.Limit(q.pageSize); limit > 0 {
+		db = db.Limit(limit)
+	}
+
+	return db
+}
+
 func (q *query) genOrder(suffix string, fields ...string) {
 	if q.order == "" {
 		q.order = strings.Join(fields, ", ") + " " + suffix
@@ -196,4 +448,8 @@ func (q *query) reset() {
 	q.fields = make([]string, 0)
 	q.order = ""
 	q.whereArgs = make([]interface{}, 0)
+	q.whereExpr = nil
+	q.deleted = deletedscope.Exclude
+	q.keys = nil
+	q.pageToken = nil
 }
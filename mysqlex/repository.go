@@ -17,6 +17,10 @@ type repository struct {
 }
 
 func (r repository) Create(entry goresource.IDbModel, args ...interface{}) (err error) {
+	goresource.AuditCreate(r.db.Statement.Context, entry)
+	if err = goresource.GenerateID(r.db.Statement.Context, entry); err != nil {
+		return
+	}
 	whereArgs, opts, db, hook := optionApply(r.db, entry, args...)
 	if r.uow != nil {
 		r.uow.commitQueues = append(r.uow.commitQueues, commitQueueItem{
@@ -51,12 +55,13 @@ func (r repository) Delete(entry goresource.IDbModel, args ...interface{}) (err
 		}
 		return
 	}
-	err = db.Model(entry).Delete(entry, args...).Error
+	err = deleteEntry(db, entry, args)
 
 	return
 }
 
 func (r repository) Update(entry goresource.IDbModel, args ...interface{}) (err error) {
+	goresource.AuditUpdate(r.db.Statement.Context, entry)
 	whereArgs, opts, db, hook := optionApply(r.db, entry, args...)
 	if r.uow != nil {
 		r.uow.commitQueues = append(r.uow.commitQueues, commitQueueItem{
@@ -65,6 +70,7 @@ func (r repository) Update(entry goresource.IDbModel, args ...interface{}) (err
 			args:   whereArgs,
 			opts:   opts,
 			filter: hook,
+			guard:  nextVersion(entry),
 		})
 		if r.repositoryBase != nil {
 			r.repositoryBase.SetUow(dbtype.MySQL, r.uow)
@@ -72,7 +78,7 @@ func (r repository) Update(entry goresource.IDbModel, args ...interface{}) (err
 
 		return
 	}
-	err = db.Model(entry).Save(entry).Error
+	err = updateEntry(db, entry)
 
 	return
 }
@@ -83,6 +89,55 @@ func (r repository) Query() goresource.IQuery {
 	}
 }
 
+// updateEntry IVersioned 按版本更新(全部字段), 未更新到数据时返回冲突
+func updateEntry(db *gorm.DB, entry goresource.IDbModel) (err error) {
+	guard := nextVersion(entry)
+	if err = updateGuarded(db, entry, guard); err != nil && guard != nil {
+		guard.Restore()
+	}
+
+	return
+}
+
+// nextVersion IVersioned 时递增版本
+func nextVersion(entry goresource.IDbModel) *goresource.VersionGuard {
+	guard, _ := goresource.NextVersion(entry)
+	return guard
+}
+
+// updateGuarded 按 guard 的版本条件更新, 工作单元提交时使用入队时的 guard
+func updateGuarded(db *gorm.DB, entry goresource.IDbModel, guard *goresource.VersionGuard) error {
+	if guard == nil {
+		return db.Model(entry).Save(entry).Error
+	}
+
+	guard.Apply()
+	db = db.Model(entry).Where(dialect.Field(guard.Field)+" = ?", guard.Old).Select("*").Updates(entry)
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return guard.Conflict()
+	}
+
+	return nil
+}
+
+// deleteEntry ISoftDeletable 写入删除时间
+func deleteEntry(db *gorm.DB, entry goresource.IDbModel, args []interface{}) error {
+	softDeletable, ok := entry.(goresource.ISoftDeletable)
+	if !ok {
+		return db.Model(entry).Delete(entry, args...).Error
+	}
+
+	db = db.Unscoped().Model(entry)
+	if len(args) > 0 {
+		db = db.Where(args[0], args[1:]...)
+	}
+
+	return db.UpdateColumn(softDeletable.DeletedAtField(), goresource.Now()).Error
+}
+
 func optionApply(db *gorm.DB, entry goresource.IDbModel, vs ...interface{}) (
 	args []interface{},
 	opts []IOption,
@@ -1,7 +1,12 @@
 package mysqlex
 
 import (
+	"database/sql/driver"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/xm-chentl/goresource"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -230,3 +235,76 @@ func Test_Update(test *testing.T) {
 		}, updatedEntry)
 	})
 }
+
+type testAuditPerson struct {
+	ID        int64     `gorm:"column:id;primaryKey"`
+	Name      string    `gorm:"column:name"`
+	Code      string    `gorm:"column:code"`
+	CreatedBy string    `gorm:"column:created_by"`
+	Created   time.Time `gorm:"column:created"`
+}
+
+func (m testAuditPerson) GetID() interface{} {
+	return m.ID
+}
+
+func (m *testAuditPerson) SetID(v interface{}) {
+	m.ID = v.(int64)
+}
+
+func (m testAuditPerson) Table() string {
+	return "test_audit_person"
+}
+
+func (m testAuditPerson) TableName() string {
+	return m.Table()
+}
+
+func (m *testAuditPerson) SetCreated(by string, at time.Time) {
+	m.CreatedBy, m.Created = by, at
+}
+
+func (m testAuditPerson) CreatedFields() []string {
+	return []string{"created_by", "created"}
+}
+
+func Test_upsertEntry(test *testing.T) {
+	test.Run("inserted", func(t *testing.T) {
+		fake := &fakeDriver{}
+		res := newFakeResource(t, fake)
+		entry := &testAuditPerson{ID: 1, Name: "a", Code: "c"}
+		inserted, err := goresource.Upsert(res.Db(), entry, goresource.UpsertOptions{ConflictFields: []string{"code"}})
+		a := assert.New(t)
+		a.NoError(err)
+		a.True(inserted)
+		a.Len(fake.inserts, 1)
+		a.True(strings.HasSuffix(fake.inserts[0], "ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)"), fake.inserts[0])
+		a.Empty(fake.queries)
+		a.Equal(int64(1), entry.ID)
+	})
+
+	test.Run("updated reads stored id", func(t *testing.T) {
+		fake := &fakeDriver{rowsAffected: 2, queryColumns: []string{"id"}, queryRows: [][]driver.Value{{int64(7)}}}
+		res := newFakeResource(t, fake)
+		entry := &testAuditPerson{ID: 1, Name: "a", Code: "c"}
+		inserted, err := goresource.Upsert(res.Db(), entry, goresource.UpsertOptions{ConflictFields: []string{"code"}})
+		a := assert.New(t)
+		a.NoError(err)
+		a.False(inserted)
+		a.Len(fake.queries, 1)
+		a.Contains(fake.queries[0], "`code` = ?")
+		a.Equal(int64(7), entry.ID)
+	})
+
+	test.Run("uow", func(t *testing.T) {
+		fake := &fakeDriver{rowsAffected: 2, queryColumns: []string{"id"}, queryRows: [][]driver.Value{{int64(7)}}}
+		res := newFakeResource(t, fake)
+		uow := res.Uow()
+		entry := &testAuditPerson{ID: 1, Name: "a", Code: "c"}
+		_, err := goresource.Upsert(res.Db(uow), entry, goresource.UpsertOptions{ConflictFields: []string{"code"}})
+		a := assert.New(t)
+		a.NoError(err)
+		a.NoError(uow.Commit())
+		a.Equal(int64(7), entry.ID)
+	})
+}
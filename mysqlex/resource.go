@@ -2,8 +2,12 @@ package mysqlex
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -17,24 +21,33 @@ type resource struct {
 type Config struct {
 	MaxIdleConns int // 最大空闲连接
 	MaxOpenConns int // 使用最大连接数
+
+	ConnMaxLifetime time.Duration // 连接最大存活时间
+	ConnMaxIdleTime time.Duration // 连接最大空闲时间
 }
 
-// Db 参数请按 ctx uow
+// Db 参数 ctx、uow (ctx 中的工作单元 goresource.ContextWithUow)
 func (f *resource) Db(args ...interface{}) goresource.IRepository {
-	repo := &repository{}
-	for _, a := range args {
-		if ctx, ok := a.(context.Context); ok {
-			repo.db = f.db.WithContext(ctx)
-		} else if uow, ok := a.(*unitOfWork); ok {
-			repo.uow = uow
-		} else if uow, ok := a.(goresource.IUnitOfWork); ok {
+	dbArgs := goresource.ParseDbArgs(args...)
+	repo := &repository{
+		db: f.db.WithContext(dbArgs.Ctx),
+	}
+	if uow, ok := dbArgs.Uow.(*unitOfWork); ok {
+		repo.uow = uow
+	} else if dbArgs.Uow != nil {
+		if repo.repositoryBase = goresource.NewRepository(dbArgs.Uow); repo.repositoryBase == nil {
+			return goresource.NewErrRepository(errs.UowNotSupported)
+		}
+		if enlisted, ok := repo.repositoryBase.GetUow(dbtype.MySQL, func(enlisted goresource.IUnitOfWork) bool {
+			uow, ok := enlisted.(*unitOfWork)
+			return ok && uow.db == f.db
+		}); ok {
+			repo.uow = enlisted.(*unitOfWork)
+		}
+		if repo.uow == nil {
 			repo.uow = newUnitOfWork(f.db)
-			repo.repositoryBase = goresource.NewRepository(uow)
 		}
 	}
-	if repo.db == nil {
-		repo.db = f.db.Debug()
-	}
 
 	return repo
 }
@@ -43,32 +56,86 @@ func (f *resource) Uow() goresource.IUnitOfWork {
 	return newUnitOfWork(f.db)
 }
 
+func (f *resource) DbType() dbtype.Value {
+	return dbtype.MySQL
+}
+
+func (f *resource) Ping(ctx context.Context) error {
+	sqlDb, err := f.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDb.PingContext(ctx)
+}
+
+// Close 关闭连接池
+func (f *resource) Close() error {
+	sqlDb, err := f.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDb.Close()
+}
+
+// New 连接失败时 panic, 请使用 Open
 func New(dsn string, configs ...Config) goresource.IResource {
+	res, err := Open(dsn, configs...)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return res
+}
+
+// Open 连接数据库, 未指定配置时 最大空闲连接 10 最大连接 100
+func Open(dsn string, configs ...Config) (goresource.IResource, error) {
+	cfg := Config{
+		MaxIdleConns: 10,
+		MaxOpenConns: 100,
+	}
+	if len(configs) > 0 {
+		cfg = configs[0]
+	}
+
+	return open(dsn, cfg)
+}
+
+func open(dsn string, cfg Config) (res goresource.IResource, err error) {
 	if dsn == "" {
-		panic("mysqlex.New parameter dsn is empty")
+		err = fmt.Errorf("mysqlex.New parameter dsn is empty")
+		return
 	}
 
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
 	if err != nil {
-		panic("mysqlex.New open database is failed err: " + err.Error())
+		err = fmt.Errorf("mysqlex.New open database is failed err: %w", err)
+		return
 	}
 
 	// add connection pool mode
 	sqlDb, err := db.DB()
 	if err != nil {
-		panic("open db failed: " + err.Error())
+		err = fmt.Errorf("open db failed: %w", err)
+		return
 	}
-	if len(configs) > 0 {
-		cfg := configs[0]
-		sqlDb.SetMaxIdleConns(cfg.MaxIdleConns)
-		sqlDb.SetMaxOpenConns(cfg.MaxOpenConns)
-	} else {
-		sqlDb.SetMaxIdleConns(10)
-		sqlDb.SetMaxOpenConns(100)
+	sqlDb.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDb.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDb.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 	}
-
-	return &resource{
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDb.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+	if err = registerErrorCallbacks(db); err != nil {
+		err = fmt.Errorf("register callbacks failed: %w", err)
+		return
+	}
+	res = &resource{
 		dsn: dsn,
 		db:  db,
 	}
+
+	return
 }
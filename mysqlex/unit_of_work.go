@@ -1,8 +1,12 @@
 package mysqlex
 
 import (
+	"reflect"
+
 	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
 	"github.com/xm-chentl/goresource/repositorytype"
+	"github.com/xm-chentl/goresource/uowstatus"
 
 	"gorm.io/gorm"
 )
@@ -19,55 +23,159 @@ type commitQueueItem struct {
 	opts   []IOption
 	filter HookFilter
 	entry  goresource.IDbModel
+	guard  *goresource.VersionGuard // 乐观锁, 入队时递增版本
+	rows   interface{}              // CreateMany 的一批模型(模型切片), 为空时创建 entry
 }
 
 type unitOfWork struct {
+	goresource.CommitHooks
+
 	db           *gorm.DB
+	tx           *gorm.DB
+	status       uowstatus.Value
 	commitQueues []commitQueueItem
 }
 
-func (u unitOfWork) Commit() (err error) {
+func (u *unitOfWork) Commit() (err error) {
+	if u.status == uowstatus.Aborted {
+		err = errs.UowDiscarded
+		return
+	}
 	if len(u.commitQueues) == 0 {
 		return
 	}
 
-	err = u.db.Transaction(func(tx *gorm.DB) (txErr error) {
-		for _, item := range u.commitQueues {
-			isPointTable := false
-			for _, o := range item.opts {
-				if _, ok := o.(*OptionTableSuffix); ok {
-					isPointTable = true
-				}
-				tx = o.Apply(tx)
+	if err = u.db.Transaction(u.exec); err == nil {
+		u.commitQueues = make([]commitQueueItem, 0)
+		u.status = uowstatus.Committed
+		u.RunCommitted()
+	}
+	err = mapError(err)
+
+	return
+}
+
+// Rollback 放弃队列，已 Prepare 的事务回滚
+func (u *unitOfWork) Rollback() (err error) {
+	err = u.RollbackPrepared()
+	u.commitQueues = make([]commitQueueItem, 0)
+	u.status = uowstatus.Aborted
+
+	return
+}
+
+func (u *unitOfWork) Discard() {
+	_ = u.Rollback()
+}
+
+func (u *unitOfWork) Pending() map[repositorytype.Value]int {
+	res := map[repositorytype.Value]int{
+		repositorytype.Create: 0,
+		repositorytype.Update: 0,
+		repositorytype.Delete: 0,
+	}
+	for _, item := range u.commitQueues {
+		res[item.rt]++
+	}
+
+	return res
+}
+
+func (u *unitOfWork) Status() uowstatus.Value {
+	return u.status
+}
+
+// Prepare 开启事务并执行队列(不提交)
+func (u *unitOfWork) Prepare() (err error) {
+	if u.status == uowstatus.Aborted {
+		err = errs.UowDiscarded
+		return
+	}
+	if len(u.commitQueues) == 0 {
+		return
+	}
+
+	tx := u.db.Begin()
+	if err = tx.Error; err != nil {
+		err = mapError(err)
+		return
+	}
+	if err = u.exec(tx); err != nil {
+		tx.Rollback()
+		return
+	}
+	u.tx = tx
+
+	return
+}
+
+func (u *unitOfWork) CommitPrepared() (err error) {
+	if u.tx == nil {
+		return
+	}
+	if err = u.tx.Commit().Error; err == nil {
+		u.commitQueues = make([]commitQueueItem, 0)
+		u.status = uowstatus.Committed
+		u.RunCommitted()
+	}
+	err = mapError(err)
+	u.tx = nil
+
+	return
+}
+
+func (u *unitOfWork) RollbackPrepared() (err error) {
+	if u.tx == nil {
+		return
+	}
+	err = u.tx.Rollback().Error
+	u.tx = nil
+
+	return
+}
+
+func (u *unitOfWork) exec(tx *gorm.DB) (txErr error) {
+	for _, item := range u.commitQueues {
+		isPointTable := false
+		for _, o := range item.opts {
+			if _, ok := o.(*OptionTableSuffix); ok {
+				isPointTable = true
+			}
+			tx = o.Apply(tx)
+		}
+		// todo: 坑 (指定了.Table()，会覆盖整笨tx对象，没有清空)【暂时处理】
+		if len(item.opts) == 0 || !isPointTable {
+			tx.Table(item.entry.Table())
+		}
+		if item.filter != nil {
+			item.entry = item.filter(item.entry)
+		}
+		if item.rt == repositorytype.Create && item.rows != nil {
+			if txErr = tx.Model(item.entry).CreateInBatches(item.rows, reflect.ValueOf(item.rows).Len()).Error; txErr != nil {
+				return
+			}
+		} else if item.rt == repositorytype.Create {
+			if txErr = tx.Model(item.entry).Create(item.entry).Error; txErr != nil {
+				return
+			}
+		} else if item.rt == repositorytype.Delete {
+			args := item.args
+			if args == nil {
+				args = make([]interface{}, 0)
 			}
-			// todo: 坑 (指定了.Table()，会覆盖整笨tx对象，没有清空)【暂时处理】
-			if len(item.opts) == 0 || !isPointTable {
-				tx.Table(item.entry.Table())
+			if txErr = deleteEntry(tx, item.entry, args); txErr != nil {
+				return
 			}
-			if item.filter != nil {
-				item.entry = item.filter(item.entry)
+		} else if item.rt == repositorytype.Update {
+			if txErr = updateGuarded(tx, item.entry, item.guard); txErr != nil {
+				return
 			}
-			if item.rt == repositorytype.Create {
-				if txErr = tx.Model(item.entry).Create(item.entry).Error; txErr != nil {
-					return
-				}
-			} else if item.rt == repositorytype.Delete {
-				args := item.args
-				if args == nil {
-					args = make([]interface{}, 0)
-				}
-				if txErr = tx.Model(item.entry).Delete(item.entry, args...).Error; txErr != nil {
-					return
-				}
-			} else if item.rt == repositorytype.Update {
-				if txErr = tx.Model(item.entry).Save(item.entry).Error; txErr != nil {
-					return
-				}
+		} else if item.rt == repositorytype.Upsert {
+			if _, txErr = upsertEntry(tx, item.entry, item.args[0].(goresource.UpsertOptions)); txErr != nil {
+				return
 			}
 		}
-
-		return
-	})
+	}
 
 	return
 }
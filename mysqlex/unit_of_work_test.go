@@ -1,9 +1,21 @@
 package mysqlex
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
 	"testing"
 
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/repositorytype"
+
+	sqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 type UserValue struct {
@@ -27,3 +39,209 @@ func Text_UPdate(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// fakeDriver 记录执行的语句, failInsert 次 INSERT 返回死锁
+// rowsAffected 为 INSERT 的影响行数(0 时为 1), 查询返回 queryColumns、queryRows
+type fakeDriver struct {
+	failInsert   int
+	rowsAffected int64
+	inserts      []string
+	updates      [][]driver.Value
+	queries      []string
+	queryColumns []string
+	queryRows    [][]driver.Value
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{driver: d}, nil
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	return nil
+}
+
+type fakeStmt struct {
+	driver *fakeDriver
+	query  string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	switch {
+	case strings.HasPrefix(s.query, "INSERT") && s.driver.failInsert > 0:
+		s.driver.failInsert--
+		return nil, &sqldriver.MySQLError{Number: errDeadlock}
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.driver.inserts = append(s.driver.inserts, s.query)
+		if s.driver.rowsAffected > 0 {
+			return fakeResult{rowsAffected: s.driver.rowsAffected}, nil
+		}
+	case strings.HasPrefix(s.query, "UPDATE"):
+		s.driver.updates = append(s.driver.updates, args)
+	}
+
+	return fakeResult{rowsAffected: 1}, nil
+}
+
+type fakeResult struct {
+	rowsAffected int64
+}
+
+func (fakeResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.driver.queries = append(s.driver.queries, s.query)
+	return &fakeRows{columns: s.driver.queryColumns, rows: s.driver.queryRows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+type testVersioned struct {
+	ID      int64 `gorm:"column:id;primaryKey"`
+	Version int64 `gorm:"column:version"`
+}
+
+func (m testVersioned) GetID() interface{} {
+	return m.ID
+}
+
+func (m *testVersioned) SetID(v interface{}) {
+	m.ID = v.(int64)
+}
+
+func (m testVersioned) Table() string {
+	return "test_versioned"
+}
+
+func (m testVersioned) TableName() string {
+	return m.Table()
+}
+
+func (m testVersioned) VersionField() string {
+	return "version"
+}
+
+func (m testVersioned) GetVersion() int64 {
+	return m.Version
+}
+
+func (m *testVersioned) SetVersion(version int64) {
+	m.Version = version
+}
+
+func newFakeResource(t *testing.T, fake *fakeDriver) goresource.IResource {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(fakeConnector{fake}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = registerErrorCallbacks(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return &resource{db: db}
+}
+
+type fakeConnector struct {
+	driver *fakeDriver
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open("")
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func Test_unitOfWork_Replay(t *testing.T) {
+	fake := &fakeDriver{failInsert: 1}
+	res := newFakeResource(t, fake)
+	uow := goresource.UowWithRetry(goresource.RetryPolicy{MaxAttempts: 2})
+	entry := &testVersioned{ID: 1, Version: 1}
+	a := assert.New(t)
+	a.NoError(res.Db(uow).Update(entry))
+	a.NoError(res.Db(uow).Create(&TestPerson{ID: 2}))
+	a.Equal(int64(2), entry.Version)
+
+	a.NoError(uow.Commit())
+	a.Equal(int64(2), entry.Version)
+	a.Len(fake.updates, 2)
+	a.Equal(fake.updates[0], fake.updates[1])
+	a.Contains(fake.updates[1], int64(1))
+	a.Contains(fake.updates[1], int64(2))
+}
+
+func Test_unitOfWork_CreateMany(t *testing.T) {
+	fake := &fakeDriver{}
+	res := newFakeResource(t, fake)
+	uow := res.Uow()
+	a := assert.New(t)
+	a.NoError(goresource.CreateMany(res.Db(uow), []goresource.IDbModel{
+		&TestPerson{ID: 1}, &TestPerson{ID: 2}, &TestPerson{ID: 3},
+	}, 2))
+	a.Equal(2, uow.Pending()[repositorytype.Create])
+	a.Empty(fake.inserts)
+
+	a.NoError(uow.Commit())
+	a.Len(fake.inserts, 2)
+}
@@ -0,0 +1,112 @@
+package mysqlex
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/repositorytype"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Upsert INSERT ... ON DUPLICATE KEY UPDATE, mysql 按表的任一唯一键判断冲突
+// 已存在时按 ConflictFields 读取已存在数据的主键(ConflictFields 为空时 entry 的主键不变)
+// dsn 开启 clientFoundRows 时更新的影响行数为 1, inserted 不可靠
+func (r repository) Upsert(entry goresource.IDbModel, opts goresource.UpsertOptions) (inserted bool, err error) {
+	goresource.AuditCreate(r.db.Statement.Context, entry)
+	if err = goresource.GenerateID(r.db.Statement.Context, entry); err != nil {
+		return
+	}
+	if r.uow != nil {
+		r.enlist(repositorytype.Upsert, []goresource.IDbModel{entry}, opts)
+		return
+	}
+
+	inserted, err = upsertEntry(r.db, entry, opts)
+
+	return
+}
+
+// upsertEntry 影响行数为 1 时为插入(更新为 2, 值未变化为 0)
+func upsertEntry(db *gorm.DB, entry goresource.IDbModel, opts goresource.UpsertOptions) (inserted bool, err error) {
+	updateFields := opts.UpdateFields
+	if len(updateFields) == 0 {
+		if updateFields, err = upsertColumns(db, entry, opts.ConflictFields); err != nil {
+			return
+		}
+	}
+	onConflict := clause.OnConflict{DoUpdates: clause.AssignmentColumns(updateFields)}
+	for _, field := range opts.ConflictFields {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field})
+	}
+
+	result := db.Model(entry).Clauses(onConflict).Create(entry)
+	if err = result.Error; err != nil {
+		return
+	}
+	if inserted = result.RowsAffected == 1; !inserted && len(opts.ConflictFields) > 0 {
+		err = readID(db, entry, opts.ConflictFields)
+	}
+
+	return
+}
+
+// readID 按冲突字段读取已存在数据的主键
+func readID(db *gorm.DB, entry goresource.IDbModel, conflictFields []string) (err error) {
+	stmt := &gorm.Statement{DB: db}
+	if err = stmt.Parse(entry); err != nil {
+		return
+	}
+	pkField := stmt.Schema.PrioritizedPrimaryField
+	if pkField == nil {
+		return
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(entry))
+	conds := make(map[string]interface{}, len(conflictFields))
+	for _, name := range conflictFields {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			return fmt.Errorf("mysqlex: upsert conflict field %s not found", name)
+		}
+		conds[field.DBName], _ = field.ValueOf(db.Statement.Context, rv)
+	}
+	table := db.Statement.Table
+	if table == "" {
+		table = stmt.Table
+	}
+
+	id := reflect.New(pkField.FieldType)
+	if err = db.Session(&gorm.Session{NewDB: true}).Table(table).Select(pkField.DBName).Where(conds).Limit(1).Row().Scan(id.Interface()); err != nil {
+		return mapError(err)
+	}
+	entry.SetID(id.Elem().Interface())
+
+	return
+}
+
+// upsertColumns 已存在时更新的列: 主键、冲突列、创建时间(autoCreateTime)、创建审计字段以外的全部列
+func upsertColumns(db *gorm.DB, entry goresource.IDbModel, conflictFields []string) (columns []string, err error) {
+	stmt := &gorm.Statement{DB: db}
+	if err = stmt.Parse(entry); err != nil {
+		return
+	}
+
+	excluded := make(map[string]bool)
+	for _, name := range goresource.CreateAuditFields(entry) {
+		excluded[name] = true
+	}
+	for _, field := range conflictFields {
+		excluded[field] = true
+	}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || field.PrimaryKey || field.AutoCreateTime > 0 || excluded[field.Name] || excluded[field.DBName] || !field.Creatable || !field.Updatable {
+			continue
+		}
+		columns = append(columns, field.DBName)
+	}
+
+	return
+}
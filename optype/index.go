@@ -0,0 +1,24 @@
+package optype
+
+// Value 仓储操作类型
+type Value string
+
+func (v Value) String() string {
+	return string(v)
+}
+
+const (
+	Create Value = "create"
+	Delete Value = "delete"
+	Update Value = "update"
+	Count  Value = "count"
+	Exec   Value = "exec"
+	Find   Value = "find"
+	First  Value = "first"
+	Cursor Value = "cursor"
+	Upsert Value = "upsert"
+
+	CreateMany Value = "create_many"
+	UpdateMany Value = "update_many"
+	DeleteMany Value = "delete_many"
+)
@@ -0,0 +1,287 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/tools"
+)
+
+// Table 发件箱表(集合)名
+const Table = "outbox"
+
+var (
+	ErrCommitHookRequired = errors.New("outbox: unit of work does not implement goresource.ICommitHook")
+	ErrAggregateIDEmpty   = errors.New("outbox: aggregate id is empty and can not be generated")
+)
+
+// Event 领域事件, Payload 以 json 保存
+type Event struct {
+	Type    string
+	Payload interface{}
+}
+
+// IEventSource 领域事件来源, Create、Delete、Update 时待发布事件在同一工作单元中写入发件箱
+type IEventSource interface {
+	goresource.IDbModel
+	Events() []Event
+	ClearEvents() // 写入发件箱并提交后调用
+}
+
+// Message 发件箱消息, 同一聚合按 Sequence 顺序发布
+type Message struct {
+	ID            string     `postgres:"id" pk:"" bson:"_id" gorm:"column:id;primaryKey"`
+	AggregateType string     `postgres:"aggregate_type" bson:"aggregate_type" gorm:"column:aggregate_type"`
+	AggregateID   string     `postgres:"aggregate_id" bson:"aggregate_id" gorm:"column:aggregate_id"`
+	Sequence      int64      `postgres:"sequence" bson:"sequence" gorm:"column:sequence"`
+	EventType     string     `postgres:"event_type" bson:"event_type" gorm:"column:event_type"`
+	Payload       string     `postgres:"payload" bson:"payload" gorm:"column:payload"`
+	CreatedAt     time.Time  `postgres:"created_at" bson:"created_at" gorm:"column:created_at"`
+	DispatchedAt  *time.Time `postgres:"dispatched_at" bson:"dispatched_at" gorm:"column:dispatched_at"`
+	Attempts      int        `postgres:"attempts" bson:"attempts" gorm:"column:attempts"`
+	LastError     string     `postgres:"last_error" bson:"last_error" gorm:"column:last_error"`
+	ClaimedUntil  *time.Time `postgres:"claimed_until" bson:"claimed_until" gorm:"column:claimed_until"`
+	Version       int64      `postgres:"version" bson:"version" gorm:"column:version"`
+}
+
+func (m Message) GetID() interface{} {
+	return m.ID
+}
+
+func (m *Message) SetID(v interface{}) {
+	if id, ok := v.(string); ok {
+		m.ID = id
+	}
+}
+
+func (m Message) Table() string {
+	return Table
+}
+
+func (m Message) TableName() string {
+	return Table
+}
+
+func (m Message) VersionField() string {
+	return "version"
+}
+
+func (m Message) GetVersion() int64 {
+	return m.Version
+}
+
+func (m *Message) SetVersion(version int64) {
+	m.Version = version
+}
+
+// lastSequence 进程内单调递增的序号
+var lastSequence int64
+
+func nextSequence() int64 {
+	for {
+		last, next := atomic.LoadInt64(&lastSequence), goresource.Now().UnixNano()
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastSequence, last, next) {
+			return next
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newMessages(source IEventSource) (messages []*Message, err error) {
+	now := goresource.Now()
+	for _, event := range source.Events() {
+		payload, marshalErr := json.Marshal(event.Payload)
+		if marshalErr != nil {
+			err = fmt.Errorf("outbox: %s marshal failed: %w", event.Type, marshalErr)
+			return
+		}
+		messages = append(messages, &Message{
+			ID:            newID(),
+			AggregateType: source.Table(),
+			AggregateID:   fmt.Sprint(source.GetID()),
+			Sequence:      nextSequence(),
+			EventType:     event.Type,
+			Payload:       string(payload),
+			CreatedAt:     now,
+		})
+	}
+
+	return
+}
+
+// WithOutbox IEventSource 的待发布事件与模型在同一工作单元中写入发件箱, 无工作单元时使用资源的工作单元
+// 调用方的工作单元须实现 goresource.ICommitHook(提交后清空事件), 聚合主键须在写入前确定(已赋值或绑定了生成器)
+func WithOutbox(resource goresource.IResource) goresource.IResource {
+	return &outboxResource{
+		resource: resource,
+	}
+}
+
+type outboxResource struct {
+	resource goresource.IResource
+}
+
+func (r *outboxResource) Db(args ...interface{}) goresource.IRepository {
+	dbArgs := goresource.ParseDbArgs(args...)
+	return &outboxRepository{
+		IRepository: r.resource.Db(args...),
+		resource:    r.resource,
+		ctx:         dbArgs.Ctx,
+		args:        args,
+		uow:         dbArgs.Uow,
+	}
+}
+
+func (r *outboxResource) Uow() goresource.IUnitOfWork {
+	return r.resource.Uow()
+}
+
+func (r *outboxResource) Unwrap() goresource.IResource {
+	return r.resource
+}
+
+type outboxRepository struct {
+	goresource.IRepository
+
+	resource goresource.IResource
+	ctx      context.Context
+	args     []interface{}
+	uow      goresource.IUnitOfWork
+}
+
+func (r *outboxRepository) Create(entry goresource.IDbModel, args ...interface{}) error {
+	return r.save([]goresource.IDbModel{entry}, func(repository goresource.IRepository) error {
+		return repository.Create(entry, args...)
+	})
+}
+
+func (r *outboxRepository) Delete(entry goresource.IDbModel, args ...interface{}) error {
+	return r.save([]goresource.IDbModel{entry}, func(repository goresource.IRepository) error {
+		return repository.Delete(entry, args...)
+	})
+}
+
+func (r *outboxRepository) Update(entry goresource.IDbModel, args ...interface{}) error {
+	return r.save([]goresource.IDbModel{entry}, func(repository goresource.IRepository) error {
+		return repository.Update(entry, args...)
+	})
+}
+
+func (r *outboxRepository) Upsert(entry goresource.IDbModel, opts goresource.UpsertOptions) (inserted bool, err error) {
+	err = r.save([]goresource.IDbModel{entry}, func(repository goresource.IRepository) (err error) {
+		inserted, err = goresource.Upsert(repository, entry, opts)
+		return
+	})
+
+	return
+}
+
+func (r *outboxRepository) CreateMany(entries []goresource.IDbModel, batchSize int) error {
+	return r.save(entries, func(repository goresource.IRepository) error {
+		return goresource.CreateMany(repository, entries, batchSize)
+	})
+}
+
+func (r *outboxRepository) UpdateMany(entries []goresource.IDbModel, batchSize int) error {
+	return r.save(entries, func(repository goresource.IRepository) error {
+		return goresource.UpdateMany(repository, entries, batchSize)
+	})
+}
+
+func (r *outboxRepository) DeleteMany(entries []goresource.IDbModel, batchSize int) error {
+	return r.save(entries, func(repository goresource.IRepository) error {
+		return goresource.DeleteMany(repository, entries, batchSize)
+	})
+}
+
+// save 执行 op 并写入 entries 的事件, 提交后清空模型事件
+func (r *outboxRepository) save(entries []goresource.IDbModel, op func(goresource.IRepository) error) (err error) {
+	sources := make([]IEventSource, 0)
+	for _, entry := range entries {
+		if source, ok := entry.(IEventSource); ok && len(source.Events()) > 0 {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return op(r.IRepository)
+	}
+
+	var hook goresource.ICommitHook
+	if r.uow != nil {
+		var ok bool
+		if hook, ok = r.uow.(goresource.ICommitHook); !ok {
+			return ErrCommitHookRequired
+		}
+	}
+	// 消息的聚合主键在提交前确定, 不能依赖数据库生成
+	for _, source := range sources {
+		if err = goresource.GenerateID(r.ctx, source); err != nil {
+			return
+		}
+		if tools.IsEmpty(source.GetID()) {
+			return ErrAggregateIDEmpty
+		}
+	}
+
+	repository, uow := r.IRepository, r.uow
+	if uow == nil {
+		uow = r.resource.Uow()
+		repository = r.resource.Db(append(append([]interface{}{}, r.args...), uow)...)
+	}
+	if err = r.write(repository, sources, op); err != nil {
+		if r.uow == nil {
+			uow.Discard()
+		}
+		return
+	}
+	clearEvents := func() {
+		for _, source := range sources {
+			source.ClearEvents()
+		}
+	}
+	if hook != nil {
+		hook.OnCommitted(clearEvents)
+		return
+	}
+	if err = uow.Commit(); err == nil {
+		clearEvents()
+	}
+
+	return
+}
+
+// write 模型写入后生成消息
+func (r *outboxRepository) write(repository goresource.IRepository, sources []IEventSource, op func(goresource.IRepository) error) (err error) {
+	if err = op(repository); err != nil {
+		return
+	}
+
+	var messages []*Message
+	for _, source := range sources {
+		if messages, err = newMessages(source); err != nil {
+			return
+		}
+		for _, message := range messages {
+			if err = repository.Create(message); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,331 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/memoryex"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testOrder struct {
+	ID     int64
+	Status string
+	events []Event
+}
+
+func (t testOrder) GetID() interface{} {
+	return t.ID
+}
+
+func (t *testOrder) SetID(v interface{}) {
+	if vv, ok := v.(int64); ok {
+		t.ID = vv
+	}
+}
+
+func (t testOrder) Table() string {
+	return "test_order"
+}
+
+func (t *testOrder) Events() []Event {
+	return t.events
+}
+
+func (t *testOrder) ClearEvents() {
+	t.events = nil
+}
+
+func (t *testOrder) raise(eventType string) {
+	t.events = append(t.events, Event{Type: eventType, Payload: map[string]interface{}{"status": t.Status}})
+}
+
+// testGeneratedOrder 绑定了主键生成器的模型
+type testGeneratedOrder struct {
+	testOrder
+}
+
+// testPlainUow 未实现 goresource.ICommitHook 的工作单元
+type testPlainUow struct {
+	goresource.IUnitOfWork
+}
+
+func messagesOf(t *testing.T, resource goresource.IResource) (messages []Message) {
+	if err := resource.Db().Query().Asc("sequence").Find(&messages); err != nil {
+		t.Fatal("err", err)
+	}
+
+	return
+}
+
+func Test_WithOutbox(test *testing.T) {
+	test.Run("without uow", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		order := &testOrder{ID: 1, Status: "created"}
+		order.raise("order.created")
+		a := assert.New(t)
+		a.NoError(resource.Db().Create(order))
+		a.Empty(order.Events())
+
+		messages := messagesOf(t, resource)
+		a.Len(messages, 1)
+		a.Equal("test_order", messages[0].AggregateType)
+		a.Equal("1", messages[0].AggregateID)
+		a.Equal("order.created", messages[0].EventType)
+		a.JSONEq(`{"status":"created"}`, messages[0].Payload)
+	})
+
+	test.Run("uow", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		order := &testOrder{ID: 1}
+		order.raise("order.created")
+		uow := goresource.Uow()
+		a := assert.New(t)
+		a.NoError(resource.Db(uow).Create(order))
+		a.Empty(messagesOf(t, resource))
+		a.NotEmpty(order.Events())
+
+		a.NoError(uow.Commit())
+		a.Len(messagesOf(t, resource), 1)
+		a.Empty(order.Events())
+	})
+
+	test.Run("create many", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		first, second, plain := &testOrder{ID: 1}, &testOrder{ID: 2}, &testOrder{ID: 3}
+		first.raise("order.created")
+		second.raise("order.created")
+		uow := goresource.Uow()
+		a := assert.New(t)
+		a.NoError(goresource.CreateMany(resource.Db(uow), []goresource.IDbModel{first, second, plain}, 2))
+		a.Empty(messagesOf(t, resource))
+
+		a.NoError(uow.Commit())
+		messages := messagesOf(t, resource)
+		a.Len(messages, 2)
+		a.Equal("1", messages[0].AggregateID)
+		a.Equal("2", messages[1].AggregateID)
+		a.Empty(first.Events())
+		a.Empty(second.Events())
+		count, _ := resource.Db().Query().Count(&testOrder{})
+		a.Equal(int64(3), count)
+	})
+
+	test.Run("uow discard", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		order := &testOrder{ID: 1}
+		order.raise("order.created")
+		uow := goresource.Uow()
+		a := assert.New(t)
+		a.NoError(resource.Db(uow).Create(order))
+		uow.Discard()
+		a.Empty(messagesOf(t, resource))
+		a.NotEmpty(order.Events())
+	})
+
+	test.Run("uow without commit hook", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		order := &testOrder{ID: 1}
+		order.raise("order.created")
+		uow := &testPlainUow{IUnitOfWork: goresource.Uow()}
+		a := assert.New(t)
+		a.Equal(ErrCommitHookRequired, resource.Db(uow).Create(order))
+		a.NotEmpty(order.Events())
+		a.Empty(uow.Pending())
+	})
+
+	test.Run("aggregate id empty", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		order := &testOrder{}
+		order.raise("order.created")
+		a := assert.New(t)
+		a.Equal(ErrAggregateIDEmpty, resource.Db().Create(order))
+		a.Empty(messagesOf(t, resource))
+		a.NotEmpty(order.Events())
+	})
+
+	test.Run("aggregate id generated", func(t *testing.T) {
+		goresource.RegisterIDGenerator(&testGeneratedOrder{}, goresource.IDGeneratorFunc(func(ctx context.Context, entry goresource.IDbModel) (interface{}, error) {
+			return int64(7), nil
+		}))
+		resource := WithOutbox(memoryex.New())
+		order := &testGeneratedOrder{}
+		order.raise("order.created")
+		a := assert.New(t)
+		a.NoError(resource.Db().Create(order))
+		a.Equal(int64(7), order.ID)
+
+		messages := messagesOf(t, resource)
+		a.Len(messages, 1)
+		a.Equal("7", messages[0].AggregateID)
+	})
+}
+
+func Test_Relay_Dispatch(test *testing.T) {
+	test.Run("order per aggregate", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		a := assert.New(t)
+		first, second := &testOrder{ID: 1}, &testOrder{ID: 2}
+		first.raise("order.created")
+		first.raise("order.paid")
+		second.raise("order.created")
+		a.NoError(resource.Db().Create(first))
+		a.NoError(resource.Db().Create(second))
+
+		published := make([]string, 0)
+		fail := true
+		relay := NewRelay(resource, func(ctx context.Context, message Message) error {
+			if message.AggregateID == "1" && message.EventType == "order.created" && fail {
+				fail = false
+				return errors.New("broker unavailable")
+			}
+			published = append(published, message.AggregateID+":"+message.EventType)
+			return nil
+		})
+
+		count, err := relay.Dispatch(context.Background())
+		a.NoError(err)
+		a.Equal(1, count)
+		a.Equal([]string{"2:order.created"}, published)
+
+		count, err = relay.Dispatch(context.Background())
+		a.NoError(err)
+		a.Equal(2, count)
+		a.Equal([]string{"2:order.created", "1:order.created", "1:order.paid"}, published)
+
+		messages := messagesOf(t, resource)
+		a.Equal(2, messages[0].Attempts)
+		for _, message := range messages {
+			a.NotNil(message.DispatchedAt)
+		}
+
+		count, err = relay.Dispatch(context.Background())
+		a.NoError(err)
+		a.Equal(0, count)
+	})
+
+	test.Run("max attempts", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		order := &testOrder{ID: 1}
+		order.raise("order.created")
+		a := assert.New(t)
+		a.NoError(resource.Db().Create(order))
+
+		calls := 0
+		relay := NewRelay(resource, func(ctx context.Context, message Message) error {
+			calls++
+			return errors.New("broker unavailable")
+		}, RelayConfig{MaxAttempts: 2})
+		for i := 0; i < 3; i++ {
+			_, err := relay.Dispatch(context.Background())
+			a.NoError(err)
+		}
+		a.Equal(2, calls)
+		a.Equal("broker unavailable", messagesOf(t, resource)[0].LastError)
+	})
+	test.Run("claimed by another relay", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		a := assert.New(t)
+		first, second := &testOrder{ID: 1}, &testOrder{ID: 2}
+		first.raise("order.created")
+		first.raise("order.paid")
+		second.raise("order.created")
+		a.NoError(resource.Db().Create(first))
+		a.NoError(resource.Db().Create(second))
+
+		published, other := make([]string, 0), make([]string, 0)
+		otherRelay := NewRelay(resource, func(ctx context.Context, message Message) error {
+			other = append(other, message.AggregateID+":"+message.EventType)
+			return nil
+		})
+		relay := NewRelay(resource, func(ctx context.Context, message Message) error {
+			if len(published) == 0 {
+				_, err := otherRelay.Dispatch(ctx)
+				a.NoError(err)
+			}
+			published = append(published, message.AggregateID+":"+message.EventType)
+			return nil
+		})
+
+		count, err := relay.Dispatch(context.Background())
+		a.NoError(err)
+		a.Equal(2, count)
+		a.Equal([]string{"1:order.created", "1:order.paid"}, published)
+		a.Equal([]string{"2:order.created"}, other)
+		for _, message := range messagesOf(t, resource) {
+			a.NotNil(message.DispatchedAt)
+			a.Equal(1, message.Attempts)
+		}
+	})
+
+	test.Run("claim expired", func(t *testing.T) {
+		now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+		goresource.Now = func() time.Time {
+			return now
+		}
+		defer func() {
+			goresource.Now = time.Now
+		}()
+
+		resource := WithOutbox(memoryex.New())
+		order := &testOrder{ID: 1}
+		order.raise("order.created")
+		a := assert.New(t)
+		a.NoError(resource.Db().Create(order))
+		message := messagesOf(t, resource)[0]
+		claimedUntil := now.Add(time.Minute)
+		message.ClaimedUntil = &claimedUntil
+		a.NoError(resource.Db().Update(&message))
+
+		relay := NewRelay(resource, func(ctx context.Context, message Message) error {
+			return nil
+		})
+		count, err := relay.Dispatch(context.Background())
+		a.NoError(err)
+		a.Equal(0, count)
+
+		now = now.Add(2 * time.Minute)
+		count, err = relay.Dispatch(context.Background())
+		a.NoError(err)
+		a.Equal(1, count)
+		a.Nil(messagesOf(t, resource)[0].ClaimedUntil)
+	})
+
+	test.Run("dead messages exceed batch size", func(t *testing.T) {
+		resource := WithOutbox(memoryex.New())
+		a := assert.New(t)
+		for id := int64(1); id <= 3; id++ {
+			order := &testOrder{ID: id}
+			order.raise("order.created")
+			a.NoError(resource.Db().Create(order))
+		}
+
+		relay := NewRelay(resource, func(ctx context.Context, message Message) error {
+			return errors.New("broker unavailable")
+		}, RelayConfig{BatchSize: 2, MaxAttempts: 1})
+		for i := 0; i < 2; i++ {
+			_, err := relay.Dispatch(context.Background())
+			a.NoError(err)
+		}
+
+		blocked, live := &testOrder{ID: 1}, &testOrder{ID: 4}
+		blocked.raise("order.paid")
+		live.raise("order.created")
+		a.NoError(resource.Db().Update(blocked))
+		a.NoError(resource.Db().Create(live))
+
+		published := make([]string, 0)
+		relay = NewRelay(resource, func(ctx context.Context, message Message) error {
+			published = append(published, message.AggregateID+":"+message.EventType)
+			return nil
+		}, RelayConfig{BatchSize: 2, MaxAttempts: 1})
+		count, err := relay.Dispatch(context.Background())
+		a.NoError(err)
+		a.Equal(1, count)
+		a.Equal([]string{"4:order.created"}, published)
+	})
+}
@@ -0,0 +1,193 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+)
+
+// Publisher 发布消息, 返回错误时重试
+type Publisher func(ctx context.Context, message Message) error
+
+// RelayConfig 转发配置
+type RelayConfig struct {
+	Interval     time.Duration   // 轮询间隔, 默认 1s
+	BatchSize    int             // 每次读取的消息数, 默认 100
+	MaxAttempts  int             // 最大发布次数, 超过后不再发布(同一聚合的后续消息阻塞), 默认 10
+	ClaimTimeout time.Duration   // 认领时长, 过期后其他实例可重新认领(须大于发布耗时), 默认 1min
+	OnError      func(err error) // 读写发件箱失败(下次轮询重试)
+}
+
+// Relay 轮询发件箱并发布未发布的消息, 同一聚合按顺序发布(失败时阻塞后续消息), 至少一次; 可多实例运行
+type Relay struct {
+	resource goresource.IResource
+	publish  Publisher
+	cfg      RelayConfig
+}
+
+// NewRelay 发件箱转发
+func NewRelay(resource goresource.IResource, publish Publisher, configs ...RelayConfig) *Relay {
+	cfg := RelayConfig{}
+	if len(configs) > 0 {
+		cfg = configs[0]
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.ClaimTimeout <= 0 {
+		cfg.ClaimTimeout = time.Minute
+	}
+
+	return &Relay{
+		resource: resource,
+		publish:  publish,
+		cfg:      cfg,
+	}
+}
+
+// Run 按间隔轮询直到 ctx 结束
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Dispatch(ctx); err != nil && ctx.Err() == nil && r.cfg.OnError != nil {
+			r.cfg.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch 发布一批消息, 返回发布成功的数量; 发布失败记录在消息中
+// 发布前以版本条件更新认领消息(多个实例同时转发时只有一个实例发布), 认领过期后其他实例可重新认领
+// 超过最大发布次数的消息不在批次中读取(避免占满批次), 其聚合的后续消息阻塞
+func (r *Relay) Dispatch(ctx context.Context) (count int, err error) {
+	db := r.resource.Db(ctx)
+	now := goresource.Now()
+	messages := make([]Message, 0)
+	err = db.Query().
+		Where(expr.And(
+			expr.IsNull("dispatched_at"),
+			expr.Lt("attempts", r.cfg.MaxAttempts),
+			expr.Or(expr.IsNull("claimed_until"), expr.Lt("claimed_until", now)),
+		)).
+		Asc("sequence").
+		Page(1).
+		PageSize(r.cfg.BatchSize).
+		Find(&messages)
+	if err != nil {
+		return
+	}
+	blocked, err := r.blockedAggregates(db, messages, now)
+	if err != nil {
+		return
+	}
+
+	for index := range messages {
+		message := &messages[index]
+		aggregate := aggregateKey(*message)
+		if blocked[aggregate] {
+			continue
+		}
+
+		// 认领
+		claimedUntil := goresource.Now().Add(r.cfg.ClaimTimeout)
+		message.ClaimedUntil = &claimedUntil
+		var conflict bool
+		if conflict, err = r.save(db, message); err != nil {
+			return
+		}
+		if conflict {
+			blocked[aggregate] = true
+			continue
+		}
+
+		message.Attempts++
+		message.ClaimedUntil = nil
+		if publishErr := r.publish(ctx, *message); publishErr != nil {
+			blocked[aggregate] = true
+			message.LastError = publishErr.Error()
+		} else {
+			dispatchedAt := goresource.Now()
+			message.DispatchedAt = &dispatchedAt
+			message.LastError = ""
+			count++
+		}
+		if conflict, err = r.save(db, message); err != nil {
+			return
+		}
+		if conflict {
+			blocked[aggregate] = true
+		}
+	}
+
+	return
+}
+
+// save 按版本更新消息, 版本冲突(已被其他实例认领或发布)时 conflict 为 true
+func (r *Relay) save(db goresource.IRepository, message *Message) (conflict bool, err error) {
+	err = db.Update(message)
+	var conflictErr *errs.ConcurrencyConflict
+	if errors.As(err, &conflictErr) {
+		return true, nil
+	}
+
+	return
+}
+
+// blockedAggregates 批次中有超过最大发布次数或被其他实例认领(未过期)的未发布消息的聚合
+// 只按批次中去重后的聚合查询, 且只有聚合的首条未发布消息会被发布、认领, 每个聚合最多一条
+func (r *Relay) blockedAggregates(db goresource.IRepository, messages []Message, now time.Time) (res map[string]bool, err error) {
+	res = make(map[string]bool)
+	aggregates := make([]expr.Expr, 0)
+	for _, message := range messages {
+		key := aggregateKey(message)
+		if _, ok := res[key]; ok {
+			continue
+		}
+		res[key] = false
+		aggregates = append(aggregates, expr.And(
+			expr.Eq("aggregate_type", message.AggregateType),
+			expr.Eq("aggregate_id", message.AggregateID),
+		))
+	}
+	if len(aggregates) == 0 {
+		return
+	}
+
+	blocked := make([]Message, 0)
+	err = db.Query().
+		Where(expr.And(
+			expr.IsNull("dispatched_at"),
+			expr.Or(expr.Gte("attempts", r.cfg.MaxAttempts), expr.Gte("claimed_until", now)),
+			expr.Or(aggregates...),
+		)).
+		Find(&blocked)
+	if err != nil {
+		return
+	}
+
+	for _, message := range blocked {
+		res[aggregateKey(message)] = true
+	}
+
+	return
+}
+
+func aggregateKey(message Message) string {
+	return message.AggregateType + ":" + message.AggregateID
+}
@@ -0,0 +1,26 @@
+package goresource
+
+// PageInfo 分页信息, 与数据使用同一筛选条件
+type PageInfo struct {
+	Total    int64 // 总数
+	Page     int   // 页码, 从 1 开始
+	PageSize int   // 每页数量, 0 为不分页
+	HasNext  bool  // 是否有下一页
+}
+
+// NewPageInfo 分页信息, page 小于 1 时为 1
+func NewPageInfo(total int64, page, pageSize int) PageInfo {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 0 {
+		pageSize = 0
+	}
+
+	return PageInfo{
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		HasNext:  pageSize > 0 && int64(page*pageSize) < total,
+	}
+}
@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/postgres/grammar"
+	"github.com/xm-chentl/goresource/postgres/metadata"
+	"github.com/xm-chentl/goresource/tools"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// maxParams 单条语句的参数上限
+const maxParams = 65535
+
+// CreateMany 每批一条多行 INSERT, 失败时逐行插入找出失败项
+func (r *repository) CreateMany(entries []goresource.IDbModel, batchSize int) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	table := metadata.Get(entries[0])
+	if batchSize < 1 {
+		batchSize = goresource.DefaultBatchSize
+	}
+	if columns := len(table.Columns()); columns > 0 && batchSize*columns > maxParams {
+		batchSize = maxParams / columns
+	}
+	for _, entry := range entries {
+		goresource.AuditCreate(r.ctx, entry)
+		if err := goresource.GenerateID(r.ctx, entry); err != nil {
+			return err
+		}
+	}
+
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		batch := entries[start:end]
+		sql, args := grammar.InsertMany(table, batch)
+		if r.uow != nil {
+			r.uow.addQueue(sql, args...)
+			r.enlist()
+			return nil
+		}
+		if err := r.exec(sql, args...); err == nil {
+			return nil
+		}
+
+		bulkErr := &errs.BulkError{}
+		for index, entry := range batch {
+			sql, args := grammar.Insert(table, entry)
+			if err := r.exec(sql, args...); err != nil {
+				bulkErr.Add(index, err)
+			}
+		}
+		return bulkErr.OrNil()
+	})
+}
+
+// UpdateMany 每批在一次往返中执行(pgx.Batch), 批中出错时逐行更新找出失败项
+func (r *repository) UpdateMany(entries []goresource.IDbModel, batchSize int) error {
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		bulkErr := &errs.BulkError{}
+		items := make([]commitQueueInfo, 0, end-start)
+		indexes := make([]int, 0, end-start)
+		for index, entry := range entries[start:end] {
+			goresource.AuditUpdate(r.ctx, entry)
+			sql, args, guard, err := updateSQL(entry)
+			if err != nil {
+				bulkErr.Add(index, err)
+				continue
+			}
+			items = append(items, commitQueueInfo{sql: sql, args: args, guard: guard})
+			indexes = append(indexes, index)
+		}
+		if r.uow != nil {
+			for _, item := range items {
+				r.uow.updateQueue(item.sql, item.guard, item.args...)
+			}
+			r.enlist()
+			return bulkErr.OrNil()
+		}
+
+		rows, batchErr := r.execBatch(items)
+		for i, item := range items {
+			var err error
+			if batchErr != nil {
+				rows[i], err = r.execRows(item.sql, item.args...)
+			}
+			switch {
+			case err != nil:
+				if item.guard != nil {
+					item.guard.Restore()
+				}
+				bulkErr.Add(indexes[i], err)
+			case item.guard != nil && rows[i] == 0:
+				bulkErr.Add(indexes[i], item.guard.Conflict())
+			}
+		}
+		return bulkErr.OrNil()
+	})
+}
+
+// DeleteMany 每批按主键 IN 删除, ISoftDeletable 写入删除时间
+func (r *repository) DeleteMany(entries []goresource.IDbModel, batchSize int) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	table := metadata.Get(entries[0])
+	pkColumn := table.PrimaryKeyColumn()
+	if pkColumn == nil {
+		return errs.DeleteFullNotAllowed
+	}
+	softDeletable, soft := entries[0].(goresource.ISoftDeletable)
+	return goresource.Batches(len(entries), batchSize, func(start, end int) error {
+		bulkErr := &errs.BulkError{}
+		ids, indexes := make([]interface{}, 0, end-start), make([]int, 0, end-start)
+		placeholders := make([]string, 0, end-start)
+		for index, entry := range entries[start:end] {
+			if tools.IsEmpty(entry.GetID()) {
+				bulkErr.Add(index, errs.DeleteFullNotAllowed)
+				continue
+			}
+			ids, indexes = append(ids, entry.GetID()), append(indexes, index)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(ids)))
+		}
+		if len(ids) == 0 {
+			return bulkErr.OrNil()
+		}
+
+		where := fmt.Sprintf("%s IN (%s)", pkColumn.Field(), strings.Join(placeholders, ", "))
+		var sql string
+		var args []interface{}
+		if soft {
+			sql, args = grammar.SoftDelete(table, formatField(softDeletable.DeletedAtField()), goresource.Now(), append([]interface{}{where}, ids...)...)
+		} else {
+			sql, args = grammar.Delete(table, entries[start], append([]interface{}{where}, ids...)...)
+		}
+		if r.uow != nil {
+			r.uow.deleteQueue(sql, args...)
+			r.enlist()
+			return bulkErr.OrNil()
+		}
+		if err := r.exec(sql, args...); err != nil {
+			for _, index := range indexes {
+				bulkErr.Add(index, err)
+			}
+		}
+		return bulkErr.OrNil()
+	})
+}
+
+// execBatch 一次往返执行多条语句(隐式事务, 出错时全部不生效), 返回各语句影响行数
+func (r repository) execBatch(items []commitQueueInfo) (rows []int64, err error) {
+	rows = make([]int64, len(items))
+	if len(items) == 0 {
+		return
+	}
+
+	conn, err := r.pool.getConn()
+	if err != nil {
+		return
+	}
+	defer conn.Release()
+
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		batch.Queue(item.sql, item.args...)
+	}
+	results := conn.SendBatch(r.ctx, batch)
+	defer results.Close()
+	for index := range items {
+		tag, execErr := results.Exec()
+		if execErr != nil {
+			return rows, mapError(execErr)
+		}
+		rows[index] = tag.RowsAffected()
+	}
+
+	return
+}
+
+func (r *repository) enlist() {
+	if r.repositoryBase != nil {
+		r.repositoryBase.SetUow(dbtype.TimeScale, r.uow)
+	}
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/xm-chentl/goresource/errs"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// cursorName 服务端游标名 (每个连接同时只有一个)
+const cursorName = "goresource_cursor"
+
+// cursor batchSize 大于 0 时在事务中使用服务端游标分批读取, 否则按行读取结果流
+type cursor struct {
+	ctx       context.Context
+	conn      *pgxpool.Conn
+	tx        pgx.Tx
+	rows      pgx.Rows
+	batchSize int
+	fetched   int // 当前批次已读取行数
+	err       error
+}
+
+func (c *cursor) Next() bool {
+	if c.err != nil || c.rows == nil {
+		return false
+	}
+	if c.rows.Next() {
+		c.fetched++
+		return true
+	}
+	if c.err = mapError(c.rows.Err()); c.err != nil {
+		return false
+	}
+	// 上一批不足 batchSize 时已读取完
+	if c.tx == nil || c.fetched < c.batchSize {
+		return false
+	}
+	if c.err = c.fetch(); c.err != nil {
+		return false
+	}
+
+	return c.Next()
+}
+
+func (c *cursor) Scan(res interface{}) error {
+	rv := reflect.ValueOf(res)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errs.ResIsNotPtr
+	}
+
+	return scanRow(c.rows, rv.Elem())
+}
+
+func (c *cursor) Err() error {
+	return c.err
+}
+
+func (c *cursor) Close() (err error) {
+	if c.rows != nil {
+		c.rows.Close()
+		c.rows = nil
+	}
+	if c.tx != nil {
+		err = c.tx.Rollback(c.ctx)
+		c.tx = nil
+	}
+	if c.conn != nil {
+		c.conn.Release()
+		c.conn = nil
+	}
+
+	return
+}
+
+func (c *cursor) fetch() (err error) {
+	if c.rows != nil {
+		c.rows.Close()
+	}
+	c.fetched = 0
+	c.rows, err = c.tx.Query(c.ctx, fmt.Sprintf("FETCH FORWARD %d FROM %s", c.batchSize, cursorName))
+	err = mapError(err)
+
+	return
+}
+
+func (c *cursor) open(sql string, args ...interface{}) (err error) {
+	if c.batchSize <= 0 {
+		c.rows, err = c.conn.Query(c.ctx, sql, args...)
+		return mapError(err)
+	}
+
+	if c.tx, err = c.conn.Begin(c.ctx); err != nil {
+		return mapError(err)
+	}
+	if _, err = c.tx.Exec(c.ctx, fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", cursorName, sql), args...); err != nil {
+		return mapError(err)
+	}
+	err = c.fetch()
+
+	return
+}
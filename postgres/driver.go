@@ -0,0 +1,14 @@
+package postgres
+
+import (
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+)
+
+func init() {
+	goresource.Register(dbtype.TimeScale, goresource.DriverFunc(func(cfg goresource.ResourceConfig) (goresource.IResource, error) {
+		return open(cfg.DSN, cfg.Pool)
+	}))
+	goresource.RegisterScheme("postgres", dbtype.TimeScale)
+	goresource.RegisterScheme("postgresql", dbtype.TimeScale)
+}
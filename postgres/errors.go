@@ -1,8 +1,37 @@
 package postgres
 
-import "errors"
+import (
+	"errors"
+	"strings"
+
+	"github.com/xm-chentl/goresource/errs"
+)
 
 var (
 	ErrUpdateSetArgsIsNotArray   = errors.New("args [0] is not []string for update fields")
 	ErrUpdateSetQueryIsNotString = errors.New("args [1] is not string for query condition")
 )
+
+// mapError 按 SQLSTATE 将驱动错误转换为 errs 中的分类
+func mapError(err error) error {
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return errs.Wrap(nil, err)
+	}
+
+	code := pgErr.SQLState()
+	switch {
+	case code == "23505":
+		return errs.Wrap(errs.ErrDuplicateKey, err)
+	case code == "23503":
+		return errs.Wrap(errs.ErrForeignKey, err)
+	case code == "40P01", code == "40001":
+		return errs.Wrap(errs.ErrDeadlock, err)
+	case code == "57014":
+		return errs.Wrap(errs.ErrTimeout, err)
+	case strings.HasPrefix(code, "08"), code == "57P01":
+		return errs.Wrap(errs.ErrConnectionLost, err)
+	}
+
+	return err
+}
@@ -9,8 +9,20 @@ import (
 	"github.com/xm-chentl/goresource/postgres/metadata"
 )
 
+// TotalField SelectWithTotal 的总数列
+const TotalField = "goresource_total"
+
 // Select 生成查询语句 fields 指定查询字段 args 0 where > 1 where-args
 func Select(table metadata.ITable, fields []string, args ...interface{}) (sql string, newArgs []interface{}) {
+	return selectSQL(table, fields, "", args...)
+}
+
+// SelectWithTotal 查询语句, 附加窗口函数统计的总数列 TotalField
+func SelectWithTotal(table metadata.ITable, fields []string, args ...interface{}) (sql string, newArgs []interface{}) {
+	return selectSQL(table, fields, fmt.Sprintf("count(*) OVER() AS %s", TotalField), args...)
+}
+
+func selectSQL(table metadata.ITable, fields []string, extra string, args ...interface{}) (sql string, newArgs []interface{}) {
 	var bf bytes.Buffer
 	bf.WriteString("SELECT ")
 	fieldArray := make([]string, 0)
@@ -27,6 +39,9 @@ func Select(table metadata.ITable, fields []string, args ...interface{}) (sql st
 		}
 	}
 
+	if extra != "" {
+		fieldArray = append(fieldArray, extra)
+	}
 	bf.WriteString(strings.Join(fieldArray, ", "))
 	bf.WriteString(" FROM ")
 	bf.WriteString(table.Name())
@@ -68,6 +83,90 @@ func Insert(table metadata.ITable, entry goresource.IDbModel) (sql string, args
 	return
 }
 
+// InsertMany 生成多行插入语句 (VALUES 多行)
+func InsertMany(table metadata.ITable, entries []goresource.IDbModel) (sql string, args []interface{}) {
+	var bf bytes.Buffer
+	bf.WriteString("INSERT INTO ")
+	bf.WriteString(table.Name())
+	columns := make([]metadata.IColumn, 0)
+	columnArray := make([]string, 0)
+	for _, column := range table.Columns() {
+		if column.AutoIncrement() {
+			continue
+		}
+		columns = append(columns, column)
+		columnArray = append(columnArray, column.Field())
+	}
+	bf.WriteString(" (")
+	bf.WriteString(strings.Join(columnArray, ", "))
+	bf.WriteString(") VALUES ")
+	args = make([]interface{}, 0, len(columns)*len(entries))
+	rowArray := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		varArray := make([]string, 0, len(columns))
+		for _, column := range columns {
+			args = append(args, column.Value(entry))
+			varArray = append(varArray, fmt.Sprintf("$%d", len(args)))
+		}
+		rowArray = append(rowArray, "("+strings.Join(varArray, ", ")+")")
+	}
+	bf.WriteString(strings.Join(rowArray, ", "))
+	bf.WriteString(";")
+	sql = bf.String()
+	return
+}
+
+// Upsert 生成插入或更新语句 (ON CONFLICT), conflictFields 为空时为主键, updateFields 为空时更新冲突列、创建审计列以外的全部列
+// RETURNING 插入时为 true, 更新时为 false, 及主键(已存在时为已存在数据的主键); 冲突且无更新列时冲突列更新为原值
+func Upsert(table metadata.ITable, entry goresource.IDbModel, conflictFields, updateFields []string) (sql string, args []interface{}) {
+	sql, args = Insert(table, entry)
+	conflicts := make([]string, 0, len(conflictFields))
+	for _, field := range conflictFields {
+		conflicts = append(conflicts, metadata.FormatField(field))
+	}
+	pkColumn := table.PrimaryKeyColumn()
+	if len(conflicts) == 0 && pkColumn != nil {
+		conflicts = append(conflicts, pkColumn.Field())
+	}
+
+	created := goresource.CreateAuditFields(entry)
+	sets := make([]string, 0)
+	for _, column := range UpdateColumns(table, updateFields) {
+		if len(updateFields) == 0 && (contains(conflicts, column.Field()) || contains(created, column.Name())) {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s=EXCLUDED.%s", column.Field(), column.Field()))
+	}
+	if len(sets) == 0 && len(conflicts) > 0 {
+		sets = append(sets, fmt.Sprintf("%s=EXCLUDED.%s", conflicts[0], conflicts[0]))
+	}
+
+	var bf bytes.Buffer
+	bf.WriteString(strings.TrimSuffix(sql, ";"))
+	bf.WriteString(" ON CONFLICT (")
+	bf.WriteString(strings.Join(conflicts, ", "))
+	bf.WriteString(") DO UPDATE SET ")
+	bf.WriteString(strings.Join(sets, ", "))
+	bf.WriteString(" RETURNING (xmax = 0)")
+	if pkColumn != nil {
+		bf.WriteString(", ")
+		bf.WriteString(pkColumn.Field())
+	}
+	bf.WriteString(";")
+	sql = bf.String()
+	return
+}
+
+func contains(array []string, value string) bool {
+	for _, v := range array {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Update 生成更新语句 args 0 where > 1 where-args
 func Update(table metadata.ITable, entry goresource.IDbModel, fields []string, args ...interface{}) (sql string, newArgs []interface{}) {
 	var bf bytes.Buffer
@@ -75,24 +174,7 @@ func Update(table metadata.ITable, entry goresource.IDbModel, fields []string, a
 	bf.WriteString(table.Name())
 	newArgs = make([]interface{}, 0)
 	updateFields := make([]string, 0)
-	columns := make([]metadata.IColumn, 0)
-	if len(fields) == 0 {
-		for index, column := range table.Columns() {
-			if column.AutoIncrement() || column.PrimaryKey() {
-				continue
-			}
-			columns = append(columns, table.Columns()[index])
-		}
-	} else {
-		for _, field := range fields {
-			column, ok := table.ColumnMap()[fmt.Sprintf(`"%s"`, field)]
-			// 过滤 不存在，自增的
-			if !ok || column.AutoIncrement() || column.PrimaryKey() {
-				continue
-			}
-			columns = append(columns, column)
-		}
-	}
+	columns := UpdateColumns(table, fields)
 	for index := range columns {
 		c := columns[index]
 		updateFields = append(updateFields, fmt.Sprintf(`%s=$%d`, c.Field(), index+1))
@@ -113,6 +195,32 @@ func Update(table metadata.ITable, entry goresource.IDbModel, fields []string, a
 	return
 }
 
+// UpdateColumns 更新的列 (fields 为空时除主键、自增外的全部列)
+func UpdateColumns(table metadata.ITable, fields []string) (columns []metadata.IColumn) {
+	columns = make([]metadata.IColumn, 0)
+	if len(fields) == 0 {
+		for index, column := range table.Columns() {
+			if column.AutoIncrement() || column.PrimaryKey() {
+				continue
+			}
+			columns = append(columns, table.Columns()[index])
+		}
+		return
+	}
+
+	columnMap := table.ColumnMap()
+	for _, field := range fields {
+		column, ok := columnMap[fmt.Sprintf(`"%s"`, field)]
+		// 过滤 不存在，自增的
+		if !ok || column.AutoIncrement() || column.PrimaryKey() {
+			continue
+		}
+		columns = append(columns, column)
+	}
+
+	return
+}
+
 // Delete 生成删除语句 args 0 where > 1 where-args
 func Delete(table metadata.ITable, entry goresource.IDbModel, args ...interface{}) (sql string, newArgs []interface{}) {
 	var bf bytes.Buffer
@@ -130,6 +238,26 @@ func Delete(table metadata.ITable, entry goresource.IDbModel, args ...interface{
 	return
 }
 
+// SoftDelete 生成软删除语句 field 删除时间列 args 0 where > 1 where-args
+func SoftDelete(table metadata.ITable, field string, deletedAt interface{}, args ...interface{}) (sql string, newArgs []interface{}) {
+	var bf bytes.Buffer
+	bf.WriteString("UPDATE ")
+	bf.WriteString(table.Name())
+	newArgs = make([]interface{}, 0)
+	var where string
+	if len(args) > 0 {
+		where, newArgs = Where(args...)
+		newArgs = append(make([]interface{}, 0, len(newArgs)+1), newArgs...)
+	}
+	bf.WriteString(fmt.Sprintf(" SET %s=$%d", field, len(newArgs)+1))
+	bf.WriteString(where)
+	bf.WriteString(";")
+	newArgs = append(newArgs, deletedAt)
+	sql = bf.String()
+
+	return
+}
+
 // Count 生成count语句 args 0 where > 1 where-args
 func Count(table metadata.ITable, args ...interface{}) (sql string, newArgs []interface{}) {
 	var bf bytes.Buffer
@@ -0,0 +1,80 @@
+package grammar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/postgres/metadata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUser struct {
+	ID   int64  `postgres:"id" pk:""`
+	Name string `postgres:"name"`
+}
+
+func (t testUser) GetID() interface{} {
+	return t.ID
+}
+
+func (t *testUser) SetID(v interface{}) {
+	if vv, ok := v.(int64); ok {
+		t.ID = vv
+	}
+}
+
+func (t testUser) Table() string {
+	return "test_user"
+}
+
+type testAuditUser struct {
+	testUser
+	CreatedAt time.Time `postgres:"created_at"`
+	UpdatedAt time.Time `postgres:"updated_at"`
+}
+
+func (t testAuditUser) Table() string {
+	return "test_audit_user"
+}
+
+func (t *testAuditUser) SetCreated(by string, at time.Time) {
+	t.CreatedAt = at
+}
+
+func (t testAuditUser) CreatedFields() []string {
+	return []string{"created_at"}
+}
+
+func Test_InsertMany(t *testing.T) {
+	sql, args := InsertMany(metadata.Get(&testUser{}), []goresource.IDbModel{
+		&testUser{ID: 1, Name: "a"},
+		&testUser{ID: 2, Name: "b"},
+	})
+	a := assert.New(t)
+	a.Equal(`INSERT INTO test_user ("id", "name") VALUES ($1, $2), ($3, $4);`, sql)
+	a.Equal([]interface{}{int64(1), "a", int64(2), "b"}, args)
+}
+
+func Test_Upsert(t *testing.T) {
+	table := metadata.Get(&testUser{})
+	entry := &testUser{ID: 1, Name: "a"}
+	t.Run("主键冲突", func(t *testing.T) {
+		sql, args := Upsert(table, entry, nil, nil)
+		a := assert.New(t)
+		a.Equal(`INSERT INTO test_user ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name" RETURNING (xmax = 0), "id";`, sql)
+		a.Equal([]interface{}{int64(1), "a"}, args)
+	})
+
+	t.Run("无更新列", func(t *testing.T) {
+		sql, _ := Upsert(table, entry, []string{"name"}, nil)
+		assert.Equal(t, `INSERT INTO test_user ("id", "name") VALUES ($1, $2) ON CONFLICT ("name") DO UPDATE SET "name"=EXCLUDED."name" RETURNING (xmax = 0), "id";`, sql)
+	})
+
+	t.Run("创建审计列", func(t *testing.T) {
+		audited := &testAuditUser{testUser: testUser{ID: 1, Name: "a"}}
+		sql, _ := Upsert(metadata.Get(audited), audited, []string{"name"}, nil)
+		assert.Equal(t, `INSERT INTO test_audit_user ("id", "name", "created_at", "updated_at") VALUES ($1, $2, $3, $4) ON CONFLICT ("name") DO UPDATE SET "updated_at"=EXCLUDED."updated_at" RETURNING (xmax = 0), "id";`, sql)
+	})
+}
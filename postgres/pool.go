@@ -14,6 +14,7 @@ type pool struct {
 func (p *pool) getConn() (conn *pgxpool.Conn, err error) {
 	conn, err = p.pgxPool.Acquire(p.ctx)
 	if err != nil {
+		err = mapError(err)
 		return
 	}
 
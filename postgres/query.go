@@ -8,30 +8,63 @@ import (
 	"strings"
 
 	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/deletedscope"
 	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/keyset"
 	"github.com/xm-chentl/goresource/postgres/grammar"
 	"github.com/xm-chentl/goresource/postgres/metadata"
 
 	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
 )
 
+// dialect expr 编译方言
+var dialect = expr.Dialect{
+	Field: formatField,
+	Placeholder: func(index int) string {
+		return fmt.Sprintf("$%d", index)
+	},
+}
+
+// formatField 已包含 " 时不处理
+func formatField(field string) string {
+	if strings.Contains(field, `"`) {
+		return field
+	}
+
+	return metadata.FormatField(field)
+}
+
 type query struct {
 	ctx       context.Context
 	pool      *pool
 	fields    []string
 	where     string
 	whereArgs []interface{}
+	whereExpr expr.Expr
 	page      int
 	pageSize  int
 	orders    []string
 	orderBys  []string
 	opts      []interface{}
+	deleted   deletedscope.Value
+	batchSize int
+	keyset    *keysetArgs
+}
+
+// keysetArgs keyset 分页参数, Find 为值接收者, 翻页 token 通过指针写回
+type keysetArgs struct {
+	token      string
+	before     bool
+	next, prev string
 }
 
 func (q *query) Count(entry goresource.IDbModel) (res int64, err error) {
 	defer q.reset()
 
-	sql, args := grammar.Count(metadata.Get(entry), q.getArgs()...)
+	table := metadata.Get(entry)
+	sql, args := grammar.Count(table, q.getArgs(table, entry)...)
 	conn, err := q.pool.getConn()
 	if err != nil {
 		return
@@ -39,7 +72,7 @@ func (q *query) Count(entry goresource.IDbModel) (res int64, err error) {
 	defer conn.Release()
 
 	row := conn.QueryRow(q.ctx, sql, args...)
-	err = row.Scan(&res)
+	err = mapError(row.Scan(&res))
 
 	return
 }
@@ -86,6 +119,14 @@ func (q *query) Fields(fields ...interface{}) goresource.IQuery {
 }
 
 func (q query) First(res interface{}) (err error) {
+	if err = q.MustFirst(res); errors.Is(err, errs.ErrNotFound) {
+		err = nil
+	}
+
+	return
+}
+
+func (q query) MustFirst(res interface{}) (err error) {
 	resRt := reflect.TypeOf(res)
 	if resRt.Kind() != reflect.Ptr {
 		err = errs.ResIsNotPtr
@@ -105,9 +146,11 @@ func (q query) First(res interface{}) (err error) {
 	if err := q.queryData(resRt, resRvSlice); err != nil {
 		return err
 	}
-	if resRvSlice.Elem().Len() > 0 {
-		resRv.Elem().Set(resRvSlice.Elem().Index(0))
+	if resRvSlice.Elem().Len() == 0 {
+		err = errs.ErrNotFound
+		return
 	}
+	resRv.Elem().Set(resRvSlice.Elem().Index(0))
 
 	return
 }
@@ -133,13 +176,63 @@ func (q query) Find(res interface{}) (err error) {
 	return
 }
 
+// FindPage 窗口函数同时查询总数, 页码超出范围(无数据)时按同一条件统计总数
+func (q *query) FindPage(res interface{}) (info goresource.PageInfo, err error) {
+	defer q.reset()
+
+	resRt := reflect.TypeOf(res)
+	if resRt.Kind() != reflect.Ptr {
+		err = errs.ResIsNotPtr
+		return
+	}
+	if resRt.Elem().Kind() != reflect.Slice {
+		err = errs.ResIsNotSlice
+		return
+	}
+
+	rt := resRt.Elem().Elem()
+	model := reflect.New(rt).Interface().(goresource.IDbModel)
+	table := metadata.Get(model)
+	whereArgs := q.getArgs(table, model)
+	sql, args := grammar.SelectWithTotal(table, q.fields, whereArgs...)
+	sql += q.orderSQL()
+	total, err := q.scanTotal(rt, reflect.ValueOf(res), sql, args...)
+	if err != nil {
+		return
+	}
+	if total == 0 && q.page > 1 {
+		countSQL, countArgs := grammar.Count(table, whereArgs...)
+		conn, connErr := q.pool.getConn()
+		if connErr != nil {
+			err = connErr
+			return
+		}
+		defer conn.Release()
+		if err = conn.QueryRow(q.ctx, countSQL, countArgs...).Scan(&total); err != nil {
+			err = mapError(err)
+			return
+		}
+	}
+	info = goresource.NewPageInfo(total, q.page, q.pageSize)
+
+	return
+}
+
 func (q query) ToArray(res interface{}) error {
 	return q.Find(res)
 }
 
+// Where args 0 where-sql > 1 where-args 或 args 0 expr.Expr
 func (q *query) Where(args ...interface{}) goresource.IQuery {
 	if len(args) > 0 {
+		if e, ok := args[0].(expr.Expr); ok {
+			q.whereExpr = e
+			q.where = ""
+			q.whereArgs = []interface{}{}
+			return q
+		}
 		q.where = args[0].(string)
+		q.whereExpr = nil
 	}
 	if len(args) > 1 {
 		q.whereArgs = args[1:]
@@ -192,12 +285,107 @@ func (q *query) SetOpts(opts ...interface{}) goresource.IQuery {
 	return q
 }
 
-func (q query) getArgs() (args []interface{}) {
+func (q *query) WithDeleted() goresource.IQuery {
+	q.deleted = deletedscope.Include
+	return q
+}
+
+func (q *query) OnlyDeleted() goresource.IQuery {
+	q.deleted = deletedscope.Only
+	return q
+}
+
+func (q *query) BatchSize(size int) goresource.IQuery {
+	q.batchSize = size
+	return q
+}
+
+func (q *query) After(token string) goresource.IQuery {
+	q.keyset = &keysetArgs{token: token}
+	return q
+}
+
+func (q *query) Before(token string) goresource.IQuery {
+	q.keyset = &keysetArgs{token: token, before: true}
+	return q
+}
+
+func (q *query) PageToken() (next, prev string) {
+	if q.keyset != nil {
+		next, prev = q.keyset.next, q.keyset.prev
+	}
+
+	return
+}
+
+// Cursor BatchSize 大于 0 时使用服务端游标(事务中)分批读取
+func (q *query) Cursor(entry goresource.IDbModel) (res goresource.ICursor, err error) {
+	defer q.reset()
+
+	rt := reflect.TypeOf(entry)
+	if rt.Kind() != reflect.Ptr || rt.Elem().Kind() != reflect.Struct {
+		err = errs.ResIsNotPtr
+		return
+	}
+
+	sql, args, _, err := q.selectSQL(rt.Elem())
+	if err != nil {
+		return
+	}
+	conn, err := q.pool.getConn()
+	if err != nil {
+		return
+	}
+	c := &cursor{
+		ctx:       q.ctx,
+		conn:      conn,
+		batchSize: q.batchSize,
+	}
+	if err = c.open(sql, args...); err != nil {
+		_ = c.Close()
+		return
+	}
+	res = c
+
+	return
+}
+
+// getArgs expr.PK 映射为表主键, model 为 ISoftDeletable 时按范围筛选删除时间
+func (q query) getArgs(table metadata.ITable, model interface{}) (args []interface{}) {
 	args = make([]interface{}, 0)
+	deleted := ""
+	if softDeletable, ok := model.(goresource.ISoftDeletable); ok {
+		switch q.deleted {
+		case deletedscope.Exclude:
+			deleted = formatField(softDeletable.DeletedAtField()) + " IS NULL"
+		case deletedscope.Only:
+			deleted = formatField(softDeletable.DeletedAtField()) + " IS NOT NULL"
+		}
+	}
+	if q.whereExpr != nil {
+		e := q.whereExpr
+		if pk := table.PrimaryKeyColumn(); pk != nil {
+			e = expr.ReplaceField(e, expr.PK, pk.Field())
+		}
+		where, whereArgs := expr.ToSQL(e, dialect, 0)
+		if deleted != "" {
+			where = fmt.Sprintf("(%s) AND %s", where, deleted)
+		}
+		args = append(args, where)
+		args = append(args, whereArgs...)
+		return
+	}
 	if strings.TrimSpace(q.where) == "" {
+		if deleted != "" {
+			args = append(args, deleted)
+		}
 		return
 	}
-	args = append(args, q.where)
+	if deleted != "" {
+		args = append(args, fmt.Sprintf("(%s) AND %s", q.where, deleted))
+	} else {
+		args = append(args, q.where)
+	}
 	args = append(args, q.whereArgs...)
 
 	return
@@ -206,13 +394,81 @@ func (q query) getArgs() (args []interface{}) {
 func (q *query) queryData(rt reflect.Type, resultsOfRv reflect.Value) (err error) {
 	defer q.reset()
 
-	sql, args := grammar.Select(
-		metadata.Get(
-			reflect.New(rt).Interface().(goresource.IDbModel),
-		),
-		q.fields,
-		q.getArgs()...,
-	)
+	sql, args, page, err := q.selectSQL(rt)
+	if err != nil {
+		return
+	}
+	if err = q.scan(rt, resultsOfRv, sql, args...); err != nil || page == nil {
+		return
+	}
+	q.keyset.next, q.keyset.prev, err = page.Result(resultsOfRv.Interface(), q.pageSize)
+
+	return
+}
+
+// selectSQL 查询语句 (条件、排序、分页), 设置 keyset 时按 token 筛选、排序
+func (q *query) selectSQL(rt reflect.Type) (sql string, args []interface{}, page *keyset.Page, err error) {
+	model := reflect.New(rt).Interface().(goresource.IDbModel)
+	table := metadata.Get(model)
+	if q.keyset == nil {
+		sql, args = grammar.Select(table, q.fields, q.getArgs(table, model)...)
+		sql += q.orderSQL()
+		return
+	}
+
+	keys := make([]keyset.Key, 0, len(q.orders)+len(q.orderBys))
+	for _, field := range q.orders {
+		keys = append(keys, keyset.Key{Field: strings.Trim(field, `"`)})
+	}
+	for _, field := range q.orderBys {
+		keys = append(keys, keyset.Key{Field: strings.Trim(field, `"`), Desc: true})
+	}
+	if page, err = keyset.New(keys, q.keyset.token, q.keyset.before, model); err != nil {
+		return
+	}
+
+	pk := table.PrimaryKeyColumn()
+	args = q.getArgs(table, model)
+	if cond := page.Cond(); cond != nil {
+		if pk != nil {
+			cond = expr.ReplaceField(cond, expr.PK, pk.Field())
+		}
+		offset := 0
+		if len(args) > 0 {
+			offset = len(args) - 1
+		}
+		where, whereArgs := expr.ToSQL(cond, dialect, offset)
+		if len(args) == 0 {
+			args = append(args, where)
+		} else {
+			args[0] = fmt.Sprintf("(%s) AND (%s)", args[0], where)
+		}
+		args = append(args, whereArgs...)
+	}
+	sql, args = grammar.Select(table, q.fields, args...)
+
+	orders := make([]string, 0, len(page.Keys))
+	for _, key := range page.Orders() {
+		field := formatField(key.Field)
+		if key.Field == expr.PK && pk != nil {
+			field = pk.Field()
+		}
+		if key.Desc {
+			orders = append(orders, field+" DESC")
+		} else {
+			orders = append(orders, field+" ASC")
+		}
+	}
+	sql += " ORDER BY " + strings.Join(orders, ", ")
+	if limit := keyset.Limit(q.pageSize); limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	return
+}
+
+// orderSQL 排序、分页
+func (q *query) orderSQL() (sql string) {
 	// Todo: 后续封装至grammar
 	if len(q.orders) > 0 {
 		sql += fmt.Sprintf(" ORDER BY %s ASC", strings.Join(q.orders, ", "))
@@ -235,12 +491,16 @@ func (q *query) queryData(rt reflect.Type, resultsOfRv reflect.Value) (err error
 		sql += fmt.Sprintf(" LIMIT %d OFFSET %d", q.pageSize, ((q.page - 1) * q.pageSize))
 	}
 
-	err = q.scan(rt, resultsOfRv, sql, args...)
-
 	return
 }
 
 func (q query) scan(rt reflect.Type, resultsOfRv reflect.Value, sql string, args ...interface{}) (err error) {
+	_, err = q.scanTotal(rt, resultsOfRv, sql, args...)
+	return
+}
+
+// scanTotal 同 scan, 结果包含 grammar.TotalField 列时返回其值
+func (q query) scanTotal(rt reflect.Type, resultsOfRv reflect.Value, sql string, args ...interface{}) (total int64, err error) {
 	conn, err := q.pool.getConn()
 	if err != nil {
 		return
@@ -248,55 +508,91 @@ func (q query) scan(rt reflect.Type, resultsOfRv reflect.Value, sql string, args
 	defer conn.Release()
 	rows, err := conn.Query(q.ctx, sql, args...)
 	if err != nil {
+		err = mapError(err)
 		return
 	}
 	defer rows.Close()
 
 	results := reflect.MakeSlice(reflect.SliceOf(rt), 0, 0)
-	rv := reflect.New(rt).Elem()
-	bindFieldMap := make(map[string]interface{})
-	nestedBindStructByMap(rt, rv, bindFieldMap)
-	fieldDescArray := rows.FieldDescriptions()
-	var resArray []interface{}
 	for rows.Next() {
-		resArray, err = rows.Values()
-		if err != nil {
+		rv := reflect.New(rt).Elem()
+		if err = scanRow(rows, rv); err != nil {
 			return
 		}
-		for index := range fieldDescArray {
-			fieldDes := fieldDescArray[index]
-			key := strings.ToLower(string(fieldDes.Name))
-			_, ok := bindFieldMap[key]
-			if !ok {
-				continue
+		if total == 0 {
+			total = totalOf(rows)
+		}
+		results = reflect.Append(results, rv)
+	}
+	if err = rows.Err(); err != nil {
+		err = mapError(err)
+		return
+	}
+	resultsOfRv.Elem().Set(results)
+
+	return
+}
+
+// totalOf 当前行的 grammar.TotalField 列
+func totalOf(rows pgx.Rows) int64 {
+	for index, fieldDesc := range rows.FieldDescriptions() {
+		if string(fieldDesc.Name) != grammar.TotalField {
+			continue
+		}
+		if values, err := rows.Values(); err == nil {
+			if total, ok := values[index].(int64); ok {
+				return total
 			}
+		}
+	}
 
-			bResRv := reflect.ValueOf(bindFieldMap[key])
-			resRv := reflect.ValueOf(resArray[index])
-			if resRv.IsValid() {
-				resRvValue := reflect.New(resRv.Type())
-				resRvValue.Elem().Set(resRv)
-				if pgValue, ok := resRvValue.Interface().(pgtype.Value); ok {
-					pgValue.AssignTo(bResRv.Interface())
-				} else {
-					bResRv.Elem().Set(resRv)
-				}
+	return 0
+}
+
+// scanRow 当前行按列名写入 rv (结构体)
+func scanRow(rows pgx.Rows, rv reflect.Value) (err error) {
+	bindFieldMap := make(map[string]interface{})
+	nestedBindStructByMap(rv.Type(), rv, bindFieldMap)
+	fieldDescArray := rows.FieldDescriptions()
+	resArray, err := rows.Values()
+	if err != nil {
+		return
+	}
+	for index := range fieldDescArray {
+		fieldDes := fieldDescArray[index]
+		key := strings.ToLower(string(fieldDes.Name))
+		_, ok := bindFieldMap[key]
+		if !ok {
+			continue
+		}
+
+		bResRv := reflect.ValueOf(bindFieldMap[key])
+		resRv := reflect.ValueOf(resArray[index])
+		if resRv.IsValid() {
+			resRvValue := reflect.New(resRv.Type())
+			resRvValue.Elem().Set(resRv)
+			if pgValue, ok := resRvValue.Interface().(pgtype.Value); ok {
+				pgValue.AssignTo(bResRv.Interface())
+			} else {
+				bResRv.Elem().Set(resRv)
 			}
 		}
-		results = reflect.Append(results, rv)
 	}
-	resultsOfRv.Elem().Set(results)
 
 	return
 }
 
 func (q *query) reset() {
 	q.where = ""
+	q.whereExpr = nil
 	q.whereArgs = []interface{}{}
 	q.page = 0
 	q.pageSize = 0
 	q.orderBys = make([]string, 0)
 	q.orders = make([]string, 0)
+	q.deleted = deletedscope.Exclude
+	q.batchSize = 0
+	q.keyset = nil
 }
 
 func (q query) exec(res interface{}, args ...interface{}) (err error) {
@@ -331,7 +627,7 @@ func (q query) exec(res interface{}, args ...interface{}) (err error) {
 
 	rows, err := conn.Query(q.ctx, sql, args...)
 	if err != nil {
-		return err
+		return mapError(err)
 	}
 	defer rows.Close()
 
@@ -2,9 +2,13 @@ package postgres
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/xm-chentl/goresource/deletedscope"
+	"github.com/xm-chentl/goresource/expr"
+	"github.com/xm-chentl/goresource/keyset"
 	"github.com/xm-chentl/goresource/postgres/grammar"
 	"github.com/xm-chentl/goresource/postgres/metadata"
 
@@ -656,3 +660,67 @@ func mockFunc(
 		cbFunc(t)
 	}
 }
+
+func Test_query_getArgs(test *testing.T) {
+	test.Run("pk", func(t *testing.T) {
+		q := &query{
+			whereExpr: expr.And(expr.ByID(1), expr.Eq("name", "ctl")),
+		}
+		args := q.getArgs(metadata.Get(&testPerson{}), &testPerson{})
+		a := assert.New(t)
+		a.Equal([]interface{}{`("id" = $1 AND "name" = $2)`, 1, "ctl"}, args)
+	})
+
+	test.Run("soft delete", func(t *testing.T) {
+		a := assert.New(t)
+		table := metadata.Get(&testPerson{})
+		q := &query{}
+		a.Equal([]interface{}{`"deleted_at" IS NULL`}, q.getArgs(table, &testSoftPerson{}))
+
+		q = &query{where: "name = $1", whereArgs: []interface{}{"ctl"}, deleted: deletedscope.Only}
+		a.Equal([]interface{}{`(name = $1) AND "deleted_at" IS NOT NULL`, "ctl"}, q.getArgs(table, &testSoftPerson{}))
+
+		q = &query{whereExpr: expr.Eq("name", "ctl"), deleted: deletedscope.Include}
+		a.Equal([]interface{}{`"name" = $1`, "ctl"}, q.getArgs(table, &testSoftPerson{}))
+	})
+}
+
+type testSoftPerson struct {
+	testPerson
+}
+
+func (testSoftPerson) DeletedAtField() string {
+	return "deleted_at"
+}
+
+func Test_query_selectSQL(test *testing.T) {
+	test.Run("keyset", func(t *testing.T) {
+		keyset.SetSecret([]byte("test"))
+		a := assert.New(t)
+		q := &query{whereExpr: expr.Eq("name", "ctl")}
+		q.Desc("age").PageSize(2).After("")
+		sql, args, page, err := q.selectSQL(reflect.TypeOf(testPerson{}))
+		a.NoError(err)
+		a.Contains(sql, `WHERE "name" = $1 ORDER BY "age" DESC, "id" ASC LIMIT 3`)
+		a.Equal([]interface{}{"ctl"}, args)
+
+		rows := []testPerson{{ID: 1, Age: 30}, {ID: 2, Age: 20}, {ID: 3, Age: 20}}
+		next, prev, err := page.Result(&rows, 2)
+		a.NoError(err)
+		a.Len(rows, 2)
+		a.Empty(prev)
+
+		q = &query{whereExpr: expr.Eq("name", "ctl")}
+		q.Desc("age").PageSize(2).After(next)
+		sql, args, _, err = q.selectSQL(reflect.TypeOf(testPerson{}))
+		a.NoError(err)
+		a.Contains(sql, `WHERE ("name" = $1) AND (("age" < $2 OR ("age" = $3 AND "id" > $4))) ORDER BY "age" DESC, "id" ASC LIMIT 3`)
+		a.Equal([]interface{}{"ctl", int16(20), int16(20), int64(2)}, args)
+	})
+	test.Run("total", func(t *testing.T) {
+		sql, args := grammar.SelectWithTotal(metadata.Get(&testPerson{}), nil, `"age" = $1`, 11)
+		a := assert.New(t)
+		a.Equal(`SELECT "id", "name", "age", count(*) OVER() AS goresource_total FROM test_person WHERE "age" = $1`, sql)
+		a.Equal([]interface{}{11}, args)
+	})
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	"github.com/xm-chentl/goresource"
 	"github.com/xm-chentl/goresource/dbtype"
@@ -10,6 +11,8 @@ import (
 	"github.com/xm-chentl/goresource/postgres/grammar"
 	"github.com/xm-chentl/goresource/postgres/metadata"
 	"github.com/xm-chentl/goresource/tools"
+
+	"github.com/jackc/pgx/v4"
 )
 
 type repository struct {
@@ -20,6 +23,10 @@ type repository struct {
 }
 
 func (r *repository) Create(entry goresource.IDbModel, args ...interface{}) (err error) {
+	goresource.AuditCreate(r.ctx, entry)
+	if err = goresource.GenerateID(r.ctx, entry); err != nil {
+		return
+	}
 	sql, args := grammar.Insert(metadata.Get(entry), entry)
 	if r.uow != nil {
 		r.uow.addQueue(sql, args...)
@@ -34,7 +41,7 @@ func (r *repository) Create(entry goresource.IDbModel, args ...interface{}) (err
 	return
 }
 
-// args 0 filter
+// Delete args 0 filter, ISoftDeletable 写入删除时间
 func (r repository) Delete(entry goresource.IDbModel, args ...interface{}) (err error) {
 	newArgs := make([]interface{}, 0)
 	newArgs = append(newArgs, args...)
@@ -51,7 +58,12 @@ func (r repository) Delete(entry goresource.IDbModel, args ...interface{}) (err
 		return
 	}
 
-	sql, args := grammar.Delete(metadata.Get(entry), entry, args...)
+	var sql string
+	if softDeletable, ok := entry.(goresource.ISoftDeletable); ok {
+		sql, args = grammar.SoftDelete(table, formatField(softDeletable.DeletedAtField()), goresource.Now(), newArgs...)
+	} else {
+		sql, args = grammar.Delete(table, entry, newArgs...)
+	}
 	if r.uow != nil {
 		r.uow.deleteQueue(sql, args...)
 		if r.repositoryBase != nil {
@@ -66,6 +78,76 @@ func (r repository) Delete(entry goresource.IDbModel, args ...interface{}) (err
 
 // args 0 update-fields 1 filter (0 where-sql 1 where-args)
 func (r repository) Update(entry goresource.IDbModel, args ...interface{}) (err error) {
+	goresource.AuditUpdate(r.ctx, entry)
+	sql, args, guard, err := updateSQL(entry, args...)
+	if err != nil {
+		return
+	}
+	if r.uow != nil {
+		r.uow.updateQueue(sql, guard, args...)
+		if r.repositoryBase != nil {
+			r.repositoryBase.SetUow(dbtype.TimeScale, r.uow)
+		}
+		return
+	}
+	rows, err := r.execRows(sql, args...)
+	switch {
+	case err != nil && guard != nil:
+		guard.Restore()
+	case err == nil && guard != nil && rows == 0:
+		err = guard.Conflict()
+	}
+
+	return
+}
+
+// Upsert INSERT ... ON CONFLICT, 已存在时 entry 的主键为已存在数据的主键
+func (r repository) Upsert(entry goresource.IDbModel, opts goresource.UpsertOptions) (inserted bool, err error) {
+	goresource.AuditCreate(r.ctx, entry)
+	if err = goresource.GenerateID(r.ctx, entry); err != nil {
+		return
+	}
+	sql, args := grammar.Upsert(metadata.Get(entry), entry, opts.ConflictFields, opts.UpdateFields)
+	if r.uow != nil {
+		r.uow.upsertQueue(sql, entry, args...)
+		if r.repositoryBase != nil {
+			r.repositoryBase.SetUow(dbtype.TimeScale, r.uow)
+		}
+		return
+	}
+
+	conn, err := r.pool.getConn()
+	if err != nil {
+		return
+	}
+	defer conn.Release()
+
+	inserted, err = scanUpsert(conn.Conn().QueryRow(r.ctx, sql, args...), entry)
+	err = mapError(err)
+
+	return
+}
+
+// scanUpsert 读取 RETURNING 的插入标识及主键
+func scanUpsert(row pgx.Row, entry goresource.IDbModel) (inserted bool, err error) {
+	dest := []interface{}{&inserted}
+	var id reflect.Value
+	if pkColumn := metadata.Get(entry).PrimaryKeyColumn(); pkColumn != nil {
+		id = reflect.New(pkColumn.Type())
+		dest = append(dest, id.Interface())
+	}
+	if err = row.Scan(dest...); err != nil {
+		return
+	}
+	if id.IsValid() {
+		entry.SetID(id.Elem().Interface())
+	}
+
+	return
+}
+
+// updateSQL 更新语句, IVersioned 时递增版本并返回 guard (出错时版本已恢复)
+func updateSQL(entry goresource.IDbModel, args ...interface{}) (sql string, newArgs []interface{}, guard *goresource.VersionGuard, err error) {
 	var updateFields []string
 	var ok bool
 	if len(args) > 0 {
@@ -76,7 +158,7 @@ func (r repository) Update(entry goresource.IDbModel, args ...interface{}) (err
 		}
 	}
 
-	newArgs := make([]interface{}, 0)
+	newArgs = make([]interface{}, 0)
 	var where string
 	if len(args) > 1 {
 		where, ok = args[1].(string)
@@ -91,42 +173,70 @@ func (r repository) Update(entry goresource.IDbModel, args ...interface{}) (err
 	}
 
 	table := metadata.Get(entry)
+	guard, versioned := goresource.NextVersion(entry)
+	if len(updateFields) > 0 {
+		if versioned {
+			updateFields = appendField(updateFields, guard.Field)
+		}
+		for _, field := range goresource.UpdateAuditFields(entry) {
+			updateFields = appendField(updateFields, field)
+		}
+	}
 	pkColumn := table.PrimaryKeyColumn()
+	argsCount := len(grammar.UpdateColumns(table, updateFields))
 	if len(newArgs) == 0 && pkColumn != nil {
-		argsCount := len(table.Columns())
-		if len(updateFields) > 0 {
-			argsCount = len(updateFields)
-		}
 		newArgs = append(newArgs, fmt.Sprintf("%s = $%d", pkColumn.Field(), argsCount+1))
 		newArgs = append(newArgs, entry.GetID())
 	}
 	if len(newArgs) == 0 {
 		// 不允许全量更新（查询没有条件、主键时）
+		if versioned {
+			guard.Restore()
+		}
 		err = errs.UpdateFullNotAllowed
 		return
 	}
+	// 乐观锁 版本条件
+	if versioned {
+		newArgs[0] = fmt.Sprintf("(%s) AND %s = $%d", newArgs[0], formatField(guard.Field), argsCount+len(newArgs))
+		newArgs = append(newArgs, guard.Old)
+	}
 
-	sql, args := grammar.Update(metadata.Get(entry), entry, updateFields, newArgs...)
-	if r.uow != nil {
-		r.uow.updateQueue(sql, args...)
-		if r.repositoryBase != nil {
-			r.repositoryBase.SetUow(dbtype.TimeScale, r.uow)
+	sql, newArgs = grammar.Update(table, entry, updateFields, newArgs...)
+
+	return
+}
+
+// appendField 字段不存在时追加
+func appendField(fields []string, field string) []string {
+	for _, f := range fields {
+		if f == field {
+			return fields
 		}
-		return
 	}
-	err = r.exec(sql, args...)
 
-	return
+	return append(append(make([]string, 0, len(fields)+1), fields...), field)
 }
 
 func (r repository) exec(sql string, args ...interface{}) (err error) {
+	_, err = r.execRows(sql, args...)
+	return
+}
+
+// execRows 返回影响行数
+func (r repository) execRows(sql string, args ...interface{}) (rows int64, err error) {
 	conn, err := r.pool.getConn()
 	if err != nil {
 		return
 	}
 	defer conn.Release()
 
-	_, err = conn.Conn().Exec(r.ctx, sql, args...)
+	tag, err := conn.Conn().Exec(r.ctx, sql, args...)
+	if err != nil {
+		err = mapError(err)
+		return
+	}
+	rows = tag.RowsAffected()
 
 	return
 }
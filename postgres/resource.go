@@ -2,9 +2,12 @@ package postgres
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
 )
 
 type resource struct {
@@ -12,36 +15,37 @@ type resource struct {
 	pgxPool *pgxpool.Pool
 }
 
-// Todo: 联合事务有问题
+// Db 参数 ctx、uow (ctx 中的工作单元 goresource.ContextWithUow)
 func (f resource) Db(args ...interface{}) goresource.IRepository {
+	dbArgs := goresource.ParseDbArgs(args...)
 	repo := &repository{
+		ctx: dbArgs.Ctx,
 		pool: &pool{
+			ctx:     dbArgs.Ctx,
 			pgxPool: f.pgxPool,
 		},
 	}
-	for index := range args {
-		if ctx, ok := args[index].(context.Context); ok {
-			repo.ctx = ctx
-			continue
+	if uow, ok := dbArgs.Uow.(*unitOfWork); ok {
+		repo.uow = uow
+	} else if dbArgs.Uow != nil {
+		if repo.repositoryBase = goresource.NewRepository(dbArgs.Uow); repo.repositoryBase == nil {
+			return goresource.NewErrRepository(errs.UowNotSupported)
 		}
-		if uow, ok := args[index].(*unitOfWork); ok {
-			repo.uow = uow
-			continue
+		if enlisted, ok := repo.repositoryBase.GetUow(dbtype.TimeScale, func(enlisted goresource.IUnitOfWork) bool {
+			uow, ok := enlisted.(*unitOfWork)
+			return ok && uow.pool.pgxPool == f.pgxPool
+		}); ok {
+			repo.uow = enlisted.(*unitOfWork)
 		}
-		if uow, ok := args[index].(goresource.IUnitOfWork); ok {
+		if repo.uow == nil {
 			repo.uow = &unitOfWork{
 				pool:          repo.pool,
 				addOfQueue:    make([]commitQueueInfo, 0),
 				deleteOfQueue: make([]commitQueueInfo, 0),
 				updateOfQueue: make([]commitQueueInfo, 0),
 			}
-			repo.repositoryBase = goresource.NewRepository(uow)
 		}
 	}
-	if repo.ctx == nil {
-		repo.ctx = context.Background()
-	}
-	repo.pool.ctx = repo.ctx
 	if repo.uow != nil {
 		repo.uow.ctx = repo.ctx
 	}
@@ -50,34 +54,92 @@ func (f resource) Db(args ...interface{}) goresource.IRepository {
 }
 
 func (f resource) Uow() goresource.IUnitOfWork {
+	ctx := context.Background()
 	return &unitOfWork{
+		ctx: ctx,
 		pool: &pool{
+			ctx:     ctx,
 			pgxPool: f.pgxPool,
 		},
 		addOfQueue:    make([]commitQueueInfo, 0),
 		updateOfQueue: make([]commitQueueInfo, 0),
+		deleteOfQueue: make([]commitQueueInfo, 0),
 	}
 }
 
+func (f resource) DbType() dbtype.Value {
+	return dbtype.TimeScale
+}
+
+func (f resource) Ping(ctx context.Context) error {
+	return f.pgxPool.Ping(ctx)
+}
+
+// Close 关闭连接池
+func (f resource) Close() error {
+	f.pgxPool.Close()
+	return nil
+}
+
+// New 连接失败时 panic, 请使用 Open
 func New(dsn string) goresource.IResource {
+	res, err := Open(dsn)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return res
+}
+
+// Open 连接数据库, pools 为连接池配置
+func Open(dsn string, pools ...goresource.PoolConfig) (goresource.IResource, error) {
+	poolCfg := goresource.PoolConfig{}
+	if len(pools) > 0 {
+		poolCfg = pools[0]
+	}
+
+	return open(dsn, poolCfg)
+}
+
+func open(dsn string, poolCfg goresource.PoolConfig, configures ...func(*pgxpool.Config)) (res goresource.IResource, err error) {
 	config, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
-		panic("connect to database config faild: " + err.Error())
+		err = fmt.Errorf("connect to database config faild: %w", err)
+		return
+	}
+	if poolCfg.MaxOpenConns > 0 {
+		config.MaxConns = int32(poolCfg.MaxOpenConns)
+	}
+	if poolCfg.MaxIdleConns > 0 {
+		config.MinConns = int32(poolCfg.MaxIdleConns)
+	}
+	if poolCfg.MaxLifetime > 0 {
+		config.MaxConnLifetime = poolCfg.MaxLifetime
+	}
+	if poolCfg.MaxIdleTime > 0 {
+		config.MaxConnIdleTime = poolCfg.MaxIdleTime
+	}
+	for _, configure := range configures {
+		configure(config)
 	}
 
 	ctx := context.Background()
 	pool, err := pgxpool.ConnectConfig(ctx, config)
 	if err != nil {
-		panic("Unable to connect to database: " + dsn)
+		err = fmt.Errorf("unable to connect to database: %w", err)
+		return
 	}
 	if err = pool.Ping(ctx); err != nil {
-		panic("connect to database faild: " + err.Error())
+		pool.Close()
+		err = fmt.Errorf("connect to database faild: %w", err)
+		return
 	}
-
-	return &resource{
+	res = &resource{
 		pgxPool: pool,
 		dsn:     dsn,
 	}
+
+	return
 }
 
 func NewByGorm(connStr string) goresource.IFactory {
@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/xm-chentl/goresource"
+)
+
+// TenantSchema 每租户 schema, 租户连接池的 search_path 为租户
+func TenantSchema(dsn string, pools ...goresource.PoolConfig) goresource.TenantResolver {
+	poolCfg := goresource.PoolConfig{}
+	if len(pools) > 0 {
+		poolCfg = pools[0]
+	}
+
+	return func(tenantID string) (goresource.IResource, error) {
+		return open(dsn, poolCfg, func(config *pgxpool.Config) {
+			config.ConnConfig.RuntimeParams["search_path"] = pgx.Identifier{tenantID}.Sanitize()
+		})
+	}
+}
@@ -2,80 +2,143 @@ package postgres
 
 import (
 	"context"
+
+	"github.com/xm-chentl/goresource"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/repositorytype"
+	"github.com/xm-chentl/goresource/uowstatus"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 type commitQueueInfo struct {
-	sql  string
-	args []interface{}
+	sql   string
+	args  []interface{}
+	guard *goresource.VersionGuard // 乐观锁, 未更新到数据时中止提交
+	entry goresource.IDbModel      // Upsert 的模型, 读取 RETURNING 的主键
 }
 
 type unitOfWork struct {
-	ctx  context.Context
-	pool *pool
+	goresource.CommitHooks
+
+	ctx    context.Context
+	pool   *pool
+	conn   *pgxpool.Conn
+	tx     pgx.Tx
+	status uowstatus.Value
 
 	addOfQueue    []commitQueueInfo
 	updateOfQueue []commitQueueInfo
 	deleteOfQueue []commitQueueInfo
 }
 
+// Commit 在事务中执行队列, 失败时保留队列(可再次 Commit 在新事务中重放)
 func (u *unitOfWork) Commit() (err error) {
-	defer u.reset()
-
-	conn, err := u.pool.getConn()
-	if err != nil {
+	if err = u.Prepare(); err != nil {
 		return
 	}
-	defer conn.Release()
+	err = u.CommitPrepared()
 
-	for index := range u.addOfQueue {
-		item := u.addOfQueue[index]
-		if _, err = conn.Exec(u.ctx, item.sql, item.args...); err != nil {
-			return
-		}
-	}
-	for index := range u.updateOfQueue {
-		item := u.updateOfQueue[index]
-		if _, err = conn.Exec(u.ctx, item.sql, item.args...); err != nil {
-			return
-		}
-	}
-	for index := range u.deleteOfQueue {
-		item := u.deleteOfQueue[index]
-		if _, err = conn.Exec(u.ctx, item.sql, item.args...); err != nil {
-			return
-		}
-	}
+	return
+}
+
+// Rollback 放弃队列，已 Prepare 的事务回滚
+func (u *unitOfWork) Rollback() (err error) {
+	err = u.RollbackPrepared()
+	u.reset()
+	u.status = uowstatus.Aborted
 
 	return
 }
 
-func (u *unitOfWork) Commit1() (err error) {
+func (u *unitOfWork) Discard() {
+	_ = u.Rollback()
+}
+
+func (u *unitOfWork) Pending() map[repositorytype.Value]int {
+	return map[repositorytype.Value]int{
+		repositorytype.Create: len(u.addOfQueue),
+		repositorytype.Update: len(u.updateOfQueue),
+		repositorytype.Delete: len(u.deleteOfQueue),
+	}
+}
+
+func (u *unitOfWork) Status() uowstatus.Value {
+	return u.status
+}
+
+// Prepare 开启事务并执行队列(不提交)
+func (u *unitOfWork) Prepare() (err error) {
+	if u.status == uowstatus.Aborted {
+		err = errs.UowDiscarded
+		return
+	}
+
 	conn, err := u.pool.getConn()
 	if err != nil {
 		return
 	}
-	defer conn.Release()
 
 	tx, err := conn.Begin(u.ctx)
 	if err != nil {
+		err = mapError(err)
+		conn.Release()
 		return
 	}
-	defer func() {
-		if tx != nil {
-			if err != nil {
-				_ = tx.Rollback(u.ctx)
-			} else {
-				_ = tx.Commit(u.ctx)
+	u.conn = conn
+	u.tx = tx
+
+	queues := [][]commitQueueInfo{u.addOfQueue, u.updateOfQueue, u.deleteOfQueue}
+	for _, queue := range queues {
+		for index := range queue {
+			if err = u.exec(tx, queue[index]); err != nil {
+				_ = u.RollbackPrepared()
+				return
 			}
 		}
-	}()
+	}
 
-	for index := range u.addOfQueue {
-		item := u.addOfQueue[index]
-		if _, err = tx.Exec(u.ctx, item.sql, item.args...); err != nil {
-			return
-		}
+	return
+}
+
+func (u *unitOfWork) exec(tx pgx.Tx, item commitQueueInfo) (err error) {
+	if item.entry != nil {
+		_, err = scanUpsert(tx.QueryRow(u.ctx, item.sql, item.args...), item.entry)
+		return mapError(err)
+	}
+
+	tag, err := tx.Exec(u.ctx, item.sql, item.args...)
+	if err = mapError(err); err == nil && item.guard != nil && tag.RowsAffected() == 0 {
+		err = item.guard.Conflict()
+	}
+
+	return
+}
+
+func (u *unitOfWork) CommitPrepared() (err error) {
+	defer u.release()
+
+	if u.tx == nil {
+		return
+	}
+	if err = u.tx.Commit(u.ctx); err == nil {
+		u.reset()
+		u.status = uowstatus.Committed
+		u.RunCommitted()
+	}
+	err = mapError(err)
+
+	return
+}
+
+func (u *unitOfWork) RollbackPrepared() (err error) {
+	defer u.release()
+
+	if u.tx == nil {
+		return
 	}
+	err = u.tx.Rollback(u.ctx)
 
 	return
 }
@@ -87,10 +150,19 @@ func (u *unitOfWork) addQueue(sql string, args ...interface{}) {
 	})
 }
 
-func (u *unitOfWork) updateQueue(sql string, args ...interface{}) {
+func (u *unitOfWork) upsertQueue(sql string, entry goresource.IDbModel, args ...interface{}) {
+	u.addOfQueue = append(u.addOfQueue, commitQueueInfo{
+		sql:   sql,
+		args:  args,
+		entry: entry,
+	})
+}
+
+func (u *unitOfWork) updateQueue(sql string, guard *goresource.VersionGuard, args ...interface{}) {
 	u.updateOfQueue = append(u.updateOfQueue, commitQueueInfo{
-		sql:  sql,
-		args: args,
+		sql:   sql,
+		args:  args,
+		guard: guard,
 	})
 }
 
@@ -101,6 +173,15 @@ func (u *unitOfWork) deleteQueue(sql string, args ...interface{}) {
 	})
 }
 
+// release 释放事务连接
+func (u *unitOfWork) release() {
+	if u.conn != nil {
+		u.conn.Release()
+	}
+	u.conn = nil
+	u.tx = nil
+}
+
 func (u *unitOfWork) reset() {
 	u.addOfQueue = make([]commitQueueInfo, 0)
 	u.updateOfQueue = make([]commitQueueInfo, 0)
@@ -0,0 +1,192 @@
+package goresource
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/xm-chentl/goresource/errs"
+)
+
+// Query 泛型查询, 包装 IQuery 直接返回 []T / T
+type Query[T IDbModel] struct {
+	query IQuery
+}
+
+func (q *Query[T]) Asc(fields ...string) *Query[T] {
+	q.query = q.query.Asc(fields...)
+	return q
+}
+
+func (q *Query[T]) Count() (int64, error) {
+	return q.query.Count(newEntry[T]())
+}
+
+func (q *Query[T]) Desc(fields ...string) *Query[T] {
+	q.query = q.query.Desc(fields...)
+	return q
+}
+
+// Exec 执行原生语句 args 同 IQuery.Exec
+func (q *Query[T]) Exec(args ...interface{}) (res []T, err error) {
+	rv, err := sliceOf[T]()
+	if err != nil {
+		return
+	}
+	if err = q.query.Exec(rv.Interface(), args...); err != nil {
+		return
+	}
+	res = toEntries[T](rv)
+
+	return
+}
+
+func (q *Query[T]) Fields(args ...interface{}) *Query[T] {
+	q.query = q.query.Fields(args...)
+	return q
+}
+
+func (q *Query[T]) Find() (res []T, err error) {
+	rv, err := sliceOf[T]()
+	if err != nil {
+		return
+	}
+	if err = q.query.Find(rv.Interface()); err != nil {
+		return
+	}
+	res = toEntries[T](rv)
+
+	return
+}
+
+// FindPage 数据及分页信息
+func (q *Query[T]) FindPage() (res []T, info PageInfo, err error) {
+	rv, err := sliceOf[T]()
+	if err != nil {
+		return
+	}
+	if info, err = q.query.FindPage(rv.Interface()); err != nil {
+		return
+	}
+	res = toEntries[T](rv)
+
+	return
+}
+
+func (q *Query[T]) First() (res T, err error) {
+	res = newEntry[T]()
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() == reflect.Ptr {
+		err = q.query.First(res)
+		return
+	}
+
+	err = q.query.First(&res)
+
+	return
+}
+
+// MustFirst 未找到时返回 errs.ErrNotFound
+func (q *Query[T]) MustFirst() (res T, err error) {
+	res = newEntry[T]()
+	if reflect.TypeOf((*T)(nil)).Elem().Kind() == reflect.Ptr {
+		err = q.query.MustFirst(res)
+		return
+	}
+
+	err = q.query.MustFirst(&res)
+
+	return
+}
+
+func (q *Query[T]) Page(page int) *Query[T] {
+	q.query = q.query.Page(page)
+	return q
+}
+
+func (q *Query[T]) PageSize(pageSize int) *Query[T] {
+	q.query = q.query.PageSize(pageSize)
+	return q
+}
+
+func (q *Query[T]) SetOpts(opts ...interface{}) *Query[T] {
+	q.query = q.query.SetOpts(opts...)
+	return q
+}
+
+func (q *Query[T]) Where(args ...interface{}) *Query[T] {
+	q.query = q.query.Where(args...)
+	return q
+}
+
+func (q *Query[T]) WithDeleted() *Query[T] {
+	q.query = q.query.WithDeleted()
+	return q
+}
+
+func (q *Query[T]) OnlyDeleted() *Query[T] {
+	q.query = q.query.OnlyDeleted()
+	return q
+}
+
+func (q *Query[T]) BatchSize(size int) *Query[T] {
+	q.query = q.query.BatchSize(size)
+	return q
+}
+
+func (q *Query[T]) Cursor() (ICursor, error) {
+	return q.query.Cursor(newEntry[T]())
+}
+
+// Iterate 游标逐行读取, fn 返回 errs.StopIteration 时提前结束(不返回错误), 返回其他错误时结束并返回
+func (q *Query[T]) Iterate(fn func(row T) error) (err error) {
+	cursor, err := q.Cursor()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := cursor.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	isPtr := reflect.TypeOf((*T)(nil)).Elem().Kind() == reflect.Ptr
+	for cursor.Next() {
+		row := newEntry[T]()
+		if isPtr {
+			err = cursor.Scan(row)
+		} else {
+			err = cursor.Scan(&row)
+		}
+		if err == nil {
+			err = fn(row)
+		}
+		if errors.Is(err, errs.StopIteration) {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+	}
+	err = cursor.Err()
+
+	return
+}
+
+func (q *Query[T]) After(token string) *Query[T] {
+	q.query = q.query.After(token)
+	return q
+}
+
+func (q *Query[T]) Before(token string) *Query[T] {
+	q.query = q.query.Before(token)
+	return q
+}
+
+// PageToken 最近一次 keyset 分页 Find 的下一页、上一页 token
+func (q *Query[T]) PageToken() (next, prev string) {
+	return q.query.PageToken()
+}
+
+// Raw 原始查询
+func (q *Query[T]) Raw() IQuery {
+	return q.query
+}
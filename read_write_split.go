@@ -0,0 +1,253 @@
+package goresource
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xm-chentl/goresource/balancetype"
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+)
+
+// replicaErrorLatency 从库读操作失败时记录的耗时(失败快的从库不因此被优先选择)
+const replicaErrorLatency = time.Second
+
+// replicaProbeEvery 最低延迟时每 N 次查询轮流选择一个从库, 耗时高(失败)的从库恢复后能重新统计
+const replicaProbeEvery = 20
+
+type readYourWritesCtxKey struct{}
+
+// WithReadYourWrites 读写分离时 ctx 中的读操作固定使用主库
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesCtxKey{}, true)
+}
+
+func isReadYourWrites(ctx context.Context) bool {
+	v, _ := ctx.Value(readYourWritesCtxKey{}).(bool)
+	return v
+}
+
+// NewReadWriteSplit 读写分离, 写操作、Exec 与工作单元使用主库, 查询按策略使用从库
+// 工作单元中(参数或 ctx) 或 WithReadYourWrites 的查询使用主库
+func NewReadWriteSplit(policy balancetype.Value, primary IResource, replicas ...IResource) IResource {
+	res := &readWriteSplit{
+		policy:    policy,
+		primary:   primary,
+		replicas:  make([]IResource, len(replicas)),
+		latencies: make([]time.Duration, len(replicas)),
+	}
+	for index := range replicas {
+		res.replicas[index] = WithInterceptors(replicas[index], res.observe(index))
+	}
+
+	return res
+}
+
+type readWriteSplit struct {
+	policy   balancetype.Value
+	primary  IResource
+	replicas []IResource
+
+	next      uint64
+	rw        sync.RWMutex
+	latencies []time.Duration // 从库读操作耗时(指数加权平均)
+}
+
+func (r *readWriteSplit) Db(args ...interface{}) IRepository {
+	dbArgs := ParseDbArgs(args...)
+	if len(r.replicas) == 0 || dbArgs.Uow != nil || isReadYourWrites(dbArgs.Ctx) {
+		return r.primary.Db(args...)
+	}
+
+	return &readWriteSplitRepository{
+		IRepository: r.primary.Db(args...),
+		args:        args,
+		split:       r,
+	}
+}
+
+func (r *readWriteSplit) Uow() IUnitOfWork {
+	return r.primary.Uow()
+}
+
+func (r *readWriteSplit) DbType() dbtype.Value {
+	return TypeOf(r.primary)
+}
+
+// Ping 检测主库与所有从库
+func (r *readWriteSplit) Ping(ctx context.Context) error {
+	for _, resource := range r.all() {
+		if pinger, ok := as[IPinger](resource); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close 关闭主库与所有从库
+func (r *readWriteSplit) Close() (err error) {
+	for _, resource := range r.all() {
+		if closer, ok := as[io.Closer](resource); ok {
+			if closeErr := closer.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	}
+
+	return
+}
+
+func (r *readWriteSplit) all() []IResource {
+	return append([]IResource{r.primary}, r.replicas...)
+}
+
+// replica 按策略选择从库, 最低延迟时未统计的从库优先, 每 replicaProbeEvery 次轮流探测一个从库
+func (r *readWriteSplit) replica() IResource {
+	index := atomic.AddUint64(&r.next, 1) - 1
+	if r.policy != balancetype.LeastLatency {
+		return r.replicas[index%uint64(len(r.replicas))]
+	}
+	if index%replicaProbeEvery == replicaProbeEvery-1 {
+		return r.replicas[(index/replicaProbeEvery)%uint64(len(r.replicas))]
+	}
+
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	picked := 0
+	for index, latency := range r.latencies {
+		if latency < r.latencies[picked] {
+			picked = index
+		}
+	}
+	return r.replicas[picked]
+}
+
+// observe 记录从库读操作耗时, 失败(未找到除外)时至少记录 replicaErrorLatency, 失败后成功时重新统计
+func (r *readWriteSplit) observe(index int) Interceptor {
+	return func(inv *Invocation, next Handler) error {
+		err := next(inv)
+		duration := inv.Duration
+		if err != nil && !errors.Is(err, errs.ErrNotFound) && duration < replicaErrorLatency {
+			duration = replicaErrorLatency
+		}
+
+		r.rw.Lock()
+		if r.latencies[index] == 0 || err == nil && r.latencies[index] >= replicaErrorLatency {
+			r.latencies[index] = duration
+		} else {
+			r.latencies[index] = (r.latencies[index]*4 + duration) / 5
+		}
+		r.rw.Unlock()
+
+		return err
+	}
+}
+
+type readWriteSplitRepository struct {
+	IRepository
+
+	args  []interface{}
+	split *readWriteSplit
+}
+
+func (r *readWriteSplitRepository) Query() IQuery {
+	return &readWriteSplitQuery{
+		IQuery:  r.split.replica().Db(r.args...).Query(),
+		primary: r.IRepository,
+	}
+}
+
+func (r *readWriteSplitRepository) Upsert(entry IDbModel, opts UpsertOptions) (bool, error) {
+	return Upsert(r.IRepository, entry, opts)
+}
+
+func (r *readWriteSplitRepository) CreateMany(entries []IDbModel, batchSize int) error {
+	return CreateMany(r.IRepository, entries, batchSize)
+}
+
+func (r *readWriteSplitRepository) UpdateMany(entries []IDbModel, batchSize int) error {
+	return UpdateMany(r.IRepository, entries, batchSize)
+}
+
+func (r *readWriteSplitRepository) DeleteMany(entries []IDbModel, batchSize int) error {
+	return DeleteMany(r.IRepository, entries, batchSize)
+}
+
+// readWriteSplitQuery 查询使用从库, Exec 原生语句可能为写操作, 使用主库
+type readWriteSplitQuery struct {
+	IQuery
+
+	primary IRepository
+}
+
+func (q *readWriteSplitQuery) Exec(res interface{}, args ...interface{}) error {
+	return q.primary.Query().Exec(res, args...)
+}
+
+func (q *readWriteSplitQuery) Asc(fields ...string) IQuery {
+	q.IQuery = q.IQuery.Asc(fields...)
+	return q
+}
+
+func (q *readWriteSplitQuery) Desc(fields ...string) IQuery {
+	q.IQuery = q.IQuery.Desc(fields...)
+	return q
+}
+
+func (q *readWriteSplitQuery) Fields(args ...interface{}) IQuery {
+	q.IQuery = q.IQuery.Fields(args...)
+	return q
+}
+
+func (q *readWriteSplitQuery) Page(page int) IQuery {
+	q.IQuery = q.IQuery.Page(page)
+	return q
+}
+
+func (q *readWriteSplitQuery) PageSize(pageSize int) IQuery {
+	q.IQuery = q.IQuery.PageSize(pageSize)
+	return q
+}
+
+func (q *readWriteSplitQuery) SetOpts(opts ...interface{}) IQuery {
+	q.IQuery = q.IQuery.SetOpts(opts...)
+	return q
+}
+
+func (q *readWriteSplitQuery) Where(args ...interface{}) IQuery {
+	q.IQuery = q.IQuery.Where(args...)
+	return q
+}
+
+func (q *readWriteSplitQuery) WithDeleted() IQuery {
+	q.IQuery = q.IQuery.WithDeleted()
+	return q
+}
+
+func (q *readWriteSplitQuery) OnlyDeleted() IQuery {
+	q.IQuery = q.IQuery.OnlyDeleted()
+	return q
+}
+
+func (q *readWriteSplitQuery) BatchSize(size int) IQuery {
+	q.IQuery = q.IQuery.BatchSize(size)
+	return q
+}
+
+func (q *readWriteSplitQuery) After(token string) IQuery {
+	q.IQuery = q.IQuery.After(token)
+	return q
+}
+
+func (q *readWriteSplitQuery) Before(token string) IQuery {
+	q.IQuery = q.IQuery.Before(token)
+	return q
+}
@@ -0,0 +1,157 @@
+package goresource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xm-chentl/goresource/balancetype"
+	"github.com/xm-chentl/goresource/errs"
+)
+
+type testSlowQuery struct {
+	testQuery
+	delay time.Duration
+}
+
+func (q *testSlowQuery) Find(res interface{}) error {
+	time.Sleep(q.delay)
+	return q.testQuery.Find(res)
+}
+
+func newTestSplitResource(name string, delay time.Duration) (testRepoResource, *testRepository) {
+	repo := &testRepository{}
+	repo.query = &testQuery{rows: []testPerson{{Name: name}}}
+	if delay > 0 {
+		return testRepoResource{repository: &testSlowRepository{testRepository: repo, delay: delay}}, repo
+	}
+
+	return testRepoResource{repository: repo}, repo
+}
+
+type testSlowRepository struct {
+	*testRepository
+	delay time.Duration
+}
+
+func (r *testSlowRepository) Query() IQuery {
+	return &testSlowQuery{testQuery: *r.testRepository.query, delay: r.delay}
+}
+
+type testFailRepository struct {
+	*testRepository
+	recovered bool
+}
+
+func (r *testFailRepository) Query() IQuery {
+	if r.recovered {
+		return r.testRepository.Query()
+	}
+	return &testFailQuery{testQuery: *r.testRepository.query}
+}
+
+type testFailQuery struct {
+	testQuery
+}
+
+func (q *testFailQuery) Find(res interface{}) error {
+	return errs.ErrConnectionLost
+}
+
+func findName(t *testing.T, db IRepository) string {
+	var res []testPerson
+	assert.NoError(t, db.Query().Find(&res))
+	return res[0].Name
+}
+
+func Test_NewReadWriteSplit(test *testing.T) {
+	test.Run("round robin", func(t *testing.T) {
+		primary, primaryRepo := newTestSplitResource("primary", 0)
+		replica1, _ := newTestSplitResource("replica-1", 0)
+		replica2, _ := newTestSplitResource("replica-2", 0)
+		resource := NewReadWriteSplit(balancetype.RoundRobin, primary, replica1, replica2)
+
+		a := assert.New(t)
+		a.Equal("replica-1", findName(t, resource.Db()))
+		a.Equal("replica-2", findName(t, resource.Db()))
+		a.Equal("replica-1", findName(t, resource.Db()))
+
+		a.NoError(resource.Db().Create(&testPerson{ID: 1}))
+		a.Len(primaryRepo.created, 1)
+		a.Equal(testDbType, TypeOf(resource))
+	})
+
+	test.Run("least latency", func(t *testing.T) {
+		primary, _ := newTestSplitResource("primary", 0)
+		slow, _ := newTestSplitResource("slow", 20*time.Millisecond)
+		fast, _ := newTestSplitResource("fast", time.Millisecond)
+		resource := NewReadWriteSplit(balancetype.LeastLatency, primary, slow, fast)
+
+		a := assert.New(t)
+		a.Equal("slow", findName(t, resource.Db()))
+		a.Equal("fast", findName(t, resource.Db()))
+		a.Equal("fast", findName(t, resource.Db()))
+	})
+
+	test.Run("least latency failed replica", func(t *testing.T) {
+		primary, _ := newTestSplitResource("primary", 0)
+		_, failedRepo := newTestSplitResource("failed", 0)
+		slow, _ := newTestSplitResource("slow", 5*time.Millisecond)
+		failed := testRepoResource{repository: &testFailRepository{testRepository: failedRepo}}
+		resource := NewReadWriteSplit(balancetype.LeastLatency, primary, failed, slow)
+
+		a := assert.New(t)
+		var res []testPerson
+		a.ErrorIs(resource.Db().Query().Find(&res), errs.ErrConnectionLost)
+		a.Equal("slow", findName(t, resource.Db()))
+		a.Equal("slow", findName(t, resource.Db()))
+	})
+
+	test.Run("least latency probes failed replica", func(t *testing.T) {
+		primary, _ := newTestSplitResource("primary", 0)
+		_, failedRepo := newTestSplitResource("failed", 0)
+		slow, _ := newTestSplitResource("slow", time.Millisecond)
+		failedRepository := &testFailRepository{testRepository: failedRepo}
+		resource := NewReadWriteSplit(balancetype.LeastLatency, primary, testRepoResource{repository: failedRepository}, slow)
+
+		a := assert.New(t)
+		var res []testPerson
+		a.ErrorIs(resource.Db().Query().Find(&res), errs.ErrConnectionLost)
+		failedRepository.recovered = true
+		for i := 1; i < replicaProbeEvery-1; i++ {
+			a.Equal("slow", findName(t, resource.Db()))
+		}
+		a.Equal("failed", findName(t, resource.Db()))
+		a.Equal("failed", findName(t, resource.Db()))
+	})
+
+	test.Run("exec on primary", func(t *testing.T) {
+		primary, primaryRepo := newTestSplitResource("primary", 0)
+		replica, replicaRepo := newTestSplitResource("replica", 0)
+		resource := NewReadWriteSplit(balancetype.RoundRobin, primary, replica)
+
+		a := assert.New(t)
+		var res []testPerson
+		a.NoError(resource.Db().Query().Where("id = ?", 1).Exec(&res, "SELECT nextval('seq')"))
+		a.Len(primaryRepo.query.execs, 1)
+		a.Empty(replicaRepo.query.execs)
+	})
+
+	test.Run("pinned to primary", func(t *testing.T) {
+		primary, _ := newTestSplitResource("primary", 0)
+		replica, _ := newTestSplitResource("replica", 0)
+		resource := NewReadWriteSplit(balancetype.RoundRobin, primary, replica)
+
+		a := assert.New(t)
+		a.Equal("primary", findName(t, resource.Db(WithReadYourWrites(context.Background()))))
+		a.Equal("primary", findName(t, resource.Db(ContextWithUow(context.Background(), Uow()))))
+		a.Equal("replica", findName(t, resource.Db()))
+	})
+
+	test.Run("no replica", func(t *testing.T) {
+		primary, _ := newTestSplitResource("primary", 0)
+		resource := NewReadWriteSplit(balancetype.RoundRobin, primary)
+		assert.Equal(t, "primary", findName(t, resource.Db()))
+	})
+}
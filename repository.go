@@ -0,0 +1,118 @@
+package goresource
+
+import (
+	"reflect"
+
+	"github.com/xm-chentl/goresource/errs"
+)
+
+// Repository 泛型仓储, 包装 IResource.Db(...) 返回的 IRepository
+type Repository[T IDbModel] struct {
+	repository IRepository
+}
+
+func (r Repository[T]) Create(entry T, args ...interface{}) error {
+	return r.repository.Create(entry, args...)
+}
+
+func (r Repository[T]) Delete(entry T, args ...interface{}) error {
+	return r.repository.Delete(entry, args...)
+}
+
+func (r Repository[T]) Update(entry T, args ...interface{}) error {
+	return r.repository.Update(entry, args...)
+}
+
+// CreateMany 批量创建, 同 goresource.CreateMany
+func (r Repository[T]) CreateMany(entries []T, batchSize int) error {
+	return CreateMany(r.repository, models(entries), batchSize)
+}
+
+// UpdateMany 批量更新, 同 goresource.UpdateMany
+func (r Repository[T]) UpdateMany(entries []T, batchSize int) error {
+	return UpdateMany(r.repository, models(entries), batchSize)
+}
+
+// DeleteMany 批量删除, 同 goresource.DeleteMany
+func (r Repository[T]) DeleteMany(entries []T, batchSize int) error {
+	return DeleteMany(r.repository, models(entries), batchSize)
+}
+
+// Upsert 插入或更新, 同 goresource.Upsert
+func (r Repository[T]) Upsert(entry T, opts ...UpsertOptions) (bool, error) {
+	return Upsert(r.repository, entry, opts...)
+}
+
+func (r Repository[T]) Query() *Query[T] {
+	return &Query[T]{
+		query: r.repository.Query(),
+	}
+}
+
+// Raw 原始仓储
+func (r Repository[T]) Raw() IRepository {
+	return r.repository
+}
+
+// Db 获取泛型仓储 args 同 IResource.Db
+func Db[T IDbModel](resource IResource, args ...interface{}) *Repository[T] {
+	return Wrap[T](resource.Db(args...))
+}
+
+// Wrap 包装已有仓储
+func Wrap[T IDbModel](repository IRepository) *Repository[T] {
+	return &Repository[T]{
+		repository: repository,
+	}
+}
+
+// models []T 转为 []IDbModel
+func models[T IDbModel](entries []T) []IDbModel {
+	res := make([]IDbModel, 0, len(entries))
+	for _, entry := range entries {
+		res = append(res, entry)
+	}
+
+	return res
+}
+
+// newEntry 创建模型实例, T 为指针时创建指向的结构
+func newEntry[T IDbModel]() (entry T) {
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	if rt.Kind() == reflect.Ptr {
+		entry = reflect.New(rt.Elem()).Interface().(T)
+	}
+
+	return
+}
+
+// sliceOf 创建结果切片, T 为指针时使用指向的结构类型(各资源按结构类型映射)
+func sliceOf[T IDbModel]() (res reflect.Value, err error) {
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		err = errs.ResIsNotStruct
+		return
+	}
+	res = reflect.New(reflect.SliceOf(rt))
+
+	return
+}
+
+// toEntries 将结构切片转为 []T
+func toEntries[T IDbModel](rv reflect.Value) (res []T) {
+	rv = reflect.Indirect(rv)
+	res = make([]T, 0, rv.Len())
+	isPtr := reflect.TypeOf((*T)(nil)).Elem().Kind() == reflect.Ptr
+	for i := 0; i < rv.Len(); i++ {
+		if isPtr {
+			res = append(res, rv.Index(i).Addr().Interface().(T))
+		} else {
+			res = append(res, rv.Index(i).Interface().(T))
+		}
+	}
+
+	return
+}
@@ -6,12 +6,23 @@ type RepositoryBase struct {
 	uow *unitOfWork
 }
 
+// GetUow 已登记的资源工作单元, 同一工作单元多次 Db 时复用; 同类型的多个资源由 match 区分实例
+func (r RepositoryBase) GetUow(dbType dbtype.Value, match func(IUnitOfWork) bool) (uow IUnitOfWork, ok bool) {
+	return r.uow.find(dbType, match)
+}
+
 func (r RepositoryBase) SetUow(dbType dbtype.Value, uow IUnitOfWork) {
-	r.uow.uowMap[dbType] = uow
+	r.uow.enlist(dbType, uow)
 }
 
+// NewRepository uow 不是 Uow() 创建的联合工作单元(其他资源的工作单元)时返回 nil
 func NewRepository(uow IUnitOfWork) *RepositoryBase {
+	jointUow, ok := uow.(*unitOfWork)
+	if !ok {
+		return nil
+	}
+
 	return &RepositoryBase{
-		uow: uow.(*unitOfWork),
+		uow: jointUow,
 	}
 }
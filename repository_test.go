@@ -0,0 +1,120 @@
+package goresource
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPerson struct {
+	ID   int64
+	Name string
+}
+
+func (t testPerson) GetID() interface{} {
+	return t.ID
+}
+
+func (t *testPerson) SetID(v interface{}) {
+	if vv, ok := v.(int64); ok {
+		t.ID = vv
+	}
+}
+
+func (t testPerson) Table() string {
+	return "test_person"
+}
+
+type testQuery struct {
+	IQuery
+	rows  []testPerson
+	where []interface{}
+	execs [][]interface{}
+}
+
+func (q *testQuery) Exec(res interface{}, args ...interface{}) error {
+	q.execs = append(q.execs, args)
+	return nil
+}
+
+func (q *testQuery) Count(entry IDbModel) (int64, error) {
+	return int64(len(q.rows)), nil
+}
+
+func (q *testQuery) Find(res interface{}) error {
+	reflect.ValueOf(res).Elem().Set(reflect.ValueOf(q.rows))
+	return nil
+}
+
+func (q *testQuery) First(res interface{}) error {
+	if len(q.rows) > 0 {
+		reflect.ValueOf(res).Elem().Set(reflect.ValueOf(q.rows[0]))
+	}
+	return nil
+}
+
+func (q *testQuery) Where(args ...interface{}) IQuery {
+	q.where = args
+	return q
+}
+
+type testRepository struct {
+	IRepository
+	query   *testQuery
+	created []IDbModel
+}
+
+func (r *testRepository) Create(entry IDbModel, args ...interface{}) error {
+	r.created = append(r.created, entry)
+	return nil
+}
+
+func (r *testRepository) Query() IQuery {
+	return r.query
+}
+
+func Test_Repository(test *testing.T) {
+	rows := []testPerson{
+		{ID: 1, Name: "name-001"},
+		{ID: 2, Name: "name-002"},
+	}
+
+	test.Run("find", func(t *testing.T) {
+		repo := Wrap[*testPerson](&testRepository{
+			query: &testQuery{rows: rows},
+		})
+		res, err := repo.Query().Where("name = ?", "name-001").Find()
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal([]*testPerson{&rows[0], &rows[1]}, res)
+	})
+
+	test.Run("first", func(t *testing.T) {
+		repo := Wrap[*testPerson](&testRepository{
+			query: &testQuery{rows: rows},
+		})
+		res, err := repo.Query().First()
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal(&rows[0], res)
+	})
+
+	test.Run("count", func(t *testing.T) {
+		repo := Wrap[*testPerson](&testRepository{
+			query: &testQuery{rows: rows},
+		})
+		count, err := repo.Query().Count()
+		a := assert.New(t)
+		a.NoError(err)
+		a.Equal(int64(2), count)
+	})
+
+	test.Run("create", func(t *testing.T) {
+		inner := &testRepository{}
+		repo := Wrap[*testPerson](inner)
+		a := assert.New(t)
+		a.NoError(repo.Create(&rows[0]))
+		a.Equal([]IDbModel{&rows[0]}, inner.created)
+	})
+}
@@ -6,4 +6,5 @@ const (
 	Create Value = iota
 	Delete
 	Update
+	Upsert
 )
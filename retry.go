@@ -0,0 +1,338 @@
+package goresource
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/xm-chentl/goresource/errs"
+)
+
+// RetryPolicy 重试策略, 第 n 次重试前等待 BaseDelay*2^(n-1)(不超过 MaxDelay) 的一半到全部(随机抖动)
+type RetryPolicy struct {
+	MaxAttempts int // 最大执行次数(含首次), 小于 2 时不重试
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration // 为 0 时不限制
+	// Retryable 可重试的错误, 为空时为 IsRetryable
+	Retryable func(err error) bool
+	// NonIdempotent 事务外的写操作(Create、Update、Delete、Upsert、批量操作、Exec)也重试, 调用方须保证可重复执行
+	NonIdempotent bool
+}
+
+// DefaultRetryPolicy 最多执行 3 次, 等待 50ms 起
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   50 * time.Millisecond,
+	MaxDelay:    time.Second,
+}
+
+// IsRetryable 死锁(序列化失败)、超时、连接断开; 联合提交已有资源提交时不可重试
+func IsRetryable(err error) bool {
+	var commitErr *CommitError
+	if errors.As(err, &commitErr) && len(commitErr.Committed) > 0 {
+		return false
+	}
+
+	return errors.Is(err, errs.ErrDeadlock) || errors.Is(err, errs.ErrTimeout) || errors.Is(err, errs.ErrConnectionLost)
+}
+
+// Do 执行 fn, 可重试的错误按策略重试, ctx 结束时返回最后一次的错误
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) (err error) {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return
+		}
+
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// delay 第 attempt 次重试前的等待时间
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// WithRetry 查询按策略重试(每次使用新的查询); 事务外的写操作仅 NonIdempotent 时重试;
+// Uow 为使用该策略的联合工作单元, 提交失败时在新事务中重放全部队列
+func WithRetry(resource IResource, policy RetryPolicy) IResource {
+	return &retryResource{
+		resource: resource,
+		policy:   policy,
+	}
+}
+
+type retryResource struct {
+	resource IResource
+	policy   RetryPolicy
+}
+
+func (r *retryResource) Db(args ...interface{}) IRepository {
+	dbArgs := ParseDbArgs(args...)
+	return &retryRepository{
+		ctx:        dbArgs.Ctx,
+		policy:     r.policy,
+		inUow:      dbArgs.Uow != nil,
+		repository: r.resource.Db(args...),
+	}
+}
+
+func (r *retryResource) Uow() IUnitOfWork {
+	return UowWithRetry(r.policy)
+}
+
+func (r *retryResource) Unwrap() IResource {
+	return r.resource
+}
+
+type retryRepository struct {
+	ctx        context.Context
+	policy     RetryPolicy
+	inUow      bool // 工作单元中写操作只加入队列
+	repository IRepository
+}
+
+func (r *retryRepository) Create(entry IDbModel, args ...interface{}) error {
+	return r.write(func() error {
+		return r.repository.Create(entry, args...)
+	})
+}
+
+func (r *retryRepository) Delete(entry IDbModel, args ...interface{}) error {
+	return r.write(func() error {
+		return r.repository.Delete(entry, args...)
+	})
+}
+
+func (r *retryRepository) Update(entry IDbModel, args ...interface{}) error {
+	return r.write(func() error {
+		return r.repository.Update(entry, args...)
+	})
+}
+
+func (r *retryRepository) Upsert(entry IDbModel, opts UpsertOptions) (inserted bool, err error) {
+	err = r.write(func() (err error) {
+		inserted, err = Upsert(r.repository, entry, opts)
+		return
+	})
+
+	return
+}
+
+func (r *retryRepository) CreateMany(entries []IDbModel, batchSize int) error {
+	return r.write(func() error {
+		return CreateMany(r.repository, entries, batchSize)
+	})
+}
+
+func (r *retryRepository) UpdateMany(entries []IDbModel, batchSize int) error {
+	return r.write(func() error {
+		return UpdateMany(r.repository, entries, batchSize)
+	})
+}
+
+func (r *retryRepository) DeleteMany(entries []IDbModel, batchSize int) error {
+	return r.write(func() error {
+		return DeleteMany(r.repository, entries, batchSize)
+	})
+}
+
+func (r *retryRepository) Query() IQuery {
+	return &retryQuery{
+		repository: r,
+		ops:        make([]func(IQuery) IQuery, 0),
+	}
+}
+
+// write 工作单元中或未允许非幂等重试时只执行一次
+func (r *retryRepository) write(fn func() error) error {
+	if r.inUow || !r.policy.NonIdempotent {
+		return fn()
+	}
+
+	return r.policy.Do(r.ctx, fn)
+}
+
+// retryQuery 记录条件等设置, 每次执行时在新的查询上重放
+type retryQuery struct {
+	repository *retryRepository
+	ops        []func(IQuery) IQuery
+	last       IQuery // 最近一次执行的查询
+}
+
+func (q *retryQuery) with(op func(IQuery) IQuery) IQuery {
+	q.ops = append(q.ops, op)
+	return q
+}
+
+// do 按策略执行, write 为 true 时按写操作处理; 执行后清空设置(同各资源查询的 reset)
+func (q *retryQuery) do(write bool, fn func(query IQuery) error) error {
+	ops := q.ops
+	q.ops = make([]func(IQuery) IQuery, 0)
+	attempt := func() error {
+		q.last = q.repository.repository.Query()
+		for _, op := range ops {
+			q.last = op(q.last)
+		}
+		return fn(q.last)
+	}
+	if write {
+		return q.repository.write(attempt)
+	}
+
+	return q.repository.policy.Do(q.repository.ctx, attempt)
+}
+
+func (q *retryQuery) Asc(fields ...string) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.Asc(fields...)
+	})
+}
+
+func (q *retryQuery) Count(entry IDbModel) (count int64, err error) {
+	err = q.do(false, func(query IQuery) (err error) {
+		count, err = query.Count(entry)
+		return
+	})
+
+	return
+}
+
+func (q *retryQuery) Desc(fields ...string) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.Desc(fields...)
+	})
+}
+
+// Exec 原生语句可能为写操作, 按写操作处理
+func (q *retryQuery) Exec(res interface{}, args ...interface{}) error {
+	return q.do(true, func(query IQuery) error {
+		return query.Exec(res, args...)
+	})
+}
+
+func (q *retryQuery) Fields(args ...interface{}) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.Fields(args...)
+	})
+}
+
+func (q *retryQuery) Find(res interface{}) error {
+	return q.do(false, func(query IQuery) error {
+		return query.Find(res)
+	})
+}
+
+func (q *retryQuery) FindPage(res interface{}) (info PageInfo, err error) {
+	err = q.do(false, func(query IQuery) (err error) {
+		info, err = query.FindPage(res)
+		return
+	})
+
+	return
+}
+
+func (q *retryQuery) First(res interface{}) error {
+	return q.do(false, func(query IQuery) error {
+		return query.First(res)
+	})
+}
+
+func (q *retryQuery) MustFirst(res interface{}) error {
+	return q.do(false, func(query IQuery) error {
+		return query.MustFirst(res)
+	})
+}
+
+func (q *retryQuery) Page(page int) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.Page(page)
+	})
+}
+
+func (q *retryQuery) PageSize(pageSize int) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.PageSize(pageSize)
+	})
+}
+
+func (q *retryQuery) SetOpts(opts ...interface{}) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.SetOpts(opts...)
+	})
+}
+
+func (q *retryQuery) ToArray(res interface{}) error {
+	return q.Find(res)
+}
+
+func (q *retryQuery) Where(args ...interface{}) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.Where(args...)
+	})
+}
+
+func (q *retryQuery) WithDeleted() IQuery {
+	return q.with(IQuery.WithDeleted)
+}
+
+func (q *retryQuery) OnlyDeleted() IQuery {
+	return q.with(IQuery.OnlyDeleted)
+}
+
+func (q *retryQuery) BatchSize(size int) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.BatchSize(size)
+	})
+}
+
+func (q *retryQuery) After(token string) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.After(token)
+	})
+}
+
+func (q *retryQuery) Before(token string) IQuery {
+	return q.with(func(query IQuery) IQuery {
+		return query.Before(token)
+	})
+}
+
+func (q *retryQuery) PageToken() (next, prev string) {
+	if q.last == nil {
+		return
+	}
+
+	return q.last.PageToken()
+}
+
+// Cursor 重试打开游标, 不重试逐行读取
+func (q *retryQuery) Cursor(entry IDbModel) (cursor ICursor, err error) {
+	err = q.do(false, func(query IQuery) (err error) {
+		cursor, err = query.Cursor(entry)
+		return
+	})
+
+	return
+}
@@ -0,0 +1,163 @@
+package goresource
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTestDeadlock = errs.Wrap(errs.ErrDeadlock, errors.New("deadlock"))
+
+// testFlakyRepository 前 failures 次调用返回死锁
+type testFlakyRepository struct {
+	testRepository
+	failures int
+	calls    int
+	queries  []*testQuery
+}
+
+func (r *testFlakyRepository) fail() error {
+	r.calls++
+	if r.calls <= r.failures {
+		return errTestDeadlock
+	}
+
+	return nil
+}
+
+func (r *testFlakyRepository) Create(entry IDbModel, args ...interface{}) error {
+	if err := r.fail(); err != nil {
+		return err
+	}
+
+	return r.testRepository.Create(entry, args...)
+}
+
+func (r *testFlakyRepository) Query() IQuery {
+	query := &testQuery{rows: []testPerson{{ID: 1}}}
+	r.queries = append(r.queries, query)
+	return &testFlakyQuery{testQuery: query, repository: r}
+}
+
+type testFlakyQuery struct {
+	*testQuery
+	repository *testFlakyRepository
+}
+
+func (q *testFlakyQuery) Find(res interface{}) error {
+	if err := q.repository.fail(); err != nil {
+		return err
+	}
+
+	return q.testQuery.Find(res)
+}
+
+func (q *testFlakyQuery) Where(args ...interface{}) IQuery {
+	q.testQuery.Where(args...)
+	return q
+}
+
+// testFlakyUow 前 failures 次 Prepare 返回死锁
+type testFlakyUow struct {
+	testPrepareUow
+	failures int
+}
+
+func (u *testFlakyUow) Prepare() error {
+	*u.logs = append(*u.logs, u.name+".prepare")
+	if u.failures > 0 {
+		u.failures--
+		return errTestDeadlock
+	}
+
+	return nil
+}
+
+func Test_WithRetry(test *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+
+	test.Run("query", func(t *testing.T) {
+		repository := &testFlakyRepository{failures: 2}
+		var res []testPerson
+		a := assert.New(t)
+		a.NoError(WithRetry(testRepoResource{repository: repository}, policy).Db().Query().Where("id = ?", 1).Find(&res))
+		a.Equal([]testPerson{{ID: 1}}, res)
+		a.Len(repository.queries, 3)
+		a.Equal([]interface{}{"id = ?", 1}, repository.queries[2].where)
+	})
+
+	test.Run("reset after find", func(t *testing.T) {
+		repository := &testFlakyRepository{}
+		query := WithRetry(testRepoResource{repository: repository}, policy).Db().Query()
+		var res []testPerson
+		a := assert.New(t)
+		a.NoError(query.Where("id = ?", 1).Find(&res))
+		a.NoError(query.Find(&res))
+		a.Len(repository.queries, 2)
+		a.Empty(repository.queries[1].where)
+	})
+
+	test.Run("attempts", func(t *testing.T) {
+		repository := &testFlakyRepository{failures: 5}
+		var res []testPerson
+		a := assert.New(t)
+		a.ErrorIs(WithRetry(testRepoResource{repository: repository}, policy).Db().Query().Find(&res), errs.ErrDeadlock)
+		a.Equal(3, repository.calls)
+	})
+
+	test.Run("write", func(t *testing.T) {
+		repository := &testFlakyRepository{failures: 1}
+		a := assert.New(t)
+		a.ErrorIs(WithRetry(testRepoResource{repository: repository}, policy).Db().Create(&testPerson{ID: 1}), errs.ErrDeadlock)
+		a.Empty(repository.created)
+
+		nonIdempotent := policy
+		nonIdempotent.NonIdempotent = true
+		repository = &testFlakyRepository{failures: 1}
+		a.NoError(WithRetry(testRepoResource{repository: repository}, nonIdempotent).Db().Create(&testPerson{ID: 1}))
+		a.Len(repository.created, 1)
+	})
+}
+
+func Test_UowWithRetry(test *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+
+	test.Run("replay", func(t *testing.T) {
+		logs := make([]string, 0)
+		uow := UowWithRetry(policy)
+		repo := NewRepository(uow)
+		repo.SetUow(dbtype.MySQL, &testPrepareUow{testUow{name: "mysql", logs: &logs}})
+		repo.SetUow(dbtype.TimeScale, &testFlakyUow{testPrepareUow: testPrepareUow{testUow{name: "timescale", logs: &logs}}, failures: 1})
+
+		a := assert.New(t)
+		a.NoError(uow.Commit())
+		a.Equal([]string{
+			"mysql.prepare",
+			"timescale.prepare",
+			"mysql.rollbackPrepared",
+			"mysql.prepare",
+			"timescale.prepare",
+			"mysql.commitPrepared",
+			"timescale.commitPrepared",
+		}, logs)
+	})
+
+	test.Run("committed", func(t *testing.T) {
+		logs := make([]string, 0)
+		uow := UowWithRetry(policy)
+		repo := NewRepository(uow)
+		repo.SetUow(dbtype.Mongo, &testUow{name: "mongo", logs: &logs})
+		repo.SetUow(dbtype.Memory, &testUow{name: "memory", logs: &logs, commitErr: errTestDeadlock})
+
+		a := assert.New(t)
+		a.ErrorIs(uow.Commit(), errs.ErrDeadlock)
+		a.Equal([]string{
+			"mongo.commit",
+			"memory.commit",
+		}, logs)
+	})
+}
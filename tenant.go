@@ -0,0 +1,268 @@
+package goresource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xm-chentl/goresource/errs"
+)
+
+type tenantCtxKey struct{}
+
+// ContextWithTenant ctx 携带租户
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenantID)
+}
+
+// TenantFromContext ctx 中的租户
+func TenantFromContext(ctx context.Context) (tenantID string, ok bool) {
+	if ctx == nil {
+		return
+	}
+
+	tenantID, ok = ctx.Value(tenantCtxKey{}).(string)
+	return
+}
+
+// TenantResolver 打开租户资源
+// 每租户数据库: TenantDSN; postgres 每租户 schema: postgres.TenantSchema; mongo 每租户数据库: mongoex.TenantDatabase
+type TenantResolver func(tenantID string) (IResource, error)
+
+// TenantDSN 按 dsn 模板打开租户资源, dsn、database 中的 {tenant} 替换为租户
+func TenantDSN(cfg ResourceConfig) TenantResolver {
+	return func(tenantID string) (IResource, error) {
+		tenantCfg := cfg
+		tenantCfg.DSN = strings.ReplaceAll(cfg.DSN, "{tenant}", tenantID)
+		tenantCfg.Database = strings.ReplaceAll(cfg.Database, "{tenant}", tenantID)
+		if tenantCfg.Alias != "" {
+			tenantCfg.Alias += ":" + tenantID
+		}
+
+		return Open(tenantCfg)
+	}
+}
+
+// ITenantFactory 多租户资源工厂, Db 按 ctx 中的租户选择资源
+// Uow 返回联合工作单元(goresource.Uow)
+type ITenantFactory interface {
+	IResource
+	Build(ctx context.Context) (IResource, error)
+	BuildByTenant(tenantID string) (IResource, error)
+	Evict(tenantID string) error // 关闭并移除租户资源
+	Close() error
+}
+
+// tenantPattern 租户只允许字母、数字、_、-
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// NewTenantFactory 多租户资源工厂, 租户资源在首次使用时打开
+// idleTimeout 大于 0 时关闭超过该时长未使用的租户资源, 租户资源的每次 Db、Uow 调用都刷新使用时间
+// (已取得的仓储、查询、工作单元不刷新, 单个操作耗时超过 idleTimeout 时需加大 idleTimeout)
+func NewTenantFactory(resolver TenantResolver, idleTimeout time.Duration) ITenantFactory {
+	return &tenantFactory{
+		resolver:    resolver,
+		idleTimeout: idleTimeout,
+		tenants:     make(map[string]*tenantEntry),
+		now:         time.Now,
+	}
+}
+
+type tenantEntry struct {
+	resource IResource
+	tenant   *tenantResource
+	lastUsed time.Time
+}
+
+// tenantResource Build 返回的租户资源, Db、Uow 刷新使用时间, 已关闭时重新打开
+type tenantResource struct {
+	IResource
+
+	factory  *tenantFactory
+	tenantID string
+}
+
+func (r *tenantResource) Db(args ...interface{}) IRepository {
+	entry, err := r.factory.open(r.tenantID)
+	if err != nil {
+		return errRepository{err: err}
+	}
+
+	return entry.resource.Db(args...)
+}
+
+func (r *tenantResource) Uow() IUnitOfWork {
+	entry, err := r.factory.open(r.tenantID)
+	if err != nil {
+		return Uow()
+	}
+
+	return entry.resource.Uow()
+}
+
+func (r *tenantResource) Unwrap() IResource {
+	return r.IResource
+}
+
+type tenantFactory struct {
+	resolver    TenantResolver
+	idleTimeout time.Duration
+
+	mutex     sync.Mutex
+	tenants   map[string]*tenantEntry
+	lastSweep time.Time
+	closed    bool
+	now       func() time.Time
+}
+
+func (f *tenantFactory) Db(args ...interface{}) IRepository {
+	resource, err := f.Build(ParseDbArgs(args...).Ctx)
+	if err != nil {
+		return errRepository{err: err}
+	}
+
+	return resource.Db(args...)
+}
+
+func (f *tenantFactory) Uow() IUnitOfWork {
+	return Uow()
+}
+
+func (f *tenantFactory) Build(ctx context.Context) (IResource, error) {
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, errs.TenantNotFound
+	}
+
+	return f.BuildByTenant(tenantID)
+}
+
+func (f *tenantFactory) BuildByTenant(tenantID string) (resource IResource, err error) {
+	entry, err := f.open(tenantID)
+	if err != nil {
+		return
+	}
+	resource = entry.tenant
+
+	return
+}
+
+// open 打开(或取已打开的)租户资源并刷新使用时间
+func (f *tenantFactory) open(tenantID string) (entry *tenantEntry, err error) {
+	if !tenantPattern.MatchString(tenantID) {
+		err = errs.TenantInvalid
+		return
+	}
+	f.sweep()
+
+	f.mutex.Lock()
+	if f.closed {
+		f.mutex.Unlock()
+		err = errs.FactoryClosed
+		return
+	}
+	if entry, ok := f.tenants[tenantID]; ok {
+		entry.lastUsed = f.now()
+		f.mutex.Unlock()
+		return entry, nil
+	}
+	f.mutex.Unlock()
+
+	resource, err := f.resolver(tenantID)
+	if err != nil {
+		err = fmt.Errorf("tenant: %s open failed: %w", tenantID, err)
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	// 并发打开时使用先登记的资源
+	if registered, ok := f.tenants[tenantID]; ok || f.closed {
+		closeResource(resource)
+		if f.closed {
+			return nil, errs.FactoryClosed
+		}
+		registered.lastUsed = f.now()
+		return registered, nil
+	}
+	entry = &tenantEntry{
+		resource: resource,
+		tenant: &tenantResource{
+			IResource: resource,
+			factory:   f,
+			tenantID:  tenantID,
+		},
+		lastUsed: f.now(),
+	}
+	f.tenants[tenantID] = entry
+
+	return
+}
+
+func (f *tenantFactory) Evict(tenantID string) error {
+	f.mutex.Lock()
+	entry, ok := f.tenants[tenantID]
+	delete(f.tenants, tenantID)
+	f.mutex.Unlock()
+	if !ok {
+		return nil
+	}
+
+	return closeResource(entry.resource)
+}
+
+func (f *tenantFactory) Close() (err error) {
+	f.mutex.Lock()
+	tenants := f.tenants
+	f.tenants = make(map[string]*tenantEntry)
+	f.closed = true
+	f.mutex.Unlock()
+
+	for tenantID, entry := range tenants {
+		if closeErr := closeResource(entry.resource); closeErr != nil && err == nil {
+			err = fmt.Errorf("tenant: %s close failed: %w", tenantID, closeErr)
+		}
+	}
+
+	return
+}
+
+// sweep 关闭空闲租户资源 (每 idleTimeout/2 最多执行一次)
+func (f *tenantFactory) sweep() {
+	if f.idleTimeout <= 0 {
+		return
+	}
+
+	f.mutex.Lock()
+	now := f.now()
+	if now.Sub(f.lastSweep) < f.idleTimeout/2 {
+		f.mutex.Unlock()
+		return
+	}
+	f.lastSweep = now
+	idle := make([]IResource, 0)
+	for tenantID, entry := range f.tenants {
+		if now.Sub(entry.lastUsed) >= f.idleTimeout {
+			idle = append(idle, entry.resource)
+			delete(f.tenants, tenantID)
+		}
+	}
+	f.mutex.Unlock()
+
+	for _, resource := range idle {
+		_ = closeResource(resource)
+	}
+}
+
+func closeResource(resource IResource) error {
+	if closer, ok := as[io.Closer](resource); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
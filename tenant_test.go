@@ -0,0 +1,125 @@
+package goresource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xm-chentl/goresource/errs"
+)
+
+func newTestTenantFactory(idleTimeout time.Duration) (*tenantFactory, map[string][]*testPingResource) {
+	opened := make(map[string][]*testPingResource)
+	f := NewTenantFactory(func(tenantID string) (IResource, error) {
+		resource := &testPingResource{IResource: testRepoResource{repository: &testRepository{}}}
+		opened[tenantID] = append(opened[tenantID], resource)
+		return resource, nil
+	}, idleTimeout).(*tenantFactory)
+
+	return f, opened
+}
+
+func Test_tenantFactory(test *testing.T) {
+	test.Run("cache by tenant", func(t *testing.T) {
+		f, opened := newTestTenantFactory(0)
+		a := assert.New(t)
+		res1, err := f.Build(ContextWithTenant(context.Background(), "t1"))
+		a.NoError(err)
+		res2, err := f.BuildByTenant("t1")
+		a.NoError(err)
+		a.Same(res1, res2)
+
+		res3, err := f.BuildByTenant("t2")
+		a.NoError(err)
+		a.NotSame(res1, res3)
+		a.Len(opened["t1"], 1)
+		a.Len(opened["t2"], 1)
+	})
+
+	test.Run("tenant required", func(t *testing.T) {
+		f, _ := newTestTenantFactory(0)
+		a := assert.New(t)
+		_, err := f.Build(context.Background())
+		a.Equal(errs.TenantNotFound, err)
+		a.Equal(errs.TenantNotFound, f.Db().Create(&testPerson{}))
+		_, err = f.BuildByTenant("t1; drop table")
+		a.Equal(errs.TenantInvalid, err)
+	})
+
+	test.Run("evict idle", func(t *testing.T) {
+		f, opened := newTestTenantFactory(time.Minute)
+		now := time.Now()
+		f.now = func() time.Time {
+			return now
+		}
+		a := assert.New(t)
+		_, err := f.BuildByTenant("t1")
+		a.NoError(err)
+		_, err = f.BuildByTenant("t2")
+		a.NoError(err)
+
+		now = now.Add(40 * time.Second)
+		_, err = f.BuildByTenant("t2")
+		a.NoError(err)
+		now = now.Add(40 * time.Second)
+		_, err = f.BuildByTenant("t2")
+		a.NoError(err)
+		a.Equal(1, opened["t1"][0].closed)
+		a.Equal(0, opened["t2"][0].closed)
+
+		_, err = f.BuildByTenant("t1")
+		a.NoError(err)
+		a.Len(opened["t1"], 2)
+	})
+
+	test.Run("db refreshes last used", func(t *testing.T) {
+		f, opened := newTestTenantFactory(time.Minute)
+		now := time.Now()
+		f.now = func() time.Time {
+			return now
+		}
+		a := assert.New(t)
+		res, err := f.BuildByTenant("t1")
+		a.NoError(err)
+
+		now = now.Add(40 * time.Second)
+		res.Db()
+		now = now.Add(40 * time.Second)
+		_, err = f.BuildByTenant("t2")
+		a.NoError(err)
+		a.Equal(0, opened["t1"][0].closed)
+
+		// 关闭后再次使用时重新打开
+		now = now.Add(2 * time.Minute)
+		_, err = f.BuildByTenant("t2")
+		a.NoError(err)
+		a.Equal(1, opened["t1"][0].closed)
+		res.Db()
+		a.Len(opened["t1"], 2)
+	})
+
+	test.Run("close", func(t *testing.T) {
+		f, opened := newTestTenantFactory(0)
+		a := assert.New(t)
+		_, err := f.BuildByTenant("t1")
+		a.NoError(err)
+		a.NoError(f.Evict("t1"))
+		a.Equal(1, opened["t1"][0].closed)
+
+		_, err = f.BuildByTenant("t2")
+		a.NoError(err)
+		a.NoError(f.Close())
+		a.Equal(1, opened["t2"][0].closed)
+		_, err = f.BuildByTenant("t2")
+		a.Equal(errs.FactoryClosed, err)
+	})
+}
+
+func Test_TenantDSN(t *testing.T) {
+	res, err := TenantDSN(ResourceConfig{Alias: "main", DSN: "test://127.0.0.1/app_{tenant}"})("t1")
+	a := assert.New(t)
+	a.NoError(err)
+	a.Equal("test://127.0.0.1/app_t1", res.(*testResource).cfg.DSN)
+	a.Equal("main:t1", res.(*testResource).cfg.Alias)
+}
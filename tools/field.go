@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 字段名匹配的 tag (按顺序)
+var tagNames = []string{"postgres", "bson", "json", "gorm"}
+
+// typeOfFields 类型 -> 字段名(小写) -> 字段索引
+var typeOfFields sync.Map
+
+func fieldsOf(rt reflect.Type) map[string][]int {
+	if v, ok := typeOfFields.Load(rt); ok {
+		return v.(map[string][]int)
+	}
+
+	res := make(map[string][]int)
+	nestedFields(rt, nil, res)
+	typeOfFields.Store(rt, res)
+
+	return res
+}
+
+// nestedFields 递归嵌套结构 (排除时间)
+func nestedFields(rt reflect.Type, index []int, res map[string][]int) {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		names := fieldNames(field)
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) && len(names) == 1 {
+			nestedFields(field.Type, fieldIndex, res)
+		}
+		for _, name := range names {
+			if _, ok := res[name]; !ok {
+				res[name] = fieldIndex
+			}
+		}
+	}
+}
+
+func fieldNames(field reflect.StructField) (names []string) {
+	names = append(names, strings.ToLower(field.Name))
+	for _, tagName := range tagNames {
+		value, ok := field.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+		if tagName == "gorm" {
+			for _, item := range strings.Split(value, ";") {
+				if strings.HasPrefix(strings.ToLower(item), "column:") {
+					names = append(names, strings.ToLower(item[len("column:"):]))
+				}
+			}
+			continue
+		}
+		value = strings.Split(value, ",")[0]
+		if value != "" && value != "-" {
+			names = append(names, strings.ToLower(value))
+		}
+	}
+
+	return
+}
+
+// FieldValue 按字段名(字段、tag名 不区分大小写, 支持嵌套结构)获取值
+func FieldValue(entry interface{}, name string) (rv reflect.Value, ok bool) {
+	rv = reflect.Indirect(reflect.ValueOf(entry))
+	index, ok := fieldsOf(rv.Type())[strings.ToLower(name)]
+	if !ok {
+		return
+	}
+	rv = rv.FieldByIndex(index)
+
+	return
+}
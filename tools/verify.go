@@ -2,7 +2,7 @@ package tools
 
 import "reflect"
 
-// IsEmpty support: string、int 8,16,32,64
+// IsEmpty support: string、int 8,16,32,64、uint 8,16,32,64、array(如 ObjectID、UUID) 为零值
 func IsEmpty(v interface{}) bool {
 	if v == nil {
 		return true
@@ -10,23 +10,13 @@ func IsEmpty(v interface{}) bool {
 	rv := reflect.ValueOf(v)
 	switch rv.Kind() {
 	case reflect.String:
-		vv := v.(string)
-		return vv == ""
-	case reflect.Int:
-		vv := v.(int)
-		return vv == 0
-	case reflect.Int8:
-		vv := v.(int8)
-		return vv == 0
-	case reflect.Int16:
-		vv := v.(int16)
-		return vv == 0
-	case reflect.Int32:
-		vv := v.(int32)
-		return vv == 0
-	case reflect.Int64:
-		vv := v.(int64)
-		return vv == 0
+		return rv.String() == ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() == 0
+	case reflect.Array:
+		return rv.IsZero()
 	}
 
 	return false
@@ -51,4 +51,22 @@ func TestIsEmtty(test *testing.T) {
 		a.Equal(IsEmpty(v), false)
 	})
 
+	test.Run("uint64.zero.true", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal(IsEmpty(uint64(0)), true)
+		a.Equal(IsEmpty(uint64(7)), false)
+	})
+
+	test.Run("array.zero.true", func(t *testing.T) {
+		a := assert.New(t)
+		a.Equal(IsEmpty([12]byte{}), true)
+		a.Equal(IsEmpty([12]byte{1}), false)
+	})
+
+	test.Run("named.string", func(t *testing.T) {
+		type id string
+		a := assert.New(t)
+		a.Equal(IsEmpty(id("")), true)
+		a.Equal(IsEmpty(id("a")), false)
+	})
 }
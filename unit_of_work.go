@@ -1,28 +1,289 @@
 package goresource
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/repositorytype"
+	"github.com/xm-chentl/goresource/uowstatus"
 )
 
+// CommitError 联合提交失败, 记录已提交、已补偿的资源
+type CommitError struct {
+	Failed         dbtype.Value
+	Err            error
+	Committed      []dbtype.Value
+	Compensated    []dbtype.Value
+	CompensateErrs map[dbtype.Value]error
+
+	uow IUnitOfWork // 失败的资源工作单元
+}
+
+func (e *CommitError) Error() string {
+	msg := fmt.Sprintf("[%s] database transaction failed: %s", e.Failed.String(), e.Err.Error())
+	if len(e.Committed) > 0 {
+		msg += fmt.Sprintf("; committed: %s", joinDbTypes(e.Committed))
+	}
+	if len(e.Compensated) > 0 {
+		msg += fmt.Sprintf("; compensated: %s", joinDbTypes(e.Compensated))
+	}
+	for dbType, err := range e.CompensateErrs {
+		msg += fmt.Sprintf("; [%s] compensate failed: %s", dbType.String(), err.Error())
+	}
+
+	return msg
+}
+
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// enlistedUow 已登记的资源工作单元, 同类型的多个资源实例分别登记
+type enlistedUow struct {
+	dbType dbtype.Value
+	uow    IUnitOfWork
+}
+
 type unitOfWork struct {
-	uowMap map[dbtype.Value]IUnitOfWork
+	CommitHooks
+
+	status      uowstatus.Value
+	enlisted    []enlistedUow // 登记顺序
+	compensates map[dbtype.Value][]CompensateFunc
+	retry       *RetryPolicy
 }
 
-func (u unitOfWork) Commit() (err error) {
-	for dbType, uow := range u.uowMap {
-		if err = uow.Commit(); err != nil {
-			err = fmt.Errorf("[%s] database transaction failed: %s", dbType.String(), err.Error())
+// Commit 联合提交
+// 1. 支持两阶段的资源先 Prepare，任一失败则全部回滚
+// 2. 不支持事务的资源按登记顺序提交，失败则回滚已 Prepare 的资源并补偿已提交的资源
+// 3. 提交已 Prepare 的资源
+// 设置重试策略时, 可重试的失败在新事务中重放全部队列(失败的资源保留了队列且没有资源已提交)
+func (u *unitOfWork) Commit() (err error) {
+	if u.retry == nil {
+		return u.commit()
+	}
+
+	retryable := u.retry.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	policy := *u.retry
+	policy.Retryable = func(err error) bool {
+		return retryable(err) && u.replayable(err)
+	}
+
+	return policy.Do(context.Background(), u.commit)
+}
+
+func (u *unitOfWork) commit() (err error) {
+	if u.status == uowstatus.Aborted {
+		err = errs.UowDiscarded
+		return
+	}
+
+	prepared := make([]enlistedUow, 0)
+	direct := make([]enlistedUow, 0)
+	for _, item := range u.enlisted {
+		p, ok := item.uow.(IPrepareUnitOfWork)
+		if !ok {
+			direct = append(direct, item)
+			continue
+		}
+		if err = p.Prepare(); err != nil {
+			if errors.Is(err, errs.UowPrepareNotSupported) {
+				err = nil
+				direct = append(direct, item)
+				continue
+			}
+			u.rollbackPrepared(prepared)
+			return &CommitError{
+				Failed: item.dbType,
+				Err:    err,
+				uow:    item.uow,
+			}
+		}
+		prepared = append(prepared, item)
+	}
+
+	committed := make([]dbtype.Value, 0)
+	for _, item := range direct {
+		if err = item.uow.Commit(); err != nil {
+			u.rollbackPrepared(prepared)
+			return u.compensate(item, err, committed)
+		}
+		committed = append(committed, item.dbType)
+	}
+	for index, item := range prepared {
+		if err = item.uow.(IPrepareUnitOfWork).CommitPrepared(); err != nil {
+			u.rollbackPrepared(prepared[index+1:])
+			return u.compensate(item, err, committed)
+		}
+		committed = append(committed, item.dbType)
+	}
+	u.status = uowstatus.Committed
+	u.RunCommitted()
+
+	return
+}
+
+// Rollback 放弃所有资源的队列
+func (u *unitOfWork) Rollback() (err error) {
+	for _, item := range u.enlisted {
+		if rollbackErr := item.uow.Rollback(); rollbackErr != nil && err == nil {
+			err = fmt.Errorf("[%s] database rollback failed: %s", item.dbType.String(), rollbackErr.Error())
+		}
+	}
+	u.status = uowstatus.Aborted
+
+	return
+}
+
+func (u *unitOfWork) Discard() {
+	_ = u.Rollback()
+}
+
+// Pending 所有资源的待提交数量
+func (u *unitOfWork) Pending() map[repositorytype.Value]int {
+	res := make(map[repositorytype.Value]int)
+	for _, item := range u.enlisted {
+		for rt, count := range item.uow.Pending() {
+			res[rt] += count
+		}
+	}
+
+	return res
+}
+
+func (u *unitOfWork) Status() uowstatus.Value {
+	return u.status
+}
+
+// Compensate 登记补偿操作
+func (u *unitOfWork) Compensate(dbType dbtype.Value, fn CompensateFunc) {
+	u.compensates[dbType] = append(u.compensates[dbType], fn)
+}
+
+// enlist 登记资源工作单元, 已登记的实例不重复登记
+func (u *unitOfWork) enlist(dbType dbtype.Value, uow IUnitOfWork) {
+	for _, item := range u.enlisted {
+		if item.uow == uow {
 			return
 		}
 	}
+	u.enlisted = append(u.enlisted, enlistedUow{
+		dbType: dbType,
+		uow:    uow,
+	})
+}
+
+// find 已登记的 dbType 资源工作单元中 match 的实例
+func (u *unitOfWork) find(dbType dbtype.Value, match func(IUnitOfWork) bool) (uow IUnitOfWork, ok bool) {
+	for _, item := range u.enlisted {
+		if item.dbType == dbType && match(item.uow) {
+			return item.uow, true
+		}
+	}
 
 	return
 }
 
+// replayable 失败的资源仍有待提交的队列(不支持事务的资源失败时已部分执行并清空队列)
+func (u *unitOfWork) replayable(err error) bool {
+	var commitErr *CommitError
+	if !errors.As(err, &commitErr) || len(commitErr.Committed) > 0 {
+		return false
+	}
+	if commitErr.uow == nil {
+		return false
+	}
+	for _, count := range commitErr.uow.Pending() {
+		if count > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *unitOfWork) rollbackPrepared(prepared []enlistedUow) {
+	for index := len(prepared) - 1; index >= 0; index-- {
+		_ = prepared[index].uow.(IPrepareUnitOfWork).RollbackPrepared()
+	}
+}
+
+// compensate 逆序执行已提交资源的补偿操作, 同类型的多个资源只补偿一次
+func (u *unitOfWork) compensate(failed enlistedUow, err error, committed []dbtype.Value) *CommitError {
+	commitErr := &CommitError{
+		Failed:         failed.dbType,
+		Err:            err,
+		uow:            failed.uow,
+		Committed:      committed,
+		Compensated:    make([]dbtype.Value, 0),
+		CompensateErrs: make(map[dbtype.Value]error),
+	}
+	for index := len(committed) - 1; index >= 0; index-- {
+		dbType := committed[index]
+		fns, ok := u.compensates[dbType]
+		if !ok || compensated(commitErr, dbType) {
+			continue
+		}
+		var compensateErr error
+		for i := len(fns) - 1; i >= 0; i-- {
+			if compensateErr = fns[i](); compensateErr != nil {
+				break
+			}
+		}
+		if compensateErr != nil {
+			commitErr.CompensateErrs[dbType] = compensateErr
+			continue
+		}
+		commitErr.Compensated = append(commitErr.Compensated, dbType)
+	}
+
+	return commitErr
+}
+
 func Uow() IUnitOfWork {
+	return newUnitOfWork()
+}
+
+// UowWithRetry 提交按 policy 重试的联合工作单元
+func UowWithRetry(policy RetryPolicy) IUnitOfWork {
+	uow := newUnitOfWork()
+	uow.retry = &policy
+
+	return uow
+}
+
+func newUnitOfWork() *unitOfWork {
 	return &unitOfWork{
-		uowMap: make(map[dbtype.Value]IUnitOfWork),
+		enlisted:    make([]enlistedUow, 0),
+		compensates: make(map[dbtype.Value][]CompensateFunc),
+	}
+}
+
+func compensated(commitErr *CommitError, dbType dbtype.Value) bool {
+	if _, ok := commitErr.CompensateErrs[dbType]; ok {
+		return true
+	}
+	for _, item := range commitErr.Compensated {
+		if item == dbType {
+			return true
+		}
 	}
+
+	return false
+}
+
+func joinDbTypes(dbTypes []dbtype.Value) string {
+	items := make([]string, 0, len(dbTypes))
+	for _, dbType := range dbTypes {
+		items = append(items, dbType.String())
+	}
+
+	return strings.Join(items, ", ")
 }
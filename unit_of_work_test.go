@@ -0,0 +1,185 @@
+package goresource
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xm-chentl/goresource/dbtype"
+	"github.com/xm-chentl/goresource/errs"
+	"github.com/xm-chentl/goresource/repositorytype"
+	"github.com/xm-chentl/goresource/uowstatus"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testUow struct {
+	name       string
+	logs       *[]string
+	commitErr  error
+	prepareErr error
+}
+
+func (u *testUow) Commit() error {
+	*u.logs = append(*u.logs, u.name+".commit")
+	return u.commitErr
+}
+
+func (u *testUow) Rollback() error {
+	*u.logs = append(*u.logs, u.name+".rollback")
+	return nil
+}
+
+func (u *testUow) Discard() {
+	_ = u.Rollback()
+}
+
+func (u *testUow) Pending() map[repositorytype.Value]int {
+	return map[repositorytype.Value]int{
+		repositorytype.Create: 1,
+	}
+}
+
+func (u *testUow) Status() uowstatus.Value {
+	return uowstatus.Pending
+}
+
+type testPrepareUow struct {
+	testUow
+}
+
+func (u *testPrepareUow) Prepare() error {
+	*u.logs = append(*u.logs, u.name+".prepare")
+	return u.prepareErr
+}
+
+func (u *testPrepareUow) CommitPrepared() error {
+	*u.logs = append(*u.logs, u.name+".commitPrepared")
+	return u.commitErr
+}
+
+func (u *testPrepareUow) RollbackPrepared() error {
+	*u.logs = append(*u.logs, u.name+".rollbackPrepared")
+	return nil
+}
+
+func Test_unitOfWork_Commit(test *testing.T) {
+	test.Run("success", func(t *testing.T) {
+		logs := make([]string, 0)
+		uow := Uow()
+		repo := NewRepository(uow)
+		repo.SetUow(dbtype.MySQL, &testPrepareUow{testUow{name: "mysql", logs: &logs}})
+		repo.SetUow(dbtype.Mongo, &testUow{name: "mongo", logs: &logs})
+		repo.SetUow(dbtype.TimeScale, &testPrepareUow{testUow{name: "timescale", logs: &logs}})
+
+		a := assert.New(t)
+		a.NoError(uow.Commit())
+		a.Equal(uowstatus.Committed, uow.Status())
+		a.Equal([]string{
+			"mysql.prepare",
+			"timescale.prepare",
+			"mongo.commit",
+			"mysql.commitPrepared",
+			"timescale.commitPrepared",
+		}, logs)
+	})
+
+	test.Run("prepare.failed", func(t *testing.T) {
+		logs := make([]string, 0)
+		uow := Uow()
+		repo := NewRepository(uow)
+		repo.SetUow(dbtype.MySQL, &testPrepareUow{testUow{name: "mysql", logs: &logs}})
+		repo.SetUow(dbtype.Mongo, &testUow{name: "mongo", logs: &logs})
+		prepareErr := errors.New("prepare")
+		repo.SetUow(dbtype.TimeScale, &testPrepareUow{testUow{name: "timescale", logs: &logs, prepareErr: prepareErr}})
+
+		err := uow.Commit()
+		a := assert.New(t)
+		a.ErrorIs(err, prepareErr)
+		a.Equal(dbtype.TimeScale, err.(*CommitError).Failed)
+		a.Empty(err.(*CommitError).Committed)
+		a.Equal([]string{
+			"mysql.prepare",
+			"timescale.prepare",
+			"mysql.rollbackPrepared",
+		}, logs)
+	})
+
+	test.Run("prepare.not supported", func(t *testing.T) {
+		logs := make([]string, 0)
+		uow := Uow()
+		repo := NewRepository(uow)
+		repo.SetUow(dbtype.Mongo, &testPrepareUow{testUow{name: "mongo", logs: &logs, prepareErr: errs.UowPrepareNotSupported}})
+
+		a := assert.New(t)
+		a.NoError(uow.Commit())
+		a.Equal([]string{
+			"mongo.prepare",
+			"mongo.commit",
+		}, logs)
+	})
+
+	test.Run("compensate", func(t *testing.T) {
+		logs := make([]string, 0)
+		uow := Uow()
+		repo := NewRepository(uow)
+		repo.SetUow(dbtype.MySQL, &testPrepareUow{testUow{name: "mysql", logs: &logs}})
+		repo.SetUow(dbtype.Mongo, &testUow{name: "mongo", logs: &logs})
+		commitErr := errors.New("commit")
+		repo.SetUow(dbtype.Memory, &testUow{name: "memory", logs: &logs, commitErr: commitErr})
+		uow.(ICompensable).Compensate(dbtype.Mongo, func() error {
+			logs = append(logs, "mongo.compensate")
+			return nil
+		})
+
+		err := uow.Commit()
+		a := assert.New(t)
+		a.ErrorIs(err, commitErr)
+		commitError := err.(*CommitError)
+		a.Equal(dbtype.Memory, commitError.Failed)
+		a.Equal([]dbtype.Value{dbtype.Mongo}, commitError.Committed)
+		a.Equal([]dbtype.Value{dbtype.Mongo}, commitError.Compensated)
+		a.Equal([]string{
+			"mysql.prepare",
+			"mongo.commit",
+			"memory.commit",
+			"mysql.rollbackPrepared",
+			"mongo.compensate",
+		}, logs)
+	})
+}
+
+func Test_unitOfWork_enlist(t *testing.T) {
+	logs := make([]string, 0)
+	uow := Uow()
+	repo := NewRepository(uow)
+	first := &testUow{name: "first", logs: &logs}
+	repo.SetUow(dbtype.Memory, first)
+	repo.SetUow(dbtype.Memory, &testUow{name: "second", logs: &logs})
+	repo.SetUow(dbtype.Memory, first)
+
+	a := assert.New(t)
+	enlisted, ok := repo.GetUow(dbtype.Memory, func(u IUnitOfWork) bool {
+		return u.(*testUow).name == "second"
+	})
+	a.True(ok)
+	a.Equal("second", enlisted.(*testUow).name)
+	a.NoError(uow.Commit())
+	a.Equal([]string{"first.commit", "second.commit"}, logs)
+
+	a.Nil(NewRepository(first))
+}
+
+func Test_unitOfWork_Discard(t *testing.T) {
+	logs := make([]string, 0)
+	uow := Uow()
+	repo := NewRepository(uow)
+	repo.SetUow(dbtype.MySQL, &testUow{name: "mysql", logs: &logs})
+	repo.SetUow(dbtype.Mongo, &testUow{name: "mongo", logs: &logs})
+
+	a := assert.New(t)
+	a.Equal(map[repositorytype.Value]int{repositorytype.Create: 2}, uow.Pending())
+	uow.Discard()
+	a.Equal(uowstatus.Aborted, uow.Status())
+	a.Equal(errs.UowDiscarded, uow.Commit())
+	a.Equal([]string{"mysql.rollback", "mongo.rollback"}, logs)
+}
@@ -0,0 +1,21 @@
+package uowstatus
+
+// Value 工作单元状态
+type Value int
+
+const (
+	Pending   Value = iota // 待提交
+	Committed              // 已提交
+	Aborted                // 已放弃(回滚)
+)
+
+func (v Value) String() string {
+	switch v {
+	case Committed:
+		return "committed"
+	case Aborted:
+		return "aborted"
+	}
+
+	return "pending"
+}
@@ -0,0 +1,63 @@
+package goresource
+
+import "github.com/xm-chentl/goresource/errs"
+
+// IVersioned 乐观锁模型, Update 时按版本条件更新并递增版本, 未更新到数据时返回 *errs.ConcurrencyConflict
+// VersionField 返回各资源的字段名(postgres 列名、bson 名、gorm 列名)
+type IVersioned interface {
+	VersionField() string
+	GetVersion() int64
+	SetVersion(version int64)
+}
+
+// VersionGuard 乐观锁更新, 更新条件为 Field = Old
+type VersionGuard struct {
+	Field string
+	Old   int64
+
+	entry     IDbModel
+	versioned IVersioned
+}
+
+// NextVersion entry 为 IVersioned 时递增版本
+func NextVersion(entry IDbModel) (guard *VersionGuard, ok bool) {
+	versioned, ok := entry.(IVersioned)
+	if !ok {
+		return
+	}
+
+	guard = &VersionGuard{
+		Field:     versioned.VersionField(),
+		Old:       versioned.GetVersion(),
+		entry:     entry,
+		versioned: versioned,
+	}
+	versioned.SetVersion(guard.Old + 1)
+
+	return
+}
+
+// New 更新后的版本
+func (g *VersionGuard) New() int64 {
+	return g.Old + 1
+}
+
+// Apply 设置为新版本, 工作单元提交时按入队时的 guard 更新, 重放时版本不再递增
+func (g *VersionGuard) Apply() {
+	g.versioned.SetVersion(g.New())
+}
+
+// Restore 恢复版本
+func (g *VersionGuard) Restore() {
+	g.versioned.SetVersion(g.Old)
+}
+
+// Conflict 恢复版本并返回冲突错误
+func (g *VersionGuard) Conflict() error {
+	g.Restore()
+	return &errs.ConcurrencyConflict{
+		Table:   g.entry.Table(),
+		ID:      g.entry.GetID(),
+		Version: g.Old,
+	}
+}